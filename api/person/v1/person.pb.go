@@ -0,0 +1,1084 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: person/v1/person.proto
+
+package personv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type DeletedFilter int32
+
+const (
+	DeletedFilter_DELETED_FILTER_UNSPECIFIED DeletedFilter = 0
+	DeletedFilter_DELETED_FILTER_EXCLUDE     DeletedFilter = 1
+	DeletedFilter_DELETED_FILTER_ONLY        DeletedFilter = 2
+	DeletedFilter_DELETED_FILTER_INCLUDE     DeletedFilter = 3
+)
+
+// Enum value maps for DeletedFilter.
+var (
+	DeletedFilter_name = map[int32]string{
+		0: "DELETED_FILTER_UNSPECIFIED",
+		1: "DELETED_FILTER_EXCLUDE",
+		2: "DELETED_FILTER_ONLY",
+		3: "DELETED_FILTER_INCLUDE",
+	}
+	DeletedFilter_value = map[string]int32{
+		"DELETED_FILTER_UNSPECIFIED": 0,
+		"DELETED_FILTER_EXCLUDE":     1,
+		"DELETED_FILTER_ONLY":        2,
+		"DELETED_FILTER_INCLUDE":     3,
+	}
+)
+
+func (x DeletedFilter) Enum() *DeletedFilter {
+	p := new(DeletedFilter)
+	*p = x
+	return p
+}
+
+func (x DeletedFilter) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (DeletedFilter) Descriptor() protoreflect.EnumDescriptor {
+	return file_person_v1_person_proto_enumTypes[0].Descriptor()
+}
+
+func (DeletedFilter) Type() protoreflect.EnumType {
+	return &file_person_v1_person_proto_enumTypes[0]
+}
+
+func (x DeletedFilter) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use DeletedFilter.Descriptor instead.
+func (DeletedFilter) EnumDescriptor() ([]byte, []int) {
+	return file_person_v1_person_proto_rawDescGZIP(), []int{0}
+}
+
+type Person struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Surname       string                 `protobuf:"bytes,3,opt,name=surname,proto3" json:"surname,omitempty"`
+	Patronymic    *string                `protobuf:"bytes,4,opt,name=patronymic,proto3,oneof" json:"patronymic,omitempty"`
+	Age           int32                  `protobuf:"varint,5,opt,name=age,proto3" json:"age,omitempty"`
+	Gender        string                 `protobuf:"bytes,6,opt,name=gender,proto3" json:"gender,omitempty"`
+	Nationality   string                 `protobuf:"bytes,7,opt,name=nationality,proto3" json:"nationality,omitempty"`
+	Version       int64                  `protobuf:"varint,8,opt,name=version,proto3" json:"version,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	DeletedAt     *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=deleted_at,json=deletedAt,proto3" json:"deleted_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Person) Reset() {
+	*x = Person{}
+	mi := &file_person_v1_person_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Person) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Person) ProtoMessage() {}
+
+func (x *Person) ProtoReflect() protoreflect.Message {
+	mi := &file_person_v1_person_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Person.ProtoReflect.Descriptor instead.
+func (*Person) Descriptor() ([]byte, []int) {
+	return file_person_v1_person_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Person) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Person) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Person) GetSurname() string {
+	if x != nil {
+		return x.Surname
+	}
+	return ""
+}
+
+func (x *Person) GetPatronymic() string {
+	if x != nil && x.Patronymic != nil {
+		return *x.Patronymic
+	}
+	return ""
+}
+
+func (x *Person) GetAge() int32 {
+	if x != nil {
+		return x.Age
+	}
+	return 0
+}
+
+func (x *Person) GetGender() string {
+	if x != nil {
+		return x.Gender
+	}
+	return ""
+}
+
+func (x *Person) GetNationality() string {
+	if x != nil {
+		return x.Nationality
+	}
+	return ""
+}
+
+func (x *Person) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Person) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Person) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *Person) GetDeletedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DeletedAt
+	}
+	return nil
+}
+
+// PersonFilter matches people on every field that is set. Soft-deleted
+// people are excluded unless deleted says otherwise.
+type PersonFilter struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          *string                `protobuf:"bytes,1,opt,name=name,proto3,oneof" json:"name,omitempty"`
+	Surname       *string                `protobuf:"bytes,2,opt,name=surname,proto3,oneof" json:"surname,omitempty"`
+	Patronymic    *string                `protobuf:"bytes,3,opt,name=patronymic,proto3,oneof" json:"patronymic,omitempty"`
+	Age           *int32                 `protobuf:"varint,4,opt,name=age,proto3,oneof" json:"age,omitempty"`
+	Gender        *string                `protobuf:"bytes,5,opt,name=gender,proto3,oneof" json:"gender,omitempty"`
+	Nationality   *string                `protobuf:"bytes,6,opt,name=nationality,proto3,oneof" json:"nationality,omitempty"`
+	CreatedAfter  *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=created_after,json=createdAfter,proto3" json:"created_after,omitempty"`
+	UpdatedSince  *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=updated_since,json=updatedSince,proto3" json:"updated_since,omitempty"`
+	Deleted       DeletedFilter          `protobuf:"varint,9,opt,name=deleted,proto3,enum=person.v1.DeletedFilter" json:"deleted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PersonFilter) Reset() {
+	*x = PersonFilter{}
+	mi := &file_person_v1_person_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PersonFilter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PersonFilter) ProtoMessage() {}
+
+func (x *PersonFilter) ProtoReflect() protoreflect.Message {
+	mi := &file_person_v1_person_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PersonFilter.ProtoReflect.Descriptor instead.
+func (*PersonFilter) Descriptor() ([]byte, []int) {
+	return file_person_v1_person_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PersonFilter) GetName() string {
+	if x != nil && x.Name != nil {
+		return *x.Name
+	}
+	return ""
+}
+
+func (x *PersonFilter) GetSurname() string {
+	if x != nil && x.Surname != nil {
+		return *x.Surname
+	}
+	return ""
+}
+
+func (x *PersonFilter) GetPatronymic() string {
+	if x != nil && x.Patronymic != nil {
+		return *x.Patronymic
+	}
+	return ""
+}
+
+func (x *PersonFilter) GetAge() int32 {
+	if x != nil && x.Age != nil {
+		return *x.Age
+	}
+	return 0
+}
+
+func (x *PersonFilter) GetGender() string {
+	if x != nil && x.Gender != nil {
+		return *x.Gender
+	}
+	return ""
+}
+
+func (x *PersonFilter) GetNationality() string {
+	if x != nil && x.Nationality != nil {
+		return *x.Nationality
+	}
+	return ""
+}
+
+func (x *PersonFilter) GetCreatedAfter() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAfter
+	}
+	return nil
+}
+
+func (x *PersonFilter) GetUpdatedSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedSince
+	}
+	return nil
+}
+
+func (x *PersonFilter) GetDeleted() DeletedFilter {
+	if x != nil {
+		return x.Deleted
+	}
+	return DeletedFilter_DELETED_FILTER_UNSPECIFIED
+}
+
+type CreatePersonRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Surname       string                 `protobuf:"bytes,2,opt,name=surname,proto3" json:"surname,omitempty"`
+	Patronymic    *string                `protobuf:"bytes,3,opt,name=patronymic,proto3,oneof" json:"patronymic,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreatePersonRequest) Reset() {
+	*x = CreatePersonRequest{}
+	mi := &file_person_v1_person_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreatePersonRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreatePersonRequest) ProtoMessage() {}
+
+func (x *CreatePersonRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_person_v1_person_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreatePersonRequest.ProtoReflect.Descriptor instead.
+func (*CreatePersonRequest) Descriptor() ([]byte, []int) {
+	return file_person_v1_person_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CreatePersonRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CreatePersonRequest) GetSurname() string {
+	if x != nil {
+		return x.Surname
+	}
+	return ""
+}
+
+func (x *CreatePersonRequest) GetPatronymic() string {
+	if x != nil && x.Patronymic != nil {
+		return *x.Patronymic
+	}
+	return ""
+}
+
+type GetPersonRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPersonRequest) Reset() {
+	*x = GetPersonRequest{}
+	mi := &file_person_v1_person_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPersonRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPersonRequest) ProtoMessage() {}
+
+func (x *GetPersonRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_person_v1_person_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPersonRequest.ProtoReflect.Descriptor instead.
+func (*GetPersonRequest) Descriptor() ([]byte, []int) {
+	return file_person_v1_person_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetPersonRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type ListPeopleRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Filter *PersonFilter          `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	// page starts at 1; page_size defaults to 10.
+	Page          int32 `protobuf:"varint,2,opt,name=page,proto3" json:"page,omitempty"`
+	PageSize      int32 `protobuf:"varint,3,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPeopleRequest) Reset() {
+	*x = ListPeopleRequest{}
+	mi := &file_person_v1_person_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPeopleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPeopleRequest) ProtoMessage() {}
+
+func (x *ListPeopleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_person_v1_person_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPeopleRequest.ProtoReflect.Descriptor instead.
+func (*ListPeopleRequest) Descriptor() ([]byte, []int) {
+	return file_person_v1_person_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListPeopleRequest) GetFilter() *PersonFilter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *ListPeopleRequest) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *ListPeopleRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+type ListPeopleResponse struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	People []*Person              `protobuf:"bytes,1,rep,name=people,proto3" json:"people,omitempty"`
+	// next_page is zero on the last page.
+	NextPage      int32 `protobuf:"varint,2,opt,name=next_page,json=nextPage,proto3" json:"next_page,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPeopleResponse) Reset() {
+	*x = ListPeopleResponse{}
+	mi := &file_person_v1_person_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPeopleResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPeopleResponse) ProtoMessage() {}
+
+func (x *ListPeopleResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_person_v1_person_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPeopleResponse.ProtoReflect.Descriptor instead.
+func (*ListPeopleResponse) Descriptor() ([]byte, []int) {
+	return file_person_v1_person_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListPeopleResponse) GetPeople() []*Person {
+	if x != nil {
+		return x.People
+	}
+	return nil
+}
+
+func (x *ListPeopleResponse) GetNextPage() int32 {
+	if x != nil {
+		return x.NextPage
+	}
+	return 0
+}
+
+type UpdatePersonRequest struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Id         int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name       string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Surname    string                 `protobuf:"bytes,3,opt,name=surname,proto3" json:"surname,omitempty"`
+	Patronymic *string                `protobuf:"bytes,4,opt,name=patronymic,proto3,oneof" json:"patronymic,omitempty"`
+	// expected_version rejects the update with FAILED_PRECONDITION unless the
+	// person is at this version; zero skips the check.
+	ExpectedVersion int64 `protobuf:"varint,5,opt,name=expected_version,json=expectedVersion,proto3" json:"expected_version,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *UpdatePersonRequest) Reset() {
+	*x = UpdatePersonRequest{}
+	mi := &file_person_v1_person_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdatePersonRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdatePersonRequest) ProtoMessage() {}
+
+func (x *UpdatePersonRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_person_v1_person_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdatePersonRequest.ProtoReflect.Descriptor instead.
+func (*UpdatePersonRequest) Descriptor() ([]byte, []int) {
+	return file_person_v1_person_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *UpdatePersonRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *UpdatePersonRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *UpdatePersonRequest) GetSurname() string {
+	if x != nil {
+		return x.Surname
+	}
+	return ""
+}
+
+func (x *UpdatePersonRequest) GetPatronymic() string {
+	if x != nil && x.Patronymic != nil {
+		return *x.Patronymic
+	}
+	return ""
+}
+
+func (x *UpdatePersonRequest) GetExpectedVersion() int64 {
+	if x != nil {
+		return x.ExpectedVersion
+	}
+	return 0
+}
+
+type DeletePersonRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Id              int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	ExpectedVersion int64                  `protobuf:"varint,2,opt,name=expected_version,json=expectedVersion,proto3" json:"expected_version,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *DeletePersonRequest) Reset() {
+	*x = DeletePersonRequest{}
+	mi := &file_person_v1_person_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletePersonRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletePersonRequest) ProtoMessage() {}
+
+func (x *DeletePersonRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_person_v1_person_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletePersonRequest.ProtoReflect.Descriptor instead.
+func (*DeletePersonRequest) Descriptor() ([]byte, []int) {
+	return file_person_v1_person_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DeletePersonRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *DeletePersonRequest) GetExpectedVersion() int64 {
+	if x != nil {
+		return x.ExpectedVersion
+	}
+	return 0
+}
+
+type WatchPeopleRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Filter *PersonFilter          `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	// types limits the event types, such as "person.created"; empty means all.
+	Types []string `protobuf:"bytes,2,rep,name=types,proto3" json:"types,omitempty"`
+	// last_event_id resumes after the event with this ID.
+	LastEventId   int64 `protobuf:"varint,3,opt,name=last_event_id,json=lastEventId,proto3" json:"last_event_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchPeopleRequest) Reset() {
+	*x = WatchPeopleRequest{}
+	mi := &file_person_v1_person_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchPeopleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchPeopleRequest) ProtoMessage() {}
+
+func (x *WatchPeopleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_person_v1_person_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchPeopleRequest.ProtoReflect.Descriptor instead.
+func (*WatchPeopleRequest) Descriptor() ([]byte, []int) {
+	return file_person_v1_person_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *WatchPeopleRequest) GetFilter() *PersonFilter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *WatchPeopleRequest) GetTypes() []string {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+func (x *WatchPeopleRequest) GetLastEventId() int64 {
+	if x != nil {
+		return x.LastEventId
+	}
+	return 0
+}
+
+type FieldChange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Old           *structpb.Value        `protobuf:"bytes,1,opt,name=old,proto3" json:"old,omitempty"`
+	New           *structpb.Value        `protobuf:"bytes,2,opt,name=new,proto3" json:"new,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FieldChange) Reset() {
+	*x = FieldChange{}
+	mi := &file_person_v1_person_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FieldChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FieldChange) ProtoMessage() {}
+
+func (x *FieldChange) ProtoReflect() protoreflect.Message {
+	mi := &file_person_v1_person_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FieldChange.ProtoReflect.Descriptor instead.
+func (*FieldChange) Descriptor() ([]byte, []int) {
+	return file_person_v1_person_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *FieldChange) GetOld() *structpb.Value {
+	if x != nil {
+		return x.Old
+	}
+	return nil
+}
+
+func (x *FieldChange) GetNew() *structpb.Value {
+	if x != nil {
+		return x.New
+	}
+	return nil
+}
+
+type PersonEvent struct {
+	state         protoimpl.MessageState  `protogen:"open.v1"`
+	Id            int64                   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type          string                  `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	PersonId      int64                   `protobuf:"varint,3,opt,name=person_id,json=personId,proto3" json:"person_id,omitempty"`
+	Actor         string                  `protobuf:"bytes,4,opt,name=actor,proto3" json:"actor,omitempty"`
+	RequestId     string                  `protobuf:"bytes,5,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Source        string                  `protobuf:"bytes,6,opt,name=source,proto3" json:"source,omitempty"`
+	Person        *Person                 `protobuf:"bytes,7,opt,name=person,proto3" json:"person,omitempty"`
+	Changes       map[string]*FieldChange `protobuf:"bytes,8,rep,name=changes,proto3" json:"changes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	CreatedAt     *timestamppb.Timestamp  `protobuf:"bytes,9,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PersonEvent) Reset() {
+	*x = PersonEvent{}
+	mi := &file_person_v1_person_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PersonEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PersonEvent) ProtoMessage() {}
+
+func (x *PersonEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_person_v1_person_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PersonEvent.ProtoReflect.Descriptor instead.
+func (*PersonEvent) Descriptor() ([]byte, []int) {
+	return file_person_v1_person_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *PersonEvent) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *PersonEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *PersonEvent) GetPersonId() int64 {
+	if x != nil {
+		return x.PersonId
+	}
+	return 0
+}
+
+func (x *PersonEvent) GetActor() string {
+	if x != nil {
+		return x.Actor
+	}
+	return ""
+}
+
+func (x *PersonEvent) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *PersonEvent) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *PersonEvent) GetPerson() *Person {
+	if x != nil {
+		return x.Person
+	}
+	return nil
+}
+
+func (x *PersonEvent) GetChanges() map[string]*FieldChange {
+	if x != nil {
+		return x.Changes
+	}
+	return nil
+}
+
+func (x *PersonEvent) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+var File_person_v1_person_proto protoreflect.FileDescriptor
+
+const file_person_v1_person_proto_rawDesc = "" +
+	"\n" +
+	"\x16person/v1/person.proto\x12\tperson.v1\x1a\x1bgoogle/protobuf/empty.proto\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x91\x03\n" +
+	"\x06Person\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x18\n" +
+	"\asurname\x18\x03 \x01(\tR\asurname\x12#\n" +
+	"\n" +
+	"patronymic\x18\x04 \x01(\tH\x00R\n" +
+	"patronymic\x88\x01\x01\x12\x10\n" +
+	"\x03age\x18\x05 \x01(\x05R\x03age\x12\x16\n" +
+	"\x06gender\x18\x06 \x01(\tR\x06gender\x12 \n" +
+	"\vnationality\x18\a \x01(\tR\vnationality\x12\x18\n" +
+	"\aversion\x18\b \x01(\x03R\aversion\x129\n" +
+	"\n" +
+	"created_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x129\n" +
+	"\n" +
+	"deleted_at\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\tdeletedAtB\r\n" +
+	"\v_patronymic\"\xc3\x03\n" +
+	"\fPersonFilter\x12\x17\n" +
+	"\x04name\x18\x01 \x01(\tH\x00R\x04name\x88\x01\x01\x12\x1d\n" +
+	"\asurname\x18\x02 \x01(\tH\x01R\asurname\x88\x01\x01\x12#\n" +
+	"\n" +
+	"patronymic\x18\x03 \x01(\tH\x02R\n" +
+	"patronymic\x88\x01\x01\x12\x15\n" +
+	"\x03age\x18\x04 \x01(\x05H\x03R\x03age\x88\x01\x01\x12\x1b\n" +
+	"\x06gender\x18\x05 \x01(\tH\x04R\x06gender\x88\x01\x01\x12%\n" +
+	"\vnationality\x18\x06 \x01(\tH\x05R\vnationality\x88\x01\x01\x12?\n" +
+	"\rcreated_after\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\fcreatedAfter\x12?\n" +
+	"\rupdated_since\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\fupdatedSince\x122\n" +
+	"\adeleted\x18\t \x01(\x0e2\x18.person.v1.DeletedFilterR\adeletedB\a\n" +
+	"\x05_nameB\n" +
+	"\n" +
+	"\b_surnameB\r\n" +
+	"\v_patronymicB\x06\n" +
+	"\x04_ageB\t\n" +
+	"\a_genderB\x0e\n" +
+	"\f_nationality\"w\n" +
+	"\x13CreatePersonRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
+	"\asurname\x18\x02 \x01(\tR\asurname\x12#\n" +
+	"\n" +
+	"patronymic\x18\x03 \x01(\tH\x00R\n" +
+	"patronymic\x88\x01\x01B\r\n" +
+	"\v_patronymic\"\"\n" +
+	"\x10GetPersonRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"u\n" +
+	"\x11ListPeopleRequest\x12/\n" +
+	"\x06filter\x18\x01 \x01(\v2\x17.person.v1.PersonFilterR\x06filter\x12\x12\n" +
+	"\x04page\x18\x02 \x01(\x05R\x04page\x12\x1b\n" +
+	"\tpage_size\x18\x03 \x01(\x05R\bpageSize\"\\\n" +
+	"\x12ListPeopleResponse\x12)\n" +
+	"\x06people\x18\x01 \x03(\v2\x11.person.v1.PersonR\x06people\x12\x1b\n" +
+	"\tnext_page\x18\x02 \x01(\x05R\bnextPage\"\xb2\x01\n" +
+	"\x13UpdatePersonRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x18\n" +
+	"\asurname\x18\x03 \x01(\tR\asurname\x12#\n" +
+	"\n" +
+	"patronymic\x18\x04 \x01(\tH\x00R\n" +
+	"patronymic\x88\x01\x01\x12)\n" +
+	"\x10expected_version\x18\x05 \x01(\x03R\x0fexpectedVersionB\r\n" +
+	"\v_patronymic\"P\n" +
+	"\x13DeletePersonRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12)\n" +
+	"\x10expected_version\x18\x02 \x01(\x03R\x0fexpectedVersion\"\x7f\n" +
+	"\x12WatchPeopleRequest\x12/\n" +
+	"\x06filter\x18\x01 \x01(\v2\x17.person.v1.PersonFilterR\x06filter\x12\x14\n" +
+	"\x05types\x18\x02 \x03(\tR\x05types\x12\"\n" +
+	"\rlast_event_id\x18\x03 \x01(\x03R\vlastEventId\"a\n" +
+	"\vFieldChange\x12(\n" +
+	"\x03old\x18\x01 \x01(\v2\x16.google.protobuf.ValueR\x03old\x12(\n" +
+	"\x03new\x18\x02 \x01(\v2\x16.google.protobuf.ValueR\x03new\"\x94\x03\n" +
+	"\vPersonEvent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x1b\n" +
+	"\tperson_id\x18\x03 \x01(\x03R\bpersonId\x12\x14\n" +
+	"\x05actor\x18\x04 \x01(\tR\x05actor\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x05 \x01(\tR\trequestId\x12\x16\n" +
+	"\x06source\x18\x06 \x01(\tR\x06source\x12)\n" +
+	"\x06person\x18\a \x01(\v2\x11.person.v1.PersonR\x06person\x12=\n" +
+	"\achanges\x18\b \x03(\v2#.person.v1.PersonEvent.ChangesEntryR\achanges\x129\n" +
+	"\n" +
+	"created_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x1aR\n" +
+	"\fChangesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12,\n" +
+	"\x05value\x18\x02 \x01(\v2\x16.person.v1.FieldChangeR\x05value:\x028\x01*\x80\x01\n" +
+	"\rDeletedFilter\x12\x1e\n" +
+	"\x1aDELETED_FILTER_UNSPECIFIED\x10\x00\x12\x1a\n" +
+	"\x16DELETED_FILTER_EXCLUDE\x10\x01\x12\x17\n" +
+	"\x13DELETED_FILTER_ONLY\x10\x02\x12\x1a\n" +
+	"\x16DELETED_FILTER_INCLUDE\x10\x032\xad\x03\n" +
+	"\rPersonService\x12A\n" +
+	"\fCreatePerson\x12\x1e.person.v1.CreatePersonRequest\x1a\x11.person.v1.Person\x12;\n" +
+	"\tGetPerson\x12\x1b.person.v1.GetPersonRequest\x1a\x11.person.v1.Person\x12I\n" +
+	"\n" +
+	"ListPeople\x12\x1c.person.v1.ListPeopleRequest\x1a\x1d.person.v1.ListPeopleResponse\x12A\n" +
+	"\fUpdatePerson\x12\x1e.person.v1.UpdatePersonRequest\x1a\x11.person.v1.Person\x12F\n" +
+	"\fDeletePerson\x12\x1e.person.v1.DeletePersonRequest\x1a\x16.google.protobuf.Empty\x12F\n" +
+	"\vWatchPeople\x12\x1d.person.v1.WatchPeopleRequest\x1a\x16.person.v1.PersonEvent0\x01B>Z<github.com/RakhimovAns/Person-Service/api/person/v1;personv1b\x06proto3"
+
+var (
+	file_person_v1_person_proto_rawDescOnce sync.Once
+	file_person_v1_person_proto_rawDescData []byte
+)
+
+func file_person_v1_person_proto_rawDescGZIP() []byte {
+	file_person_v1_person_proto_rawDescOnce.Do(func() {
+		file_person_v1_person_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_person_v1_person_proto_rawDesc), len(file_person_v1_person_proto_rawDesc)))
+	})
+	return file_person_v1_person_proto_rawDescData
+}
+
+var file_person_v1_person_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_person_v1_person_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_person_v1_person_proto_goTypes = []any{
+	(DeletedFilter)(0),            // 0: person.v1.DeletedFilter
+	(*Person)(nil),                // 1: person.v1.Person
+	(*PersonFilter)(nil),          // 2: person.v1.PersonFilter
+	(*CreatePersonRequest)(nil),   // 3: person.v1.CreatePersonRequest
+	(*GetPersonRequest)(nil),      // 4: person.v1.GetPersonRequest
+	(*ListPeopleRequest)(nil),     // 5: person.v1.ListPeopleRequest
+	(*ListPeopleResponse)(nil),    // 6: person.v1.ListPeopleResponse
+	(*UpdatePersonRequest)(nil),   // 7: person.v1.UpdatePersonRequest
+	(*DeletePersonRequest)(nil),   // 8: person.v1.DeletePersonRequest
+	(*WatchPeopleRequest)(nil),    // 9: person.v1.WatchPeopleRequest
+	(*FieldChange)(nil),           // 10: person.v1.FieldChange
+	(*PersonEvent)(nil),           // 11: person.v1.PersonEvent
+	nil,                           // 12: person.v1.PersonEvent.ChangesEntry
+	(*timestamppb.Timestamp)(nil), // 13: google.protobuf.Timestamp
+	(*structpb.Value)(nil),        // 14: google.protobuf.Value
+	(*emptypb.Empty)(nil),         // 15: google.protobuf.Empty
+}
+var file_person_v1_person_proto_depIdxs = []int32{
+	13, // 0: person.v1.Person.created_at:type_name -> google.protobuf.Timestamp
+	13, // 1: person.v1.Person.updated_at:type_name -> google.protobuf.Timestamp
+	13, // 2: person.v1.Person.deleted_at:type_name -> google.protobuf.Timestamp
+	13, // 3: person.v1.PersonFilter.created_after:type_name -> google.protobuf.Timestamp
+	13, // 4: person.v1.PersonFilter.updated_since:type_name -> google.protobuf.Timestamp
+	0,  // 5: person.v1.PersonFilter.deleted:type_name -> person.v1.DeletedFilter
+	2,  // 6: person.v1.ListPeopleRequest.filter:type_name -> person.v1.PersonFilter
+	1,  // 7: person.v1.ListPeopleResponse.people:type_name -> person.v1.Person
+	2,  // 8: person.v1.WatchPeopleRequest.filter:type_name -> person.v1.PersonFilter
+	14, // 9: person.v1.FieldChange.old:type_name -> google.protobuf.Value
+	14, // 10: person.v1.FieldChange.new:type_name -> google.protobuf.Value
+	1,  // 11: person.v1.PersonEvent.person:type_name -> person.v1.Person
+	12, // 12: person.v1.PersonEvent.changes:type_name -> person.v1.PersonEvent.ChangesEntry
+	13, // 13: person.v1.PersonEvent.created_at:type_name -> google.protobuf.Timestamp
+	10, // 14: person.v1.PersonEvent.ChangesEntry.value:type_name -> person.v1.FieldChange
+	3,  // 15: person.v1.PersonService.CreatePerson:input_type -> person.v1.CreatePersonRequest
+	4,  // 16: person.v1.PersonService.GetPerson:input_type -> person.v1.GetPersonRequest
+	5,  // 17: person.v1.PersonService.ListPeople:input_type -> person.v1.ListPeopleRequest
+	7,  // 18: person.v1.PersonService.UpdatePerson:input_type -> person.v1.UpdatePersonRequest
+	8,  // 19: person.v1.PersonService.DeletePerson:input_type -> person.v1.DeletePersonRequest
+	9,  // 20: person.v1.PersonService.WatchPeople:input_type -> person.v1.WatchPeopleRequest
+	1,  // 21: person.v1.PersonService.CreatePerson:output_type -> person.v1.Person
+	1,  // 22: person.v1.PersonService.GetPerson:output_type -> person.v1.Person
+	6,  // 23: person.v1.PersonService.ListPeople:output_type -> person.v1.ListPeopleResponse
+	1,  // 24: person.v1.PersonService.UpdatePerson:output_type -> person.v1.Person
+	15, // 25: person.v1.PersonService.DeletePerson:output_type -> google.protobuf.Empty
+	11, // 26: person.v1.PersonService.WatchPeople:output_type -> person.v1.PersonEvent
+	21, // [21:27] is the sub-list for method output_type
+	15, // [15:21] is the sub-list for method input_type
+	15, // [15:15] is the sub-list for extension type_name
+	15, // [15:15] is the sub-list for extension extendee
+	0,  // [0:15] is the sub-list for field type_name
+}
+
+func init() { file_person_v1_person_proto_init() }
+func file_person_v1_person_proto_init() {
+	if File_person_v1_person_proto != nil {
+		return
+	}
+	file_person_v1_person_proto_msgTypes[0].OneofWrappers = []any{}
+	file_person_v1_person_proto_msgTypes[1].OneofWrappers = []any{}
+	file_person_v1_person_proto_msgTypes[2].OneofWrappers = []any{}
+	file_person_v1_person_proto_msgTypes[6].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_person_v1_person_proto_rawDesc), len(file_person_v1_person_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_person_v1_person_proto_goTypes,
+		DependencyIndexes: file_person_v1_person_proto_depIdxs,
+		EnumInfos:         file_person_v1_person_proto_enumTypes,
+		MessageInfos:      file_person_v1_person_proto_msgTypes,
+	}.Build()
+	File_person_v1_person_proto = out.File
+	file_person_v1_person_proto_goTypes = nil
+	file_person_v1_person_proto_depIdxs = nil
+}
@@ -1,14 +1,28 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	_ "github.com/RakhimovAns/Person-Service/docs"
 	"github.com/RakhimovAns/Person-Service/internal/config"
+	"github.com/RakhimovAns/Person-Service/internal/graph"
 	"github.com/RakhimovAns/Person-Service/internal/handler"
 	"github.com/RakhimovAns/Person-Service/internal/repository"
+	"github.com/RakhimovAns/Person-Service/internal/rpc"
 	"github.com/RakhimovAns/Person-Service/internal/service"
+	"github.com/RakhimovAns/Person-Service/internal/tracing"
 	"github.com/RakhimovAns/Person-Service/pkg/client"
 	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/RakhimovAns/Person-Service/pkg/metrics"
+	"github.com/RakhimovAns/Person-Service/pkg/sink"
+	"github.com/RakhimovAns/Person-Service/pkg/webhook"
+	"github.com/jmoiron/sqlx"
 	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
 )
 
 // @title Person Service API
@@ -18,29 +32,307 @@ import (
 // @host localhost:8080
 // @BasePath /api/v1
 // @schemes http
+
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name Authorization
+// @description Bearer token set by ADMIN_TOKEN
 func main() {
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	logger := logging.New(cfg.LogLevel)
+	logger := logging.New(cfg.LogLevel, cfg.LogFormat)
+	if _, err := logging.ParseLevel(cfg.LogLevel); err != nil {
+		logger.Warn("Using log level %s: %v", logger.Level(), err)
+	}
 
-	db, err := repository.NewPostgresDB(cfg.DB)
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		logger.Fatal("Failed to initialize tracing: %v", err)
+	}
+	db, err := repository.NewPostgresDB(cfg.DB, logger)
 	if err != nil {
 		logger.Fatal("Failed to initialize db: %v", err)
 	}
+	metrics.RegisterDB(db.DB, cfg.DB.Name)
+
+	replicas, err := repository.NewPostgresReplicas(cfg.DB, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize read replicas: %v", err)
+	}
+	for i, replica := range replicas.DBs() {
+		metrics.RegisterDB(replica.DB, fmt.Sprintf("%s_replica%d", cfg.DB.Name, i+1))
+	}
+
+	migrator, err := repository.NewMigrator(db, logger)
+	if err != nil {
+		logger.Fatal("Failed to load migrations: %v", err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(context.Background(), migrator, os.Args[2:]); err != nil {
+			logger.Fatal("Migration failed: %v", err)
+		}
+		return
+	}
+
+	if cfg.MigrateOnStart {
+		if err := migrator.Up(context.Background()); err != nil {
+			logger.Fatal("Failed to apply migrations: %v", err)
+		}
+	}
+
+	transactor := repository.NewTransactor(db)
+	personRepo, personTransactor, closeStorage, err := newPersonStorage(cfg.Storage, db, replicas, transactor, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize %s storage: %v", cfg.Storage.Backend, err)
+	}
+	auditRepo := repository.NewAuditRepository(db, logger)
+	importRepo := repository.NewImportRepository(db, logger)
+	idempotencyRepo := repository.NewIdempotencyRepository(db, logger)
+	outboxRepo := repository.NewOutboxRepository(db, logger)
+	webhookRepo := repository.NewWebhookRepository(db, logger)
+	healthRepo := repository.NewHealthRepository(db, logger)
+	agifyClient := client.NewAgifyClient(cfg.AgifyURL, cfg.Providers.BreakerThreshold, cfg.Providers.BreakerCooldown, cfg.Providers.Propagate("agify"), logger)
+	genderizeClient := client.NewGenderizeClient(cfg.GenderizeURL, cfg.Providers.BreakerThreshold, cfg.Providers.BreakerCooldown, cfg.Providers.Propagate("genderize"), logger)
+	nationalizeClient := client.NewNationalizeClient(cfg.NationalizeURL, cfg.Providers.BreakerThreshold, cfg.Providers.BreakerCooldown, cfg.Providers.Propagate("nationalize"), logger)
+
+	broker := service.NewEventBroker(cfg.Stream.BufferSize)
+	personService := service.NewTracedPersonService(service.NewPersonService(
+		personRepo,
+		auditRepo,
+		outboxRepo,
+		personTransactor,
+		broker,
+		agifyClient,
+		genderizeClient,
+		nationalizeClient,
+		cfg.Duplicates,
+		cfg.Batch,
+		logger,
+	))
+	personHandler := handler.NewPersonHandler(personService, cfg.Stream.Heartbeat, logger)
+	importService := service.NewImportService(importRepo, logger)
+	importHandler := handler.NewImportHandler(importService, cfg.Import.MaxBytes, logger)
+	var webhookSecrets *webhook.SecretBox
+	if cfg.Webhooks.SecretKey != "" {
+		if webhookSecrets, err = webhook.NewSecretBox(cfg.Webhooks.SecretKey); err != nil {
+			logger.Fatal("Failed to load WEBHOOK_SECRET_KEY: %v", err)
+		}
+	} else {
+		logger.Warn("WEBHOOK_SECRET_KEY is not set, webhooks cannot be registered")
+	}
+	webhookService := service.NewWebhookService(webhookRepo, webhookSecrets, cfg.Webhooks, logger)
+	webhookHandler := handler.NewWebhookHandler(webhookService, logger)
+	idempotencyService := service.NewIdempotencyService(idempotencyRepo, cfg.Idempotency.TTL, cfg.Idempotency.LockTimeout, logger)
+
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	runWorker := func(run func(ctx context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(workerCtx)
+		}()
+	}
+
+	purgeWorker := service.NewPurgeWorker(personRepo, auditRepo, idempotencyRepo, outboxRepo, personTransactor, cfg.Purge.Retention, cfg.Outbox.Retention, cfg.Purge.Interval, logger)
+	runWorker(purgeWorker.Run)
+
+	importWorker := service.NewImportWorker(importRepo, personService, personTransactor, cfg.Import, logger)
+	runWorker(importWorker.Run)
+
+	eventSink, err := newSink(cfg.Outbox, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize outbox sink: %v", err)
+	}
+	// The internal sinks go first, so that they get each message without
+	// waiting for an external sink that is slow to fail.
+	relaySinks := []sink.Sink{webhookService}
+	if cfg.Listen.Enabled {
+		eventListener := repository.NewEventListener(cfg.DB, cfg.Listen.MinReconnect, cfg.Listen.MaxReconnect, logger)
+		changeListener := service.NewChangeListener(eventListener, outboxRepo, cfg.Listen, cfg.Stream.BufferSize, logger)
+		changeListener.OnChange(broker.Broadcast)
+		runWorker(changeListener.Run)
+	} else {
+		relaySinks = append(relaySinks, broker)
+	}
+	relaySinks = append(relaySinks, eventSink)
+
+	outboxRelay := service.NewOutboxRelay(outboxRepo, sink.NewMulti(relaySinks...), cfg.Outbox, logger)
+	runWorker(outboxRelay.Run)
+
+	webhookWorker := service.NewWebhookWorker(webhookRepo, webhookSecrets, cfg.Webhooks, logger)
+	runWorker(webhookWorker.Run)
+
+	graphHandler := graph.NewHandler(personService, cfg.GraphQL, cfg.Stream.Heartbeat, logger)
 
-	personRepo := repository.NewPersonRepository(db, logger)
-	agifyClient := client.NewAgifyClient(cfg.AgifyURL, logger)
-	genderizeClient := client.NewGenderizeClient(cfg.GenderizeURL, logger)
-	nationalizeClient := client.NewNationalizeClient(cfg.NationalizeURL, logger)
+	adminHandler := handler.NewAdminHandler(cfg.AdminToken, logger)
 
-	personService := service.NewPersonService(personRepo, agifyClient, genderizeClient, nationalizeClient, logger)
-	personHandler := handler.NewPersonHandler(personService, logger)
+	healthService := service.NewHealthService(healthRepo, migrator, []client.Provider{agifyClient, genderizeClient, nationalizeClient}, cfg.Health, logger)
+	healthHandler := handler.NewHealthHandler(healthService, logger)
+
+	server := handler.NewServer(cfg, personHandler, importHandler, webhookHandler, graphHandler, adminHandler, healthHandler, idempotencyService, logger)
+	grpcServer := rpc.NewServer(cfg.GRPC, personService, logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serverErrors := make(chan error, 2)
+	go func() {
+		if err := server.Run(); err != nil {
+			serverErrors <- fmt.Errorf("HTTP server: %w", err)
+		}
+	}()
+	go func() {
+		if err := grpcServer.Run(); err != nil {
+			serverErrors <- fmt.Errorf("gRPC server: %w", err)
+		}
+	}()
+
+	failed := false
+	select {
+	case <-ctx.Done():
+		logger.Info("Shutting down")
+	case err := <-serverErrors:
+		logger.Error("Server error: %v", err)
+		failed = true
+	}
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout)
+	defer cancel()
+
+	// Streams never finish on their own and would hold the servers open.
+	broker.Close()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Failed to drain HTTP requests: %v", err)
+	}
+	grpcServer.Shutdown(shutdownCtx)
+
+	stopWorkers()
+	if !waitGroup(shutdownCtx, &workers) {
+		logger.Warn("Background workers did not stop in time")
+	}
+
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error("Failed to flush traces: %v", err)
+	}
+	if err := closeStorage(); err != nil {
+		logger.Error("Failed to close %s storage: %v", cfg.Storage.Backend, err)
+	}
+	if err := replicas.Close(); err != nil {
+		logger.Error("Failed to close read replicas: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		logger.Error("Failed to close db: %v", err)
+	}
+
+	logger.Info("Shutdown complete")
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// waitGroup waits for wg until ctx is done. It reports whether wg finished.
+func waitGroup(ctx context.Context, wg *sync.WaitGroup) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// newPersonStorage creates the person repository of the configured backend,
+// the transactor that writes to people run in, and a function that closes
+// the backend. People kept outside Postgres cannot share its transactions
+// with the audit log and the outbox, so theirs are chained to transactor.
+func newPersonStorage(cfg config.StorageConfig, db *sqlx.DB, replicas *repository.ReplicaSet, transactor repository.Transactor, logger logging.Logger) (repository.PersonRepository, repository.Transactor, func() error, error) {
+	switch cfg.Backend {
+	case "postgres":
+		return repository.NewPersonRepository(db, replicas, logger), transactor, func() error { return nil }, nil
+	case "memory":
+		memoryDB := repository.NewMemoryDB()
+		chained := repository.NewChainedTransactor(transactor, repository.NewMemoryTransactor(memoryDB), logger)
+		return repository.NewMemoryPersonRepository(memoryDB), chained, func() error { return nil }, nil
+	case "sqlite":
+		sqliteDB, err := repository.NewSQLiteDB(cfg.SQLitePath)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		chained := repository.NewChainedTransactor(transactor, repository.NewTransactor(sqliteDB), logger)
+		return repository.NewSQLitePersonRepository(sqliteDB, logger), chained, sqliteDB.Close, nil
+	default:
+		return nil, nil, nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
+
+// newSink creates the sink configured for the outbox relay. Webhook
+// subscriptions receive events regardless of it.
+func newSink(cfg config.OutboxConfig, logger logging.Logger) (sink.Sink, error) {
+	switch cfg.Sink {
+	case "none":
+		return sink.NewMulti(), nil
+	case "stdout":
+		return sink.NewWriterSink(os.Stdout), nil
+	case "file":
+		file, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		return sink.NewWriterSink(file), nil
+	case "webhook":
+		if cfg.WebhookURL == "" {
+			return nil, fmt.Errorf("OUTBOX_WEBHOOK_URL is required for the webhook sink")
+		}
+		return sink.NewWebhookSink(cfg.WebhookURL, cfg.Timeout, logger), nil
+	default:
+		return nil, fmt.Errorf("unknown outbox sink %q", cfg.Sink)
+	}
+}
+
+// runMigrate handles the "migrate up|down [steps]|status" subcommand.
+func runMigrate(ctx context.Context, migrator *repository.Migrator, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up|down [steps]|status")
+	}
 
-	server := handler.NewServer(cfg, personHandler)
-	if err := server.Run(); err != nil {
-		logger.Fatal("Server error: %v", err)
+	switch args[0] {
+	case "up":
+		return migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+			steps = n
+		}
+		return migrator.Down(ctx, steps)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%06d  %-40s  %s\n", status.Version, status.Name, appliedAt)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
 	}
 }
@@ -0,0 +1,56 @@
+// Command webhook-receiver is a local stand-in for a webhook subscriber. It
+// verifies delivery signatures and prints every delivery it receives.
+//
+//	go run ./cmd/webhook-receiver -addr :9090 -secret <secret>
+//
+// Register http://localhost:9090/ through POST /api/v1/webhooks with the same
+// secret. Use -status to answer with an error and exercise retries.
+package main
+
+import (
+	"flag"
+	"github.com/RakhimovAns/Person-Service/pkg/webhook"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+func main() {
+	addr := flag.String("addr", ":9090", "address to listen on")
+	secret := flag.String("secret", "", "subscription secret; signatures are not checked when empty")
+	status := flag.Int("status", http.StatusNoContent, "status code to answer deliveries with")
+	tolerance := flag.Duration("tolerance", 5*time.Minute, "maximum age of a delivery timestamp")
+	flag.Parse()
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if *secret != "" && !webhook.Verify(*secret, r.Header.Get(webhook.SignatureHeader),
+			r.Header.Get(webhook.TimestampHeader), body, *tolerance) {
+			log.Printf("Rejected delivery %s: invalid signature", r.Header.Get(webhook.DeliveryHeader))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		log.Printf("Delivery %s: event %s (%s) %s",
+			r.Header.Get(webhook.DeliveryHeader),
+			r.Header.Get(webhook.EventIDHeader),
+			r.Header.Get(webhook.EventTypeHeader),
+			body,
+		)
+		w.WriteHeader(*status)
+	})
+
+	log.Printf("Listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
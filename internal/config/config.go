@@ -1,26 +1,215 @@
 package config
 
 import (
+	"fmt"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
 	"github.com/caarlos0/env/v6"
 	"github.com/joho/godotenv"
+	"slices"
+	"time"
 )
 
 type DBConfig struct {
+	// DSN is a connection URL or key/value string. When set, it is used in
+	// place of the individual connection settings.
+	DSN      string `env:"DB_DSN"`
 	Host     string `env:"DB_HOST"`
 	Port     string `env:"DB_PORT"`
 	User     string `env:"DB_USER"`
 	Password string `env:"DB_PASSWORD"`
 	Name     string `env:"DB_NAME"`
 	SSLMode  string `env:"DB_SSLMODE"`
+
+	ApplicationName  string        `env:"DB_APPLICATION_NAME" envDefault:"person-service"`
+	StatementTimeout time.Duration `env:"DB_STATEMENT_TIMEOUT" envDefault:"30s"`
+	MaxOpenConns     int           `env:"DB_MAX_OPEN_CONNS" envDefault:"25"`
+	MaxIdleConns     int           `env:"DB_MAX_IDLE_CONNS" envDefault:"10"`
+	ConnMaxLifetime  time.Duration `env:"DB_CONN_MAX_LIFETIME" envDefault:"30m"`
+	ConnMaxIdleTime  time.Duration `env:"DB_CONN_MAX_IDLE_TIME" envDefault:"5m"`
+
+	ConnectRetries    int           `env:"DB_CONNECT_RETRIES" envDefault:"10"`
+	ConnectMinBackoff time.Duration `env:"DB_CONNECT_MIN_BACKOFF" envDefault:"500ms"`
+	ConnectMaxBackoff time.Duration `env:"DB_CONNECT_MAX_BACKOFF" envDefault:"10s"`
+
+	// ReplicaDSNs are the read replicas, as connection URLs or key/value
+	// strings, separated by commas.
+	ReplicaDSNs          []string      `env:"DB_REPLICA_DSNS" envSeparator:","`
+	MaxReplicaLag        time.Duration `env:"DB_MAX_REPLICA_LAG" envDefault:"5s"`
+	ReplicaCheckInterval time.Duration `env:"DB_REPLICA_CHECK_INTERVAL" envDefault:"5s"`
+}
+
+// StorageConfig selects where people are stored: postgres, memory or
+// sqlite. Everything else is kept in Postgres whichever it is.
+type StorageConfig struct {
+	Backend    string `env:"STORAGE_BACKEND" envDefault:"postgres"`
+	SQLitePath string `env:"STORAGE_SQLITE_PATH" envDefault:"people.db"`
+}
+
+type DuplicateConfig struct {
+	Policy    string  `env:"DUPLICATE_POLICY" envDefault:"warn"`
+	Threshold float64 `env:"DUPLICATE_THRESHOLD" envDefault:"0.9"`
+}
+
+func (c DuplicateConfig) validate() error {
+	switch c.Policy {
+	case domain.DuplicatePolicyWarn, domain.DuplicatePolicyReject, domain.DuplicatePolicyExisting:
+	default:
+		return fmt.Errorf("DUPLICATE_POLICY must be %s, %s or %s, got %q",
+			domain.DuplicatePolicyWarn, domain.DuplicatePolicyReject, domain.DuplicatePolicyExisting, c.Policy)
+	}
+	if c.Threshold < 0 || c.Threshold > 1 {
+		return fmt.Errorf("DUPLICATE_THRESHOLD must be between 0 and 1, got %v", c.Threshold)
+	}
+	return nil
+}
+
+type PurgeConfig struct {
+	Retention time.Duration `env:"PURGE_RETENTION" envDefault:"720h"`
+	Interval  time.Duration `env:"PURGE_INTERVAL" envDefault:"1h"`
+}
+
+type BatchConfig struct {
+	MaxItems    int `env:"BATCH_MAX_ITEMS" envDefault:"100"`
+	Concurrency int `env:"BATCH_CONCURRENCY" envDefault:"5"`
+	ChunkSize   int `env:"BATCH_CHUNK_SIZE" envDefault:"50"`
+}
+
+type ImportConfig struct {
+	MaxBytes     int64         `env:"IMPORT_MAX_BYTES" envDefault:"10485760"`
+	PollInterval time.Duration `env:"IMPORT_POLL_INTERVAL" envDefault:"5s"`
+	Lease        time.Duration `env:"IMPORT_LEASE" envDefault:"5m"`
+}
+
+type IdempotencyConfig struct {
+	TTL time.Duration `env:"IDEMPOTENCY_TTL" envDefault:"24h"`
+	// MaxBodyBytes caps the request bodies read to tell retries apart.
+	MaxBodyBytes int64 `env:"IDEMPOTENCY_MAX_BODY_BYTES" envDefault:"10485760"`
+	// LockTimeout is how long a key stays reserved without a response
+	// before a retry takes it over, as happens when the server dies
+	// mid-request. It must exceed the longest request.
+	LockTimeout time.Duration `env:"IDEMPOTENCY_LOCK_TIMEOUT" envDefault:"1m"`
+}
+
+type OutboxConfig struct {
+	Sink         string        `env:"OUTBOX_SINK" envDefault:"stdout"`
+	File         string        `env:"OUTBOX_FILE" envDefault:"outbox.jsonl"`
+	WebhookURL   string        `env:"OUTBOX_WEBHOOK_URL"`
+	Timeout      time.Duration `env:"OUTBOX_TIMEOUT" envDefault:"10s"`
+	PollInterval time.Duration `env:"OUTBOX_POLL_INTERVAL" envDefault:"1s"`
+	BatchSize    int           `env:"OUTBOX_BATCH_SIZE" envDefault:"100"`
+	MaxBackoff   time.Duration `env:"OUTBOX_MAX_BACKOFF" envDefault:"5m"`
+	// Lease is how long a relay holds the events it claimed before another
+	// relay may publish them again.
+	Lease time.Duration `env:"OUTBOX_LEASE" envDefault:"5m"`
+	// Retention is how long published events are kept for stream resumes.
+	Retention time.Duration `env:"OUTBOX_RETENTION" envDefault:"168h"`
+}
+
+type WebhookConfig struct {
+	Timeout      time.Duration `env:"WEBHOOK_TIMEOUT" envDefault:"10s"`
+	PollInterval time.Duration `env:"WEBHOOK_POLL_INTERVAL" envDefault:"1s"`
+	BatchSize    int           `env:"WEBHOOK_BATCH_SIZE" envDefault:"20"`
+	MaxAttempts  int           `env:"WEBHOOK_MAX_ATTEMPTS" envDefault:"8"`
+	MinBackoff   time.Duration `env:"WEBHOOK_MIN_BACKOFF" envDefault:"10s"`
+	MaxBackoff   time.Duration `env:"WEBHOOK_MAX_BACKOFF" envDefault:"1h"`
+	// Lease is how long a worker holds the deliveries it claimed before
+	// another worker may send them again.
+	Lease time.Duration `env:"WEBHOOK_LEASE" envDefault:"5m"`
+	// SecretKey is the hex-encoded 32-byte key subscription secrets are
+	// encrypted with. Webhooks cannot be registered without it.
+	SecretKey string `env:"WEBHOOK_SECRET_KEY"`
+	// AllowPrivateTargets lets webhooks reach loopback and private
+	// addresses. Only meant for local development.
+	AllowPrivateTargets bool `env:"WEBHOOK_ALLOW_PRIVATE_TARGETS" envDefault:"false"`
+}
+
+type StreamConfig struct {
+	BufferSize int           `env:"STREAM_BUFFER_SIZE" envDefault:"1000"`
+	Heartbeat  time.Duration `env:"STREAM_HEARTBEAT" envDefault:"15s"`
+}
+
+type ListenConfig struct {
+	Enabled        bool          `env:"LISTEN_ENABLED" envDefault:"true"`
+	MinReconnect   time.Duration `env:"LISTEN_MIN_RECONNECT" envDefault:"1s"`
+	MaxReconnect   time.Duration `env:"LISTEN_MAX_RECONNECT" envDefault:"1m"`
+	BackfillWindow time.Duration `env:"LISTEN_BACKFILL_WINDOW" envDefault:"1m"`
+}
+
+type GraphQLConfig struct {
+	MaxDepth int `env:"GRAPHQL_MAX_DEPTH" envDefault:"10"`
+}
+
+type GRPCConfig struct {
+	Port       string `env:"GRPC_PORT" envDefault:"50051"`
+	Reflection bool   `env:"GRPC_REFLECTION" envDefault:"true"`
+}
+
+type HTTPConfig struct {
+	ReadTimeout       time.Duration `env:"HTTP_READ_TIMEOUT" envDefault:"30s"`
+	ReadHeaderTimeout time.Duration `env:"HTTP_READ_HEADER_TIMEOUT" envDefault:"5s"`
+	WriteTimeout      time.Duration `env:"HTTP_WRITE_TIMEOUT" envDefault:"30s"`
+	IdleTimeout       time.Duration `env:"HTTP_IDLE_TIMEOUT" envDefault:"2m"`
+	MaxHeaderBytes    int           `env:"HTTP_MAX_HEADER_BYTES" envDefault:"1048576"`
+}
+
+type ShutdownConfig struct {
+	Timeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"30s"`
+}
+
+type ProviderConfig struct {
+	BreakerThreshold int           `env:"PROVIDER_BREAKER_THRESHOLD" envDefault:"5"`
+	BreakerCooldown  time.Duration `env:"PROVIDER_BREAKER_COOLDOWN" envDefault:"30s"`
+	// NoPropagation names the providers, out of agify, genderize and
+	// nationalize, that are sent no trace context or baggage.
+	NoPropagation []string `env:"PROVIDER_NO_PROPAGATION" envSeparator:","`
+}
+
+// Propagate reports whether the trace context is propagated to provider.
+func (c ProviderConfig) Propagate(provider string) bool {
+	return !slices.Contains(c.NoPropagation, provider)
+}
+
+type HealthConfig struct {
+	Timeout                time.Duration `env:"HEALTH_TIMEOUT" envDefault:"2s"`
+	FailOnDegradedProvider bool          `env:"HEALTH_FAIL_ON_DEGRADED_PROVIDER" envDefault:"false"`
+}
+
+type TracingConfig struct {
+	Exporter    string  `env:"TRACING_EXPORTER" envDefault:"none"`
+	Endpoint    string  `env:"TRACING_ENDPOINT"`
+	Insecure    bool    `env:"TRACING_INSECURE" envDefault:"true"`
+	File        string  `env:"TRACING_FILE" envDefault:"traces.jsonl"`
+	SampleRatio float64 `env:"TRACING_SAMPLE_RATIO" envDefault:"1"`
+	ServiceName string  `env:"TRACING_SERVICE_NAME" envDefault:"person-service"`
 }
 
 type Config struct {
 	Port           string `env:"PORT" envDefault:"8080"`
+	HTTP           HTTPConfig
+	Shutdown       ShutdownConfig
 	LogLevel       string `env:"LOG_LEVEL" envDefault:"info"`
+	LogFormat      string `env:"LOG_FORMAT" envDefault:"text"`
+	AdminToken     string `env:"ADMIN_TOKEN"`
+	MigrateOnStart bool   `env:"MIGRATE_ON_START" envDefault:"true"`
 	DB             DBConfig
+	Storage        StorageConfig
 	AgifyURL       string `env:"AGIFY_URL" envDefault:"https://api.agify.io"`
 	GenderizeURL   string `env:"GENDERIZE_URL" envDefault:"https://api.genderize.io"`
 	NationalizeURL string `env:"NATIONALIZE_URL" envDefault:"https://api.nationalize.io"`
+	Providers      ProviderConfig
+	Duplicates     DuplicateConfig
+	Purge          PurgeConfig
+	Batch          BatchConfig
+	Import         ImportConfig
+	Idempotency    IdempotencyConfig
+	Outbox         OutboxConfig
+	Webhooks       WebhookConfig
+	Stream         StreamConfig
+	Listen         ListenConfig
+	GRPC           GRPCConfig
+	GraphQL        GraphQLConfig
+	Tracing        TracingConfig
+	Health         HealthConfig
 }
 
 func Load() (*Config, error) {
@@ -30,6 +219,9 @@ func Load() (*Config, error) {
 	if err := env.Parse(cfg); err != nil {
 		return nil, err
 	}
+	if err := cfg.Duplicates.validate(); err != nil {
+		return nil, err
+	}
 
 	return cfg, nil
 }
@@ -1,17 +1,29 @@
 package controller
 
 import (
+	"context"
 	"github.com/RakhimovAns/Person-Service/internal/domain"
 	"github.com/RakhimovAns/Person-Service/internal/service"
 	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
 )
 
 type PersonController interface {
-	Create(person domain.PersonInput) (domain.Person, error)
-	GetAll(filter domain.PersonFilter, page, limit int) ([]domain.Person, error)
-	GetByID(id int) (domain.Person, error)
-	Update(id int, person domain.PersonInput) (domain.Person, error)
-	Delete(id int) error
+	Create(ctx context.Context, person domain.PersonInput) (domain.Person, error)
+	PrepareCreate(ctx context.Context, person domain.PersonInput) (domain.Person, error)
+	CreatePrepared(ctx context.Context, person domain.Person) (domain.Person, error)
+	GetAll(ctx context.Context, filter domain.PersonFilter, page, limit int) ([]domain.Person, error)
+	Export(ctx context.Context, filter domain.PersonFilter, fn func(person domain.Person) error) error
+	GetByID(ctx context.Context, id int) (domain.Person, error)
+	Update(ctx context.Context, id int, person domain.PersonInput, versions []int) (domain.Person, error)
+	Patch(ctx context.Context, id int, patch domain.PersonPatch, versions []int) (domain.Person, error)
+	Delete(ctx context.Context, id int, versions []int) error
+	Merge(ctx context.Context, targetID, sourceID int, versions []int) (domain.Person, error)
+	Restore(ctx context.Context, id int) (domain.Person, error)
+	Enrich(ctx context.Context, id int) (domain.Person, error)
+	History(ctx context.Context, id int) ([]domain.AuditEntry, error)
+	HistoryBatch(ctx context.Context, ids []int) (map[int][]domain.AuditEntry, error)
+	CreateBatch(ctx context.Context, inputs []domain.PersonInput, mode string) (domain.BatchResult, error)
+	Watch(ctx context.Context, filter domain.PersonFilter, lastEventID int64) <-chan domain.Event
 }
 
 type personController struct {
@@ -26,27 +38,82 @@ func NewPersonController(service service.PersonService, logger logging.Logger) P
 	}
 }
 
-func (c *personController) Create(person domain.PersonInput) (domain.Person, error) {
+func (c *personController) Create(ctx context.Context, person domain.PersonInput) (domain.Person, error) {
 	c.logger.Debug("Creating person: %+v", person)
-	return c.service.Create(person)
+	return c.service.Create(ctx, person)
 }
 
-func (c *personController) GetAll(filter domain.PersonFilter, page, limit int) ([]domain.Person, error) {
+func (c *personController) PrepareCreate(ctx context.Context, person domain.PersonInput) (domain.Person, error) {
+	c.logger.Debug("Preparing person: %+v", person)
+	return c.service.PrepareCreate(ctx, person)
+}
+
+func (c *personController) CreatePrepared(ctx context.Context, person domain.Person) (domain.Person, error) {
+	c.logger.Debug("Creating prepared person: %+v", person)
+	return c.service.CreatePrepared(ctx, person)
+}
+
+func (c *personController) GetAll(ctx context.Context, filter domain.PersonFilter, page, limit int) ([]domain.Person, error) {
 	c.logger.Debug("Getting all persons with filter: %+v, page: %d, limit: %d", filter, page, limit)
-	return c.service.GetAll(filter, page, limit)
+	return c.service.GetAll(ctx, filter, page, limit)
+}
+
+func (c *personController) Export(ctx context.Context, filter domain.PersonFilter, fn func(person domain.Person) error) error {
+	c.logger.Debug("Exporting persons with filter: %+v", filter)
+	return c.service.Export(ctx, filter, fn)
 }
 
-func (c *personController) GetByID(id int) (domain.Person, error) {
+func (c *personController) GetByID(ctx context.Context, id int) (domain.Person, error) {
 	c.logger.Debug("Getting person by ID: %d", id)
-	return c.service.GetByID(id)
+	return c.service.GetByID(ctx, id)
 }
 
-func (c *personController) Update(id int, person domain.PersonInput) (domain.Person, error) {
+func (c *personController) Update(ctx context.Context, id int, person domain.PersonInput, versions []int) (domain.Person, error) {
 	c.logger.Debug("Updating person with ID: %d, data: %+v", id, person)
-	return c.service.Update(id, person)
+	return c.service.Update(ctx, id, person, versions)
 }
 
-func (c *personController) Delete(id int) error {
+func (c *personController) Patch(ctx context.Context, id int, patch domain.PersonPatch, versions []int) (domain.Person, error) {
+	c.logger.Debug("Patching person with ID: %d, data: %+v", id, patch)
+	return c.service.Patch(ctx, id, patch, versions)
+}
+
+func (c *personController) Delete(ctx context.Context, id int, versions []int) error {
 	c.logger.Debug("Deleting person with ID: %d", id)
-	return c.service.Delete(id)
+	return c.service.Delete(ctx, id, versions)
+}
+
+func (c *personController) Merge(ctx context.Context, targetID, sourceID int, versions []int) (domain.Person, error) {
+	c.logger.Debug("Merging person %d into %d", sourceID, targetID)
+	return c.service.Merge(ctx, targetID, sourceID, versions)
+}
+
+func (c *personController) Restore(ctx context.Context, id int) (domain.Person, error) {
+	c.logger.Debug("Restoring person with ID: %d", id)
+	return c.service.Restore(ctx, id)
+}
+
+func (c *personController) Enrich(ctx context.Context, id int) (domain.Person, error) {
+	c.logger.Debug("Enriching person with ID: %d", id)
+	return c.service.Enrich(ctx, id)
+}
+
+func (c *personController) History(ctx context.Context, id int) ([]domain.AuditEntry, error) {
+	c.logger.Debug("Getting history of person with ID: %d", id)
+	return c.service.History(ctx, id)
+}
+
+func (c *personController) HistoryBatch(ctx context.Context, ids []int) (map[int][]domain.AuditEntry, error) {
+	c.logger.Debug("Getting history of %d people", len(ids))
+	return c.service.HistoryBatch(ctx, ids)
+}
+
+func (c *personController) CreateBatch(ctx context.Context, inputs []domain.PersonInput, mode string) (domain.BatchResult, error) {
+	c.logger.Debug("Creating batch of %d persons in %s mode", len(inputs), mode)
+	return c.service.CreateBatch(ctx, inputs, mode)
+}
+
+func (c *personController) Watch(ctx context.Context, filter domain.PersonFilter, lastEventID int64) <-chan domain.Event {
+	c.logger.Debug("Watching persons after event %d", lastEventID)
+	return c.service.Watch(ctx, filter, lastEventID)
 }
@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+	AuditActionMerge   = "merge"
+	AuditActionEnrich  = "enrich"
+	AuditActionPurge   = "purge"
+)
+
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+type AuditEntry struct {
+	ID        int64                  `json:"id"`
+	PersonID  int                    `json:"person_id"`
+	Action    string                 `json:"action"`
+	Actor     string                 `json:"actor"`
+	RequestID string                 `json:"request_id,omitempty"`
+	Source    string                 `json:"source"`
+	OldData   json.RawMessage        `json:"old_data" swaggertype:"object"`
+	NewData   json.RawMessage        `json:"new_data" swaggertype:"object"`
+	Diff      map[string]FieldChange `json:"diff"`
+	CreatedAt time.Time              `json:"created_at"`
+}
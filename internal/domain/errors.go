@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNotFound     = errors.New("person not found")
+	ErrDuplicate    = errors.New("duplicate person")
+	ErrInvalidMerge = errors.New("invalid merge")
+
+	ErrVersionMismatch = errors.New("person version mismatch")
+
+	ErrInvalidBatch = errors.New("invalid batch")
+
+	ErrImportNotFound  = errors.New("import job not found")
+	ErrInvalidImport   = errors.New("invalid import")
+	ErrImportLeaseLost = errors.New("import job leased to another worker")
+
+	ErrIdempotencyMismatch   = errors.New("idempotency key reused with a different request")
+	ErrIdempotencyInProgress = errors.New("idempotency key request in progress")
+	ErrIdempotencyKeyLost    = errors.New("idempotency key taken over by another request")
+
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrInvalidWebhook   = errors.New("invalid webhook")
+	ErrWebhooksDisabled = errors.New("webhooks are disabled")
+)
+
+type DuplicateError struct {
+	Existing   Person
+	Similarity float64
+	Policy     string
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("duplicate of person %d (similarity %.2f)", e.Existing.ID, e.Similarity)
+}
+
+func (e *DuplicateError) Unwrap() error {
+	return ErrDuplicate
+}
+
+type MovedError struct {
+	ID int
+}
+
+func (e *MovedError) Error() string {
+	return fmt.Sprintf("person was merged into %d", e.ID)
+}
+
+func (e *MovedError) Unwrap() error {
+	return ErrNotFound
+}
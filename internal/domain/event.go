@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+const (
+	EventPersonCreated  = "person.created"
+	EventPersonUpdated  = "person.updated"
+	EventPersonDeleted  = "person.deleted"
+	EventPersonEnriched = "person.enriched"
+)
+
+// Event is a domain event about a change to a person. Person holds the state
+// after the change, or the last state before it for person.deleted.
+type Event struct {
+	ID        int64                  `json:"id"`
+	Type      string                 `json:"type"`
+	PersonID  int                    `json:"person_id"`
+	Actor     string                 `json:"actor"`
+	RequestID string                 `json:"request_id,omitempty"`
+	Source    string                 `json:"source"`
+	Person    *Person                `json:"person"`
+	Changes   map[string]FieldChange `json:"changes,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+	Attempts  int                    `json:"-"`
+}
@@ -0,0 +1,54 @@
+package domain
+
+import "time"
+
+const (
+	HealthStatusUp       = "up"
+	HealthStatusDegraded = "degraded"
+	HealthStatusDown     = "down"
+)
+
+const (
+	ReadinessReady    = "ready"
+	ReadinessDegraded = "degraded"
+	ReadinessNotReady = "not_ready"
+)
+
+type Readiness struct {
+	Status     string          `json:"status"`
+	Database   DatabaseCheck   `json:"database"`
+	Migrations MigrationsCheck `json:"migrations"`
+	Providers  []ProviderCheck `json:"providers"`
+}
+
+type DatabaseCheck struct {
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+type MigrationsCheck struct {
+	Status  string `json:"status"`
+	Applied int    `json:"applied"`
+	Pending int    `json:"pending"`
+	Error   string `json:"error,omitempty"`
+}
+
+type ProviderCheck struct {
+	Name           string         `json:"name"`
+	Status         string         `json:"status"`
+	Reachable      bool           `json:"reachable"`
+	CircuitBreaker string         `json:"circuit_breaker"`
+	Quota          *ProviderQuota `json:"quota,omitempty"`
+	Error          string         `json:"error,omitempty"`
+	LastError      string         `json:"last_error,omitempty"`
+	LastErrorAt    *time.Time     `json:"last_error_at,omitempty"`
+}
+
+// ProviderQuota is the rate limit the provider reported in its last
+// response. It is unknown until the provider has been called.
+type ProviderQuota struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	ResetAt   time.Time `json:"reset_at"`
+}
@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// IdempotencyRecord is the stored outcome of a request made with an
+// Idempotency-Key header. StatusCode is nil while the request is in flight.
+type IdempotencyRecord struct {
+	Key         string
+	RequestHash string
+	StatusCode  *int
+	Headers     map[string]string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
@@ -0,0 +1,40 @@
+package domain
+
+import "time"
+
+const (
+	ImportFormatCSV   = "csv"
+	ImportFormatJSONL = "jsonl"
+)
+
+const (
+	ImportStatusPending   = "pending"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+type ImportJob struct {
+	ID        int64  `json:"id"`
+	Status    string `json:"status"`
+	Format    string `json:"format"`
+	Filename  string `json:"filename"`
+	Actor     string `json:"actor"`
+	Total     int    `json:"total"`
+	Processed int    `json:"processed"`
+	Succeeded int    `json:"succeeded"`
+	// Skipped counts records that matched a stored person under the
+	// "existing" duplicate policy.
+	Skipped    int        `json:"skipped"`
+	Failed     int        `json:"failed"`
+	Error      *string    `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty" db:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+type ImportError struct {
+	Line  int    `json:"line"`
+	Raw   string `json:"raw"`
+	Error string `json:"error"`
+}
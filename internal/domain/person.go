@@ -1,13 +1,23 @@
 package domain
 
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
 type Person struct {
-	ID          int     `json:"id"`
-	Name        string  `json:"name"`
-	Surname     string  `json:"surname"`
-	Patronymic  *string `json:"patronymic,omitempty"`
-	Age         int     `json:"age"`
-	Gender      string  `json:"gender"`
-	Nationality string  `json:"nationality"`
+	ID          int        `json:"id"`
+	Name        string     `json:"name"`
+	Surname     string     `json:"surname"`
+	Patronymic  *string    `json:"patronymic,omitempty"`
+	Age         int        `json:"age"`
+	Gender      string     `json:"gender"`
+	Nationality string     `json:"nationality"`
+	Version     int        `json:"version"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
 }
 
 type PersonInput struct {
@@ -16,11 +26,142 @@ type PersonInput struct {
 	Patronymic *string `json:"patronymic,omitempty"`
 }
 
+// PersonPatch lists the fields to change. Patronymic can also be set to
+// null to clear it.
+type PersonPatch struct {
+	Name       *string          `json:"name,omitempty"`
+	Surname    *string          `json:"surname,omitempty"`
+	Patronymic Nullable[string] `json:"patronymic,omitempty" swaggertype:"string"`
+}
+
+// Nullable is a JSON field that tells apart being absent from being null.
+type Nullable[T any] struct {
+	// Set is true when the field was present, even as null.
+	Set   bool
+	Value *T
+}
+
+func (n *Nullable[T]) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	if string(data) == "null" {
+		n.Value = nil
+		return nil
+	}
+
+	var value T
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	n.Value = &value
+	return nil
+}
+
 type PersonFilter struct {
-	Name        *string `json:"name,omitempty"`
-	Surname     *string `json:"surname,omitempty"`
-	Patronymic  *string `json:"patronymic,omitempty"`
-	Age         *int    `json:"age,omitempty"`
-	Gender      *string `json:"gender,omitempty"`
-	Nationality *string `json:"nationality,omitempty"`
+	Name         *string    `json:"name,omitempty"`
+	Surname      *string    `json:"surname,omitempty"`
+	Patronymic   *string    `json:"patronymic,omitempty"`
+	Age          *int       `json:"age,omitempty"`
+	Gender       *string    `json:"gender,omitempty"`
+	Nationality  *string    `json:"nationality,omitempty"`
+	CreatedAfter *time.Time `json:"created_after,omitempty"`
+	UpdatedSince *time.Time `json:"updated_since,omitempty"`
+	Deleted      string     `json:"deleted,omitempty"`
+	// Sort is a field from SortFields, prefixed with "-" for descending
+	// order. Empty sorts by ID.
+	Sort string `json:"sort,omitempty"`
+}
+
+// SortFields are the fields people can be sorted by.
+var SortFields = []string{"id", "name", "surname", "age", "created_at", "updated_at"}
+
+// ValidSort reports whether sort is empty or names one of SortFields.
+func ValidSort(sort string) bool {
+	if sort == "" {
+		return true
+	}
+	field := strings.TrimPrefix(sort, "-")
+	for _, sortField := range SortFields {
+		if field == sortField {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether person satisfies the filter, applying the same
+// rules as the repository does in SQL.
+func (f PersonFilter) Matches(person Person) bool {
+	switch f.Deleted {
+	case DeletedOnly:
+		if person.DeletedAt == nil {
+			return false
+		}
+	case DeletedInclude:
+	default:
+		if person.DeletedAt != nil {
+			return false
+		}
+	}
+
+	switch {
+	case f.Name != nil && person.Name != *f.Name,
+		f.Surname != nil && person.Surname != *f.Surname,
+		f.Patronymic != nil && (person.Patronymic == nil || *person.Patronymic != *f.Patronymic),
+		f.Age != nil && person.Age != *f.Age,
+		f.Gender != nil && person.Gender != *f.Gender,
+		f.Nationality != nil && person.Nationality != *f.Nationality,
+		f.CreatedAfter != nil && !person.CreatedAt.After(*f.CreatedAfter),
+		f.UpdatedSince != nil && person.UpdatedAt.Before(*f.UpdatedSince):
+		return false
+	}
+
+	return true
+}
+
+type MergeInput struct {
+	SourceID int `json:"source_id" binding:"required"`
+}
+
+const (
+	DuplicatePolicyWarn     = "warn"
+	DuplicatePolicyReject   = "reject"
+	DuplicatePolicyExisting = "existing"
+)
+
+const (
+	DeletedExclude = "exclude"
+	DeletedOnly    = "only"
+	DeletedInclude = "include"
+)
+
+const (
+	BatchModeAtomic  = "atomic"
+	BatchModePartial = "partial"
+)
+
+const (
+	BatchStatusCreated  = "created"
+	BatchStatusExisting = "existing"
+	BatchStatusFailed   = "failed"
+	BatchStatusSkipped  = "skipped"
+	BatchStatusUnknown  = "unknown" // stored in a transaction that may not have committed
+)
+
+type BatchInput struct {
+	Items []PersonInput `json:"items" binding:"required"`
+	Mode  string        `json:"mode,omitempty" enums:"atomic,partial"`
+}
+
+type BatchItemResult struct {
+	Index  int     `json:"index"`
+	Status string  `json:"status"`
+	Person *Person `json:"person,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+type BatchResult struct {
+	Mode    string            `json:"mode"`
+	Created int               `json:"created"`
+	Failed  int               `json:"failed"`
+	Items   []BatchItemResult `json:"items"`
 }
@@ -0,0 +1,53 @@
+package domain
+
+import "time"
+
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusSucceeded = "succeeded"
+	DeliveryStatusFailed    = "failed"
+)
+
+// EventTypes lists the event types a webhook can subscribe to.
+var EventTypes = []string{EventPersonCreated, EventPersonUpdated, EventPersonDeleted, EventPersonEnriched}
+
+type WebhookInput struct {
+	URL string `json:"url" binding:"required,url"`
+	// Events filters the event types delivered; empty means all.
+	Events []string `json:"events"`
+	// Secret signs deliveries; one is generated, and returned once, when
+	// empty.
+	Secret string `json:"secret"`
+}
+
+// WebhookSubscription is a registered webhook. Secret is only returned when
+// it was generated on creation.
+type WebhookSubscription struct {
+	ID        int64     `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64      `json:"id"`
+	SubscriptionID int64      `json:"subscription_id" db:"subscription_id"`
+	EventID        int64      `json:"event_id" db:"event_id"`
+	EventType      string     `json:"event_type" db:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	ResponseStatus *int       `json:"response_status,omitempty" db:"response_status"`
+	Error          *string    `json:"error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty" db:"delivered_at"`
+}
+
+// WebhookDispatch is a due delivery with everything needed to send it.
+type WebhookDispatch struct {
+	Delivery WebhookDelivery
+	URL      string
+	Secret   string
+	Payload  []byte
+}
@@ -0,0 +1,59 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+)
+
+// resolverError is reported with its code, and any details, in the error
+// extensions so that clients need not match on messages.
+type resolverError struct {
+	message    string
+	extensions map[string]interface{}
+}
+
+func (e *resolverError) Error() string {
+	return e.message
+}
+
+func (e *resolverError) Extensions() map[string]interface{} {
+	return e.extensions
+}
+
+func newError(code, message string) error {
+	return &resolverError{
+		message:    message,
+		extensions: map[string]interface{}{"code": code},
+	}
+}
+
+// resolveError maps service errors to the codes matching the REST status
+// codes for the same errors.
+func resolveError(err error, message string) error {
+	var moved *domain.MovedError
+	var duplicate *domain.DuplicateError
+
+	switch {
+	case errors.As(err, &moved):
+		return &resolverError{
+			message:    fmt.Sprintf("Person was merged into %d", moved.ID),
+			extensions: map[string]interface{}{"code": "NOT_FOUND", "merged_into": moved.ID},
+		}
+	case errors.Is(err, domain.ErrNotFound):
+		return newError("NOT_FOUND", "Person not found")
+	case errors.As(err, &duplicate):
+		return &resolverError{
+			message: "Person already exists",
+			extensions: map[string]interface{}{
+				"code":        "ALREADY_EXISTS",
+				"existing_id": duplicate.Existing.ID,
+				"similarity":  duplicate.Similarity,
+			},
+		}
+	case errors.Is(err, domain.ErrVersionMismatch):
+		return newError("PRECONDITION_FAILED", "Person has been modified")
+	default:
+		return newError("INTERNAL", message)
+	}
+}
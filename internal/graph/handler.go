@@ -0,0 +1,141 @@
+package graph
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"github.com/RakhimovAns/Person-Service/internal/config"
+	"github.com/RakhimovAns/Person-Service/internal/service"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/graph-gophers/graphql-go"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//go:embed schema.graphql
+var schema string
+
+type readOnlyKey struct{}
+
+// Handler serves GraphQL over HTTP at /graphql. Queries and mutations are
+// POSTed as JSON; GET runs queries only. Requests accepting
+// text/event-stream, which subscriptions need, get their results as
+// Server-Sent Events: a "next" event per result and "complete" at the end.
+type Handler struct {
+	schema    *graphql.Schema
+	heartbeat time.Duration
+	logger    logging.Logger
+}
+
+func NewHandler(service service.PersonService, cfg config.GraphQLConfig, heartbeat time.Duration, logger logging.Logger) *Handler {
+	resolver := &Resolver{
+		service: service,
+		logger:  logger,
+	}
+
+	return &Handler{
+		schema: graphql.MustParseSchema(schema, resolver,
+			graphql.UseStringDescriptions(),
+			graphql.MaxDepth(cfg.MaxDepth),
+		),
+		heartbeat: heartbeat,
+		logger:    logger,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *gin.Engine) {
+	router.GET("/graphql", h.Serve)
+	router.POST("/graphql", h.Serve)
+}
+
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+func (h *Handler) Serve(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req request
+	if c.Request.Method == http.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				h.logger.Debug("Invalid variables parameter: %v", err)
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variables parameter"})
+				return
+			}
+		}
+		ctx = context.WithValue(ctx, readOnlyKey{}, true)
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Debug("Invalid request body: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if req.Query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query is required"})
+		return
+	}
+
+	if strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
+		h.stream(c, ctx, req)
+		return
+	}
+
+	c.JSON(http.StatusOK, h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+}
+
+func (h *Handler) stream(c *gin.Context, ctx context.Context, req request) {
+	responses, err := h.schema.Subscribe(ctx, req.Query, req.OperationName, req.Variables)
+	if err != nil {
+		h.logger.Error("Failed to subscribe: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to subscribe"})
+		return
+	}
+
+	// Subscriptions outlive the server write timeout.
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case response, ok := <-responses:
+			if !ok {
+				c.Render(-1, sse.Event{Event: "complete", Data: ""})
+				return false
+			}
+			c.Render(-1, sse.Event{Event: "next", Data: response})
+			return true
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
+
+// checkWritable rejects mutations sent with GET, which must be safe to
+// repeat and may be issued by the browser on another site's behalf.
+func checkWritable(ctx context.Context) error {
+	if readOnly, _ := ctx.Value(readOnlyKey{}).(bool); readOnly {
+		return newError("BAD_REQUEST", "Mutations must be sent with POST")
+	}
+	return nil
+}
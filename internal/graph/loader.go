@@ -0,0 +1,64 @@
+package graph
+
+import (
+	"context"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/internal/service"
+	"sync"
+)
+
+// historyLoader batches the history lookups of the people resolved by one
+// field. The field primes it with every person it returns, so the first
+// history or enrichment resolved loads them all in a single query instead
+// of one query per person.
+type historyLoader struct {
+	service service.PersonService
+	mu      sync.Mutex
+	pending map[int]struct{}
+	loaded  map[int][]domain.AuditEntry
+}
+
+func newHistoryLoader(service service.PersonService) *historyLoader {
+	return &historyLoader{
+		service: service,
+		pending: make(map[int]struct{}),
+		loaded:  make(map[int][]domain.AuditEntry),
+	}
+}
+
+func (l *historyLoader) prime(ids ...int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, id := range ids {
+		if _, ok := l.loaded[id]; !ok {
+			l.pending[id] = struct{}{}
+		}
+	}
+}
+
+func (l *historyLoader) load(ctx context.Context, id int) ([]domain.AuditEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if entries, ok := l.loaded[id]; ok {
+		return entries, nil
+	}
+
+	l.pending[id] = struct{}{}
+	ids := make([]int, 0, len(l.pending))
+	for pendingID := range l.pending {
+		ids = append(ids, pendingID)
+	}
+
+	history, err := l.service.HistoryBatch(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pendingID := range ids {
+		l.loaded[pendingID] = history[pendingID]
+		delete(l.pending, pendingID)
+	}
+	return l.loaded[id], nil
+}
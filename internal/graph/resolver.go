@@ -0,0 +1,296 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/internal/service"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/graph-gophers/graphql-go"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultPage  = 1
+	defaultLimit = 10
+)
+
+// Resolver is the root resolver. Every field delegates to the person service.
+type Resolver struct {
+	service service.PersonService
+	logger  logging.Logger
+}
+
+type personFilterInput struct {
+	Name         *string
+	Surname      *string
+	Patronymic   *string
+	Age          *int32
+	Gender       *string
+	Nationality  *string
+	CreatedAfter *graphql.Time
+	UpdatedSince *graphql.Time
+	Deleted      string
+}
+
+type personSortInput struct {
+	Field     string
+	Direction string
+}
+
+type personInput struct {
+	Name       string
+	Surname    string
+	Patronymic *string
+}
+
+func (r *Resolver) Person(ctx context.Context, args struct{ ID graphql.ID }) (*personResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	person, err := r.service.GetByID(ctx, id)
+	var moved *domain.MovedError
+	if errors.As(err, &moved) {
+		person, err = r.service.GetByID(ctx, moved.ID)
+	}
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		r.log(ctx).Error("Failed to get person by ID %d: %v", id, err)
+		return nil, resolveError(err, "Failed to get person")
+	}
+
+	return r.personResolver(person), nil
+}
+
+func (r *Resolver) People(ctx context.Context, args struct {
+	Filter *personFilterInput
+	Sort   *personSortInput
+	Page   int32
+	Limit  int32
+}) ([]*personResolver, error) {
+	filter := toFilter(args.Filter)
+	if args.Sort != nil {
+		filter.Sort = strings.ToLower(args.Sort.Field)
+		if args.Sort.Direction == "DESC" {
+			filter.Sort = "-" + filter.Sort
+		}
+	}
+
+	page := int(args.Page)
+	if page < 1 {
+		page = defaultPage
+	}
+
+	limit := int(args.Limit)
+	if limit < 1 {
+		limit = defaultLimit
+	}
+
+	people, err := r.service.GetAll(ctx, filter, page, limit)
+	if err != nil {
+		r.log(ctx).Error("Failed to get people: %v", err)
+		return nil, resolveError(err, "Failed to get people")
+	}
+
+	loader := newHistoryLoader(r.service)
+	resolvers := make([]*personResolver, 0, len(people))
+	for _, person := range people {
+		loader.prime(person.ID)
+		resolvers = append(resolvers, &personResolver{person: person, loader: loader})
+	}
+	return resolvers, nil
+}
+
+func (r *Resolver) CreatePerson(ctx context.Context, args struct{ Input personInput }) (*personResolver, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+
+	input, err := toInput(args.Input)
+	if err != nil {
+		return nil, err
+	}
+
+	person, err := r.service.Create(ctx, input)
+	var duplicate *domain.DuplicateError
+	if errors.As(err, &duplicate) {
+		r.log(ctx).Info("Duplicate of person %d detected (similarity %.2f)", duplicate.Existing.ID, duplicate.Similarity)
+		if duplicate.Policy == domain.DuplicatePolicyExisting {
+			return r.personResolver(person), nil
+		}
+		return nil, resolveError(err, "")
+	}
+	if err != nil {
+		r.log(ctx).Error("Failed to create person: %v", err)
+		return nil, resolveError(err, "Failed to create person")
+	}
+
+	return r.personResolver(person), nil
+}
+
+func (r *Resolver) UpdatePerson(ctx context.Context, args struct {
+	ID              graphql.ID
+	Input           personInput
+	ExpectedVersion *int32
+}) (*personResolver, error) {
+	if err := checkWritable(ctx); err != nil {
+		return nil, err
+	}
+
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	input, err := toInput(args.Input)
+	if err != nil {
+		return nil, err
+	}
+
+	person, err := r.service.Update(ctx, id, input, versions(args.ExpectedVersion))
+	if err != nil {
+		r.log(ctx).Error("Failed to update person with ID %d: %v", id, err)
+		return nil, resolveError(err, "Failed to update person")
+	}
+
+	return r.personResolver(person), nil
+}
+
+func (r *Resolver) DeletePerson(ctx context.Context, args struct {
+	ID              graphql.ID
+	ExpectedVersion *int32
+}) (bool, error) {
+	if err := checkWritable(ctx); err != nil {
+		return false, err
+	}
+
+	id, err := parseID(args.ID)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.service.Delete(ctx, id, versions(args.ExpectedVersion)); err != nil {
+		r.log(ctx).Error("Failed to delete person with ID %d: %v", id, err)
+		return false, resolveError(err, "Failed to delete person")
+	}
+
+	return true, nil
+}
+
+// PersonChanged completes when the subscriber falls too far behind; clients
+// resubscribe with the ID of the last event they received.
+func (r *Resolver) PersonChanged(ctx context.Context, args struct {
+	Filter      *personFilterInput
+	Types       *[]string
+	LastEventID *graphql.ID
+}) (<-chan *eventResolver, error) {
+	types := make(map[string]bool)
+	if args.Types != nil {
+		known := make(map[string]bool, len(domain.EventTypes))
+		for _, eventType := range domain.EventTypes {
+			known[eventType] = true
+		}
+		for _, eventType := range *args.Types {
+			if !known[eventType] {
+				return nil, newError("BAD_REQUEST", "Unknown event type "+strconv.Quote(eventType))
+			}
+			types[eventType] = true
+		}
+	}
+
+	lastEventID := int64(0)
+	if args.LastEventID != nil {
+		id, err := strconv.ParseInt(string(*args.LastEventID), 10, 64)
+		if err != nil || id < 0 {
+			return nil, newError("BAD_REQUEST", "Invalid lastEventId")
+		}
+		lastEventID = id
+	}
+
+	events := r.service.Watch(ctx, toFilter(args.Filter), lastEventID)
+	resolvers := make(chan *eventResolver)
+	go func() {
+		defer close(resolvers)
+		for event := range events {
+			if len(types) > 0 && !types[event.Type] {
+				continue
+			}
+			select {
+			case resolvers <- &eventResolver{event: event, loader: newHistoryLoader(r.service)}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return resolvers, nil
+}
+
+func (r *Resolver) log(ctx context.Context) logging.Logger {
+	return logging.FromContext(ctx, r.logger)
+}
+
+func (r *Resolver) personResolver(person domain.Person) *personResolver {
+	return &personResolver{person: person, loader: newHistoryLoader(r.service)}
+}
+
+func toFilter(input *personFilterInput) domain.PersonFilter {
+	filter := domain.PersonFilter{Deleted: domain.DeletedExclude}
+	if input == nil {
+		return filter
+	}
+
+	filter.Name = input.Name
+	filter.Surname = input.Surname
+	filter.Patronymic = input.Patronymic
+	filter.Gender = input.Gender
+	filter.Nationality = input.Nationality
+
+	if input.Age != nil {
+		age := int(*input.Age)
+		filter.Age = &age
+	}
+	if input.CreatedAfter != nil {
+		filter.CreatedAfter = &input.CreatedAfter.Time
+	}
+	if input.UpdatedSince != nil {
+		filter.UpdatedSince = &input.UpdatedSince.Time
+	}
+	if input.Deleted != "" {
+		filter.Deleted = strings.ToLower(input.Deleted)
+	}
+
+	return filter
+}
+
+func toInput(input personInput) (domain.PersonInput, error) {
+	if input.Name == "" || input.Surname == "" {
+		return domain.PersonInput{}, newError("BAD_REQUEST", "Name and surname are required")
+	}
+
+	return domain.PersonInput{
+		Name:       input.Name,
+		Surname:    input.Surname,
+		Patronymic: input.Patronymic,
+	}, nil
+}
+
+func parseID(id graphql.ID) (int, error) {
+	value, err := strconv.Atoi(string(id))
+	if err != nil {
+		return 0, newError("BAD_REQUEST", "Invalid ID")
+	}
+	return value, nil
+}
+
+func versions(expected *int32) []int {
+	if expected == nil {
+		return nil
+	}
+	return []int{int(*expected)}
+}
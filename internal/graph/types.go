@@ -0,0 +1,279 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/graph-gophers/graphql-go"
+	"sort"
+	"strconv"
+)
+
+// enrichedFields are the fields predicted from the name.
+var enrichedFields = []string{"age", "gender", "nationality"}
+
+type personResolver struct {
+	person domain.Person
+	loader *historyLoader
+}
+
+func (r *personResolver) ID() graphql.ID {
+	return personID(r.person.ID)
+}
+
+func (r *personResolver) Name() string {
+	return r.person.Name
+}
+
+func (r *personResolver) Surname() string {
+	return r.person.Surname
+}
+
+func (r *personResolver) Patronymic() *string {
+	return r.person.Patronymic
+}
+
+func (r *personResolver) Age() int32 {
+	return int32(r.person.Age)
+}
+
+func (r *personResolver) Gender() string {
+	return r.person.Gender
+}
+
+func (r *personResolver) Nationality() string {
+	return r.person.Nationality
+}
+
+func (r *personResolver) Version() int32 {
+	return int32(r.person.Version)
+}
+
+func (r *personResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: r.person.CreatedAt}
+}
+
+func (r *personResolver) UpdatedAt() graphql.Time {
+	return graphql.Time{Time: r.person.UpdatedAt}
+}
+
+func (r *personResolver) DeletedAt() *graphql.Time {
+	if r.person.DeletedAt == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *r.person.DeletedAt}
+}
+
+func (r *personResolver) History(ctx context.Context) ([]*auditEntryResolver, error) {
+	history, err := r.loader.load(ctx, r.person.ID)
+	if err != nil {
+		return nil, resolveError(err, "Failed to get person history")
+	}
+
+	resolvers := make([]*auditEntryResolver, 0, len(history))
+	for _, entry := range history {
+		resolvers = append(resolvers, &auditEntryResolver{entry: entry})
+	}
+	return resolvers, nil
+}
+
+func (r *personResolver) Enrichment(ctx context.Context) (*enrichmentResolver, error) {
+	history, err := r.loader.load(ctx, r.person.ID)
+	if err != nil {
+		return nil, resolveError(err, "Failed to get person history")
+	}
+
+	resolver := &enrichmentResolver{person: r.person}
+	for i := range history {
+		if enriches(history[i]) {
+			resolver.entry = &history[i]
+		}
+	}
+	return resolver, nil
+}
+
+// enriches reports whether entry recorded new predictions for the person.
+func enriches(entry domain.AuditEntry) bool {
+	switch entry.Action {
+	case domain.AuditActionCreate, domain.AuditActionEnrich:
+		return true
+	}
+	for _, field := range enrichedFields {
+		if _, ok := entry.Diff[field]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+type enrichmentResolver struct {
+	person domain.Person
+	entry  *domain.AuditEntry
+}
+
+func (r *enrichmentResolver) Age() int32 {
+	return int32(r.person.Age)
+}
+
+func (r *enrichmentResolver) Gender() string {
+	return r.person.Gender
+}
+
+func (r *enrichmentResolver) Nationality() string {
+	return r.person.Nationality
+}
+
+func (r *enrichmentResolver) Action() *string {
+	if r.entry == nil {
+		return nil
+	}
+	return &r.entry.Action
+}
+
+func (r *enrichmentResolver) EnrichedAt() *graphql.Time {
+	if r.entry == nil {
+		return nil
+	}
+	return &graphql.Time{Time: r.entry.CreatedAt}
+}
+
+type auditEntryResolver struct {
+	entry domain.AuditEntry
+}
+
+func (r *auditEntryResolver) ID() graphql.ID {
+	return graphql.ID(strconv.FormatInt(r.entry.ID, 10))
+}
+
+func (r *auditEntryResolver) Action() string {
+	return r.entry.Action
+}
+
+func (r *auditEntryResolver) Actor() string {
+	return r.entry.Actor
+}
+
+func (r *auditEntryResolver) RequestID() *string {
+	return optionalString(r.entry.RequestID)
+}
+
+func (r *auditEntryResolver) Source() string {
+	return r.entry.Source
+}
+
+func (r *auditEntryResolver) Changes() []*fieldChangeResolver {
+	return fieldChanges(r.entry.Diff)
+}
+
+func (r *auditEntryResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: r.entry.CreatedAt}
+}
+
+type eventResolver struct {
+	event  domain.Event
+	loader *historyLoader
+}
+
+func (r *eventResolver) ID() graphql.ID {
+	return graphql.ID(strconv.FormatInt(r.event.ID, 10))
+}
+
+func (r *eventResolver) Type() string {
+	return r.event.Type
+}
+
+func (r *eventResolver) PersonID() graphql.ID {
+	return personID(r.event.PersonID)
+}
+
+func (r *eventResolver) Actor() string {
+	return r.event.Actor
+}
+
+func (r *eventResolver) RequestID() *string {
+	return optionalString(r.event.RequestID)
+}
+
+func (r *eventResolver) Source() string {
+	return r.event.Source
+}
+
+func (r *eventResolver) Person() *personResolver {
+	if r.event.Person == nil {
+		return nil
+	}
+	return &personResolver{person: *r.event.Person, loader: r.loader}
+}
+
+func (r *eventResolver) Changes() []*fieldChangeResolver {
+	return fieldChanges(r.event.Changes)
+}
+
+func (r *eventResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: r.event.CreatedAt}
+}
+
+type fieldChangeResolver struct {
+	field  string
+	change domain.FieldChange
+}
+
+func (r *fieldChangeResolver) Field() string {
+	return r.field
+}
+
+func (r *fieldChangeResolver) Old() *jsonValue {
+	return newJSONValue(r.change.Old)
+}
+
+func (r *fieldChangeResolver) New() *jsonValue {
+	return newJSONValue(r.change.New)
+}
+
+// fieldChanges lists the changes ordered by field, as maps have no order.
+func fieldChanges(changes map[string]domain.FieldChange) []*fieldChangeResolver {
+	resolvers := make([]*fieldChangeResolver, 0, len(changes))
+	for field, change := range changes {
+		resolvers = append(resolvers, &fieldChangeResolver{field: field, change: change})
+	}
+	sort.Slice(resolvers, func(i, j int) bool {
+		return resolvers[i].field < resolvers[j].field
+	})
+	return resolvers
+}
+
+// jsonValue is the JSON scalar: any value, passed through as JSON.
+type jsonValue struct {
+	value interface{}
+}
+
+func newJSONValue(value interface{}) *jsonValue {
+	if value == nil {
+		return nil
+	}
+	return &jsonValue{value: value}
+}
+
+func (jsonValue) ImplementsGraphQLType(name string) bool {
+	return name == "JSON"
+}
+
+func (v *jsonValue) UnmarshalGraphQL(input interface{}) error {
+	v.value = input
+	return nil
+}
+
+func (v jsonValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.value)
+}
+
+func personID(id int) graphql.ID {
+	return graphql.ID(strconv.Itoa(id))
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+type AdminHandler struct {
+	token  string
+	logger logging.Logger
+}
+
+// NewAdminHandler serves the operational endpoints. Requests must present
+// token as a bearer token; without a token the endpoints are not served.
+func NewAdminHandler(token string, logger logging.Logger) *AdminHandler {
+	return &AdminHandler{
+		token:  token,
+		logger: logger,
+	}
+}
+
+type LogLevel struct {
+	Level string `json:"level" binding:"required" enums:"debug,info,warn,error,fatal"`
+}
+
+func (h *AdminHandler) RegisterRoutes(router *gin.Engine) {
+	if h.token == "" {
+		h.logger.Warn("ADMIN_TOKEN is not set, admin endpoints are disabled")
+		return
+	}
+
+	admin := router.Group("/api/v1/admin", h.authorize)
+	{
+		admin.GET("/log-level", h.GetLogLevel)
+		admin.PUT("/log-level", h.SetLogLevel)
+	}
+}
+
+func (h *AdminHandler) authorize(c *gin.Context) {
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+}
+
+// @Summary Get log level
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} LogLevel
+// @Failure 401 {object} map[string]string
+// @Router /admin/log-level [get]
+func (h *AdminHandler) GetLogLevel(c *gin.Context) {
+	c.JSON(http.StatusOK, LogLevel{Level: h.logger.Level()})
+}
+
+// @Summary Set log level
+// @Description Change the log level of the running instance. The change is not persisted and only affects the instance serving the request.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param input body LogLevel true "New log level"
+// @Success 200 {object} LogLevel
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /admin/log-level [put]
+func (h *AdminHandler) SetLogLevel(c *gin.Context) {
+	var input LogLevel
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	previous := h.logger.Level()
+	if err := h.logger.SetLevel(input.Level); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid log level"})
+		return
+	}
+
+	h.logger.Warn("Log level changed from %s to %s", previous, h.logger.Level())
+	c.JSON(http.StatusOK, LogLevel{Level: h.logger.Level()})
+}
@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"errors"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+func errorResponse(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Person not found"})
+	case errors.Is(err, domain.ErrImportNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import job not found"})
+	case errors.Is(err, domain.ErrWebhookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+	case errors.Is(err, domain.ErrDeliveryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook delivery not found"})
+	case errors.Is(err, domain.ErrVersionMismatch):
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Person has been modified"})
+	case errors.Is(err, domain.ErrInvalidMerge):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot merge a person into itself"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
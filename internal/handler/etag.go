@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	errInvalidPrecondition = errors.New("invalid precondition header")
+	// errWeakPrecondition is an If-Match header listing weak tags only,
+	// which never match under the strong comparison it calls for.
+	errWeakPrecondition = errors.New("If-Match lists no strong entity tag")
+)
+
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseETags splits a list of entity tags such as `"3", W/"4"` into versions.
+// A lone "*" is returned as an empty list with wildcard set. With strong set,
+// weak tags are left out, as they never match under strong comparison.
+func parseETags(header string, strong bool) (versions []int, wildcard bool, err error) {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return nil, true, nil
+		}
+
+		tag, weak := strings.CutPrefix(tag, "W/")
+		version, err := strconv.Atoi(strings.Trim(tag, `"`))
+		if err != nil || version < 1 {
+			return nil, false, errInvalidPrecondition
+		}
+		if weak && strong {
+			continue
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, false, nil
+}
+
+// ifMatchVersions returns the versions listed in the If-Match header, any of
+// which the write may apply to, or none when the header is absent or "*".
+// If-Match uses strong comparison, so a header with weak tags only fails
+// with errWeakPrecondition.
+func ifMatchVersions(c *gin.Context) ([]int, error) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return nil, nil
+	}
+
+	versions, wildcard, err := parseETags(header, true)
+	if err != nil {
+		return nil, err
+	}
+	if wildcard {
+		return nil, nil
+	}
+	if len(versions) == 0 {
+		return nil, errWeakPrecondition
+	}
+
+	return versions, nil
+}
+
+// ifMatch reads the If-Match versions of a write. It writes a 412 response
+// and returns false when the header is invalid or cannot match.
+func (h *PersonHandler) ifMatch(c *gin.Context) ([]int, bool) {
+	versions, err := ifMatchVersions(c)
+	if errors.Is(err, errWeakPrecondition) {
+		h.log(c).Debug("Weak If-Match header: %s", c.GetHeader("If-Match"))
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "If-Match requires a strong ETag"})
+		return nil, false
+	}
+	if err != nil {
+		h.log(c).Debug("Invalid If-Match header: %v", err)
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Invalid If-Match header"})
+		return nil, false
+	}
+	return versions, true
+}
+
+// notModified reports whether the If-None-Match header matches version.
+func notModified(c *gin.Context, version int) bool {
+	header := c.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	versions, wildcard, err := parseETags(header, false)
+	if err != nil {
+		return false
+	}
+	if wildcard {
+		return true
+	}
+
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"errors"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseETags(t *testing.T) {
+	tests := []struct {
+		header   string
+		strong   bool
+		versions []int
+		wildcard bool
+		err      error
+	}{
+		{header: `"3"`, versions: []int{3}},
+		{header: `"3", W/"4" ,"5"`, versions: []int{3, 4, 5}},
+		{header: `"3", W/"4"`, strong: true, versions: []int{3}},
+		{header: `W/"4"`, strong: true},
+		{header: `*`, wildcard: true},
+		{header: `"3", *`, wildcard: true},
+		{header: `"abc"`, err: errInvalidPrecondition},
+		{header: `"0"`, err: errInvalidPrecondition},
+		{header: `W/"-1"`, strong: true, err: errInvalidPrecondition},
+		{header: ``, err: errInvalidPrecondition},
+	}
+
+	for _, tt := range tests {
+		versions, wildcard, err := parseETags(tt.header, tt.strong)
+		if !errors.Is(err, tt.err) || wildcard != tt.wildcard || !slices.Equal(versions, tt.versions) {
+			t.Errorf("parseETags(%q, %v) = %v, %v, %v; want %v, %v, %v",
+				tt.header, tt.strong, versions, wildcard, err, tt.versions, tt.wildcard, tt.err)
+		}
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewPersonHandler(nil, time.Second, logging.New("error", "text"))
+
+	tests := []struct {
+		header   string
+		versions []int
+		status   int
+	}{
+		{header: ``},
+		{header: `*`},
+		{header: `"3", "4"`, versions: []int{3, 4}},
+		{header: `W/"3", "4"`, versions: []int{4}},
+		{header: `W/"3"`, status: http.StatusPreconditionFailed},
+		{header: `3 4`, status: http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPut, "/api/v1/people/1", nil)
+		if tt.header != "" {
+			c.Request.Header.Set("If-Match", tt.header)
+		}
+
+		versions, ok := h.ifMatch(c)
+		if tt.status != 0 {
+			if ok || w.Code != tt.status {
+				t.Errorf("If-Match %q: got ok %v and status %d, want a %d response", tt.header, ok, w.Code, tt.status)
+			}
+			continue
+		}
+		if !ok || !slices.Equal(versions, tt.versions) {
+			t.Errorf("If-Match %q: got %v, %v; want %v", tt.header, versions, ok, tt.versions)
+		}
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{header: ``, want: false},
+		{header: `"3"`, want: true},
+		{header: `W/"3"`, want: true},
+		{header: `"2", "4"`, want: false},
+		{header: `*`, want: true},
+		{header: `garbage`, want: false},
+	}
+
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/people/1", nil)
+		if tt.header != "" {
+			c.Request.Header.Set("If-None-Match", tt.header)
+		}
+
+		if got := notModified(c, 3); got != tt.want {
+			t.Errorf("If-None-Match %q: got %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
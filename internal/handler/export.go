@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/pkg/xlsx"
+	"io"
+	"strings"
+	"time"
+)
+
+var exportColumns = map[string]func(p domain.Person) interface{}{
+	"id":          func(p domain.Person) interface{} { return p.ID },
+	"name":        func(p domain.Person) interface{} { return p.Name },
+	"surname":     func(p domain.Person) interface{} { return p.Surname },
+	"patronymic":  func(p domain.Person) interface{} { return derefString(p.Patronymic) },
+	"age":         func(p domain.Person) interface{} { return p.Age },
+	"gender":      func(p domain.Person) interface{} { return p.Gender },
+	"nationality": func(p domain.Person) interface{} { return p.Nationality },
+	"version":     func(p domain.Person) interface{} { return p.Version },
+	"created_at":  func(p domain.Person) interface{} { return p.CreatedAt },
+	"updated_at":  func(p domain.Person) interface{} { return p.UpdatedAt },
+	"deleted_at": func(p domain.Person) interface{} {
+		if p.DeletedAt == nil {
+			return nil
+		}
+		return *p.DeletedAt
+	},
+}
+
+var defaultExportColumns = []string{"id", "name", "surname", "patronymic", "age", "gender", "nationality", "created_at", "updated_at"}
+
+var exportContentTypes = map[string]string{
+	"csv":   "text/csv; charset=utf-8",
+	"jsonl": "application/x-ndjson",
+	"xlsx":  "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// exporter encodes people row by row in one of the export formats.
+type exporter interface {
+	Write(person domain.Person) error
+	Close() error
+}
+
+func parseExportColumns(value string) ([]string, error) {
+	if value == "" {
+		return defaultExportColumns, nil
+	}
+
+	var columns []string
+	for _, column := range strings.Split(value, ",") {
+		column = strings.TrimSpace(column)
+		if _, ok := exportColumns[column]; !ok {
+			return nil, fmt.Errorf("unknown column %q", column)
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+func newExporter(format string, w io.Writer, columns []string) (exporter, error) {
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(columns); err != nil {
+			return nil, err
+		}
+		return &csvExporter{w: cw, columns: columns}, nil
+	case "jsonl":
+		return &jsonlExporter{w: bufio.NewWriter(w), columns: columns}, nil
+	case "xlsx":
+		xw, err := xlsx.NewWriter(w, "People")
+		if err != nil {
+			return nil, err
+		}
+		header := make([]interface{}, len(columns))
+		for i, column := range columns {
+			header[i] = column
+		}
+		if err := xw.WriteRow(header); err != nil {
+			return nil, err
+		}
+		return &xlsxExporter{w: xw, columns: columns}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+type csvExporter struct {
+	w       *csv.Writer
+	columns []string
+	rows    int
+}
+
+func (e *csvExporter) Write(person domain.Person) error {
+	record := make([]string, len(e.columns))
+	for i, column := range e.columns {
+		switch v := exportColumns[column](person).(type) {
+		case nil:
+		case string:
+			record[i] = escapeFormula(v)
+		case time.Time:
+			record[i] = v.Format(time.RFC3339)
+		default:
+			record[i] = fmt.Sprint(v)
+		}
+	}
+
+	if err := e.w.Write(record); err != nil {
+		return err
+	}
+
+	e.rows++
+	if e.rows%500 == 0 {
+		e.w.Flush()
+	}
+	return e.w.Error()
+}
+
+func (e *csvExporter) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type jsonlExporter struct {
+	w       *bufio.Writer
+	columns []string
+}
+
+func (e *jsonlExporter) Write(person domain.Person) error {
+	if err := e.w.WriteByte('{'); err != nil {
+		return err
+	}
+
+	for i, column := range e.columns {
+		if i > 0 {
+			_ = e.w.WriteByte(',')
+		}
+
+		key, _ := json.Marshal(column)
+		value, err := json.Marshal(exportColumns[column](person))
+		if err != nil {
+			return err
+		}
+
+		_, _ = e.w.Write(key)
+		_ = e.w.WriteByte(':')
+		_, _ = e.w.Write(value)
+	}
+
+	_, err := e.w.WriteString("}\n")
+	return err
+}
+
+func (e *jsonlExporter) Close() error {
+	return e.w.Flush()
+}
+
+type xlsxExporter struct {
+	w       *xlsx.Writer
+	columns []string
+}
+
+func (e *xlsxExporter) Write(person domain.Person) error {
+	row := make([]interface{}, len(e.columns))
+	// Cells are written as inline strings, never as formulas, so unlike CSV
+	// they need no escaping.
+	for i, column := range e.columns {
+		row[i] = exportColumns[column](person)
+	}
+	return e.w.WriteRow(row)
+}
+
+func (e *xlsxExporter) Close() error {
+	return e.w.Close()
+}
+
+// exportFilename keeps only characters that are safe in a
+// Content-Disposition header and makes sure the extension matches format.
+func exportFilename(requested, format string, now time.Time) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return -1
+		}
+	}, requested)
+
+	name = strings.TrimSuffix(name, "."+format)
+	if strings.Trim(name, ".") == "" {
+		name = "people-" + now.Format("20060102-150405")
+	}
+
+	return name + "." + format
+}
+
+// escapeFormula prefixes text that spreadsheets would take for a formula
+// with a quote, so that names cannot run as formulas once opened as CSV.
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func derefString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
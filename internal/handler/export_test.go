@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEscapeFormula(t *testing.T) {
+	tests := map[string]string{
+		"":                  "",
+		"Ivan":              "Ivan",
+		"=1+1":              "'=1+1",
+		"+7 900":            "'+7 900",
+		"-Ivan":             "'-Ivan",
+		"@SUM(A1)":          "'@SUM(A1)",
+		"\tIvan":            "'\tIvan",
+		"\rIvan":            "'\rIvan",
+		"Ivan=1":            "Ivan=1",
+		"'=already escaped": "'=already escaped",
+	}
+
+	for value, want := range tests {
+		if got := escapeFormula(value); got != want {
+			t.Errorf("escapeFormula(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+var formulaPerson = domain.Person{ID: 1, Name: `=HYPERLINK("http://example.com")`, Surname: "-Petrov", Age: 30}
+
+func TestCSVExporterEscapesFormulas(t *testing.T) {
+	var buf bytes.Buffer
+	e, err := newExporter("csv", &buf, []string{"id", "name", "surname", "age"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Write(formulaPerson); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"id", "name", "surname", "age"},
+		{"1", `'=HYPERLINK("http://example.com")`, "'-Petrov", "30"},
+	}
+	if len(records) != len(want) || !slices.Equal(records[0], want[0]) || !slices.Equal(records[1], want[1]) {
+		t.Errorf("got %q, want %q", records, want)
+	}
+}
+
+// XLSX cells are inline strings, which spreadsheets never evaluate, so their
+// text is written as it is.
+func TestXLSXExporterKeepsText(t *testing.T) {
+	var buf bytes.Buffer
+	e, err := newExporter("xlsx", &buf, []string{"name", "surname"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Write(formulaPerson); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := archive.Open("xl/worksheets/sheet1.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	sheet, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, text := range []string{`>=HYPERLINK(&#34;http://example.com&#34;)<`, `>-Petrov<`} {
+		if !strings.Contains(string(sheet), text) {
+			t.Errorf("sheet does not contain %s:\n%s", text, sheet)
+		}
+	}
+	if strings.Contains(string(sheet), "<f>") {
+		t.Errorf("sheet contains a formula:\n%s", sheet)
+	}
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/internal/service"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type HealthHandler struct {
+	service service.HealthService
+	logger  logging.Logger
+}
+
+func NewHealthHandler(service service.HealthService, logger logging.Logger) *HealthHandler {
+	return &HealthHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes mounts the probes at the root, next to /metrics, where
+// orchestrators expect them.
+func (h *HealthHandler) RegisterRoutes(router *gin.Engine) {
+	router.GET("/healthz", h.Live)
+	router.GET("/readyz", h.Ready)
+}
+
+// Live reports that the process is serving requests. It checks no
+// dependencies, so that an outage of one does not get the service restarted.
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Ready reports whether the service can take traffic, with the state of each
+// dependency.
+func (h *HealthHandler) Ready(c *gin.Context) {
+	readiness := h.service.Ready(c.Request.Context())
+
+	status := http.StatusOK
+	if readiness.Status == domain.ReadinessNotReady {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, readiness)
+}
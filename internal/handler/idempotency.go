@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/internal/service"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/RakhimovAns/Person-Service/pkg/requestctx"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+)
+
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// replayedHeaders are the response headers stored with an idempotent
+// response and sent again on replay.
+var replayedHeaders = []string{"Content-Type", "Content-Disposition", "ETag", "Location"}
+
+// idempotency makes POST requests carrying an Idempotency-Key header safe to
+// retry: the first response is stored and replayed for later requests with
+// the same key and payload. Keys are scoped to the route and actor, so that
+// callers cannot collide with each other. Server errors release the key so
+// that the request can be retried. A request whose reservation was taken
+// over after the lock timeout leaves the key to the request that took it.
+// Bodies over maxBodyBytes are refused.
+func idempotency(idempotencyService service.IdempotencyService, maxBodyBytes int64, logger logging.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotencyKeyHeader)
+		if c.Request.Method != http.MethodPost || key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBodyBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Request body exceeds %d bytes", maxBodyBytes)})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		key = scopedKey(c, key)
+		record, token, err := idempotencyService.Begin(ctx, key, requestHash(c.Request, body))
+		switch {
+		case errors.Is(err, domain.ErrIdempotencyMismatch):
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was used with a different request"})
+			return
+		case errors.Is(err, domain.ErrIdempotencyInProgress):
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still in progress"})
+			return
+		case err != nil:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check idempotency key"})
+			return
+		case record != nil:
+			replay(c, record)
+			return
+		}
+
+		// The outcome is stored even if the client has gone away, since a
+		// retry is exactly what we expect next.
+		storeCtx := context.WithoutCancel(ctx)
+		completed := false
+		defer func() {
+			if !completed {
+				if err := idempotencyService.Release(storeCtx, key, token); err != nil {
+					logger.Error("Failed to release idempotency key: %v", err)
+				}
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+
+		headers := make(map[string]string)
+		for _, name := range replayedHeaders {
+			if value := recorder.Header().Get(name); value != "" {
+				headers[name] = value
+			}
+		}
+		err = idempotencyService.Complete(storeCtx, key, token, status, headers, recorder.body.Bytes())
+		if errors.Is(err, domain.ErrIdempotencyKeyLost) {
+			logger.Warn("Idempotency key was taken over while the request ran, its response is not stored")
+			return
+		}
+		if err != nil {
+			logger.Error("Failed to store idempotent response: %v", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// scopedKey binds key to the method, route and actor of the request. The
+// result is a hash, so it fits the key column whatever the inputs.
+func scopedKey(c *gin.Context, key string) string {
+	h := sha256.New()
+	h.Write([]byte(c.Request.Method + " " + c.FullPath() + "\n"))
+	h.Write([]byte(requestctx.Actor(c.Request.Context()) + "\n"))
+	h.Write([]byte(key))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// requestHash identifies a request by its method, URL and body.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replay(c *gin.Context, record *domain.IdempotencyRecord) {
+	for name, value := range record.Headers {
+		c.Header(name, value)
+	}
+	c.Header(idempotentReplayedHeader, "true")
+	c.Status(*record.StatusCode)
+	_, _ = c.Writer.Write(record.Body)
+	c.Abort()
+}
+
+// responseRecorder copies everything written to the response so that it can
+// be stored for replay.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
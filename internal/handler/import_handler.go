@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/internal/service"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type ImportHandler struct {
+	service  service.ImportService
+	maxBytes int64
+	logger   logging.Logger
+}
+
+func NewImportHandler(service service.ImportService, maxBytes int64, logger logging.Logger) *ImportHandler {
+	return &ImportHandler{
+		service:  service,
+		maxBytes: maxBytes,
+		logger:   logger,
+	}
+}
+
+func (h *ImportHandler) log(c *gin.Context) logging.Logger {
+	return logging.FromContext(c.Request.Context(), h.logger)
+}
+
+func (h *ImportHandler) RegisterRoutes(router *gin.Engine) {
+	api := router.Group("/api/v1")
+	{
+		api.POST("/imports", h.Create)
+		api.GET("/imports/:id", h.GetByID)
+		api.GET("/imports/:id/errors", h.Errors)
+	}
+}
+
+// @Summary Start an import
+// @Description Upload a CSV (name,surname,patronymic) or JSON Lines file of people. The file is processed in the background; poll the returned job for progress.
+// @Tags imports
+// @Accept multipart/form-data
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Produce json
+// @Param file formData file false "CSV or JSONL file"
+// @Param format query string false "File format, detected from the file name or content type when omitted" Enums(csv, jsonl)
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
+// @Success 202 {object} domain.ImportJob
+// @Failure 400 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /imports [post]
+func (h *ImportHandler) Create(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxBytes)
+
+	var (
+		body        io.Reader = c.Request.Body
+		filename    string
+		contentType = c.ContentType()
+	)
+
+	if contentType == "multipart/form-data" {
+		header, err := c.FormFile("file")
+		if err != nil {
+			h.uploadError(c, err)
+			return
+		}
+
+		file, err := header.Open()
+		if err != nil {
+			h.log(c).Error("Failed to open uploaded file: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read uploaded file"})
+			return
+		}
+		defer file.Close()
+
+		body = file
+		filename = filepath.Base(header.Filename)
+		contentType = header.Header.Get("Content-Type")
+	}
+
+	format := importFormat(c.Query("format"), filename, contentType)
+	if format == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown file format, use csv or jsonl"})
+		return
+	}
+
+	payload, err := io.ReadAll(body)
+	if err != nil {
+		h.uploadError(c, err)
+		return
+	}
+
+	job, err := h.service.Create(c.Request.Context(), format, filename, payload)
+	if errors.Is(err, domain.ErrInvalidImport) {
+		h.log(c).Debug("Invalid import: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.log(c).Error("Failed to create import job: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create import job"})
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("/api/v1/imports/%d", job.ID))
+	c.JSON(http.StatusAccepted, job)
+}
+
+// @Summary Get import job
+// @Description Get status and progress of an import job
+// @Tags imports
+// @Produce json
+// @Param id path int true "Import job ID"
+// @Success 200 {object} domain.ImportJob
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /imports/{id} [get]
+func (h *ImportHandler) GetByID(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log(c).Debug("Invalid ID parameter: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID parameter"})
+		return
+	}
+
+	job, err := h.service.GetByID(c.Request.Context(), id)
+	if err != nil {
+		h.log(c).Error("Failed to get import job %d: %v", id, err)
+		errorResponse(c, err, "Failed to get import job")
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// @Summary Download import error report
+// @Description Download the records of an import job that failed, as CSV (default) or JSON
+// @Tags imports
+// @Produce text/csv
+// @Produce json
+// @Param id path int true "Import job ID"
+// @Param format query string false "Report format" Enums(csv, json)
+// @Success 200 {array} domain.ImportError
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /imports/{id}/errors [get]
+func (h *ImportHandler) Errors(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log(c).Debug("Invalid ID parameter: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID parameter"})
+		return
+	}
+
+	importErrors, err := h.service.Errors(c.Request.Context(), id)
+	if err != nil {
+		h.log(c).Error("Failed to get errors of import job %d: %v", id, err)
+		errorResponse(c, err, "Failed to get import errors")
+		return
+	}
+
+	if c.Query("format") == "json" {
+		c.JSON(http.StatusOK, importErrors)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="import-%d-errors.csv"`, id))
+	c.Status(http.StatusOK)
+
+	// Raw lines come straight from the upload, and errors may quote them, so
+	// both are escaped like exported names.
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{"line", "error", "raw"})
+	for _, importErr := range importErrors {
+		_ = w.Write([]string{strconv.Itoa(importErr.Line), escapeFormula(importErr.Error), escapeFormula(importErr.Raw)})
+	}
+	w.Flush()
+}
+
+func (h *ImportHandler) uploadError(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("File exceeds %d bytes", h.maxBytes)})
+		return
+	}
+
+	h.log(c).Debug("Invalid upload: %v", err)
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid upload"})
+}
+
+func importFormat(explicit, filename, contentType string) string {
+	switch strings.ToLower(explicit) {
+	case domain.ImportFormatCSV, domain.ImportFormatJSONL:
+		return strings.ToLower(explicit)
+	case "":
+	default:
+		return ""
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return domain.ImportFormatCSV
+	case ".jsonl", ".ndjson":
+		return domain.ImportFormatJSONL
+	}
+
+	switch contentType {
+	case "text/csv", "application/csv":
+		return domain.ImportFormatCSV
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+		return domain.ImportFormatJSONL
+	}
+
+	return ""
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/RakhimovAns/Person-Service/pkg/metrics"
+	"github.com/RakhimovAns/Person-Service/pkg/requestctx"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+const (
+	actorHeader     = "X-Actor"
+	requestIDHeader = requestctx.RequestIDHeader
+)
+
+// requestContext stores the caller identity and request ID in the request
+// context so that the service can attribute changes in the audit log, along
+// with a logger that tags every line with the request ID and trace ID. The
+// X-Actor header is not authenticated, so the actor is recorded as
+// unverified.
+// Requests without a usable X-Request-ID get a generated one; either way it
+// is echoed back.
+func requestContext(logger logging.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(requestIDHeader)
+		if !requestctx.ValidRequestID(requestID) {
+			requestID = requestctx.NewRequestID()
+		}
+		c.Header(requestIDHeader, requestID)
+
+		ctx := c.Request.Context()
+		ctx = requestctx.WithClaimedActor(ctx, c.GetHeader(actorHeader))
+		ctx = requestctx.WithRequestID(ctx, requestID)
+		ctx = requestctx.WithSource(ctx, requestctx.SourceAPI)
+		log := logger.With("request_id", requestID)
+		if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+			log = log.With("trace_id", spanContext.TraceID().String())
+		}
+		ctx = logging.NewContext(ctx, log)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+// recovery turns panics into 500 responses and logs them, with the stack,
+// through the request-scoped logger.
+func recovery(logger logging.Logger) gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any) {
+		logging.FromContext(c.Request.Context(), logger).Error("Panic serving %s %s: %v\n%s",
+			c.Request.Method, c.Request.URL.Path, err, debug.Stack())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	})
+}
+
+// accessLog logs every request once it has been served, through the
+// request-scoped logger.
+func accessLog(logger logging.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		log := logging.FromContext(c.Request.Context(), logger).With(
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", c.Writer.Status(),
+			"duration", time.Since(start),
+			"client_ip", c.ClientIP(),
+		)
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			log.Warn("%s %s %d", c.Request.Method, c.Request.URL.Path, c.Writer.Status())
+			return
+		}
+		log.Info("%s %s %d", c.Request.Method, c.Request.URL.Path, c.Writer.Status())
+	}
+}
+
+// instrument records request counts and latencies by route pattern. Requests
+// that match no route share one label.
+func instrument() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		done := metrics.TrackInFlight()
+		defer done()
+
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.ObserveHTTPRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
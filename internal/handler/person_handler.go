@@ -1,34 +1,51 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"github.com/RakhimovAns/Person-Service/internal/domain"
 	"github.com/RakhimovAns/Person-Service/internal/service"
 	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 type PersonHandler struct {
-	service service.PersonService
-	logger  logging.Logger
+	service   service.PersonService
+	heartbeat time.Duration
+	logger    logging.Logger
 }
 
-func NewPersonHandler(service service.PersonService, logger logging.Logger) *PersonHandler {
+func NewPersonHandler(service service.PersonService, heartbeat time.Duration, logger logging.Logger) *PersonHandler {
 	return &PersonHandler{
-		service: service,
-		logger:  logger,
+		service:   service,
+		heartbeat: heartbeat,
+		logger:    logger,
 	}
 }
 
+func (h *PersonHandler) log(c *gin.Context) logging.Logger {
+	return logging.FromContext(c.Request.Context(), h.logger)
+}
+
 func (h *PersonHandler) RegisterRoutes(router *gin.Engine) {
 	api := router.Group("/api/v1")
 	{
 		api.GET("/people", h.GetAll)
 		api.POST("/people", h.Create)
+		api.POST("/people/batch", h.CreateBatch)
+		api.GET("/people/export", h.Export)
+		api.GET("/people/stream", h.Stream)
 		api.GET("/people/:id", h.GetByID)
 		api.PUT("/people/:id", h.Update)
+		api.PATCH("/people/:id", h.Patch)
 		api.DELETE("/people/:id", h.Delete)
+		api.POST("/people/:id/merge", h.Merge)
+		api.POST("/people/:id/restore", h.Restore)
+		api.POST("/people/:id/enrich", h.Enrich)
+		api.GET("/people/:id/history", h.History)
 	}
 }
 
@@ -38,52 +55,105 @@ func (h *PersonHandler) RegisterRoutes(router *gin.Engine) {
 // @Accept json
 // @Produce json
 // @Param input body domain.PersonInput true "Person input"
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
+// @Success 200 {object} domain.Person "Existing duplicate returned"
 // @Success 201 {object} domain.Person
 // @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
 // @Failure 500 {object} map[string]string
 // @Router /people [post]
 func (h *PersonHandler) Create(c *gin.Context) {
 	var input domain.PersonInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		h.logger.Debug("Invalid request body: %v", err)
+		h.log(c).Debug("Invalid request body: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
 
-	person, err := h.service.Create(input)
+	person, err := h.service.Create(c.Request.Context(), input)
+	var duplicate *domain.DuplicateError
+	if errors.As(err, &duplicate) {
+		h.log(c).Info("Duplicate of person %d detected (similarity %.2f)", duplicate.Existing.ID, duplicate.Similarity)
+		if duplicate.Policy == domain.DuplicatePolicyExisting {
+			c.Header("ETag", etag(person.Version))
+			c.JSON(http.StatusOK, person)
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{
+			"error":       "Person already exists",
+			"existing_id": duplicate.Existing.ID,
+			"similarity":  duplicate.Similarity,
+		})
+		return
+	}
 	if err != nil {
-		h.logger.Error("Failed to create person: %v", err)
+		h.log(c).Error("Failed to create person: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create person"})
 		return
 	}
 
+	c.Header("ETag", etag(person.Version))
 	c.JSON(http.StatusCreated, person)
 }
 
+// @Summary Create people in bulk
+// @Description Create up to BATCH_MAX_ITEMS people at once. In atomic mode nothing is stored unless every item succeeds; in partial mode (default) successful items are kept.
+// @Tags people
+// @Accept json
+// @Produce json
+// @Param input body domain.BatchInput true "Batch input"
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
+// @Success 201 {object} domain.BatchResult "Every item was created"
+// @Success 207 {object} domain.BatchResult "Some items failed"
+// @Failure 400 {object} map[string]string
+// @Failure 422 {object} domain.BatchResult "Atomic batch was rolled back"
+// @Failure 500 {object} map[string]string
+// @Router /people/batch [post]
+func (h *PersonHandler) CreateBatch(c *gin.Context) {
+	var input domain.BatchInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		h.log(c).Debug("Invalid request body: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	result, err := h.service.CreateBatch(c.Request.Context(), input.Items, input.Mode)
+	if errors.Is(err, domain.ErrInvalidBatch) {
+		h.log(c).Debug("Invalid batch: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.log(c).Error("Failed to create batch: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create people"})
+		return
+	}
+
+	switch {
+	case result.Failed == 0:
+		c.JSON(http.StatusCreated, result)
+	case result.Mode == domain.BatchModeAtomic:
+		c.JSON(http.StatusUnprocessableEntity, result)
+	default:
+		c.JSON(http.StatusMultiStatus, result)
+	}
+}
+
 // GetAll godoc
 // @Summary Get all people
 // @Description Get list of people
 // @Tags people
 // @Produce json
+// @Param created_after query string false "Only people created after this RFC 3339 timestamp"
+// @Param updated_since query string false "Only people updated at or after this RFC 3339 timestamp, ordered by update time"
+// @Param deleted query string false "Soft-deleted rows: exclude (default), only or include" Enums(exclude, only, include)
 // @Success 200 {array} domain.Person
+// @Failure 400 {object} map[string]string
 // @Router /people [get]
 func (h *PersonHandler) GetAll(c *gin.Context) {
-	filter := domain.PersonFilter{
-		Name:        getStringPointer(c.Query("name")),
-		Surname:     getStringPointer(c.Query("surname")),
-		Patronymic:  getStringPointer(c.Query("patronymic")),
-		Gender:      getStringPointer(c.Query("gender")),
-		Nationality: getStringPointer(c.Query("nationality")),
-	}
-
-	if ageStr := c.Query("age"); ageStr != "" {
-		age, err := strconv.Atoi(ageStr)
-		if err != nil {
-			h.logger.Debug("Invalid age parameter: %v", err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid age parameter"})
-			return
-		}
-		filter.Age = &age
+	filter, ok := h.parseFilter(c)
+	if !ok {
+		return
 	}
 
 	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
@@ -96,9 +166,9 @@ func (h *PersonHandler) GetAll(c *gin.Context) {
 		limit = 10
 	}
 
-	people, err := h.service.GetAll(filter, page, limit)
+	people, err := h.service.GetAll(c.Request.Context(), filter, page, limit)
 	if err != nil {
-		h.logger.Error("Failed to get people: %v", err)
+		h.log(c).Error("Failed to get people: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get people"})
 		return
 	}
@@ -106,13 +176,88 @@ func (h *PersonHandler) GetAll(c *gin.Context) {
 	c.JSON(http.StatusOK, people)
 }
 
+// @Summary Export people
+// @Description Stream every person matching the list filters as CSV, JSON Lines or XLSX
+// @Tags people
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string false "Export format" Enums(csv, jsonl, xlsx) default(csv)
+// @Param columns query string false "Comma-separated columns, e.g. id,name,surname"
+// @Param filename query string false "File name for Content-Disposition"
+// @Param name query string false "Filter by name"
+// @Param surname query string false "Filter by surname"
+// @Param patronymic query string false "Filter by patronymic"
+// @Param age query int false "Filter by age"
+// @Param gender query string false "Filter by gender"
+// @Param nationality query string false "Filter by nationality"
+// @Param created_after query string false "Only people created after this RFC 3339 timestamp"
+// @Param updated_since query string false "Only people updated at or after this RFC 3339 timestamp"
+// @Param deleted query string false "Soft-deleted rows: exclude (default), only or include" Enums(exclude, only, include)
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Router /people/export [get]
+func (h *PersonHandler) Export(c *gin.Context) {
+	filter, ok := h.parseFilter(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format parameter"})
+		return
+	}
+
+	columns, err := parseExportColumns(c.Query("columns"))
+	if err != nil {
+		h.log(c).Debug("Invalid columns parameter: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid columns parameter"})
+		return
+	}
+
+	clearWriteDeadline(c)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, exportFilename(c.Query("filename"), format, time.Now())))
+	c.Status(http.StatusOK)
+
+	exp, err := newExporter(format, c.Writer, columns)
+	if err != nil {
+		h.log(c).Error("Failed to start export: %v", err)
+		return
+	}
+
+	rows := 0
+	err = h.service.Export(c.Request.Context(), filter, func(person domain.Person) error {
+		rows++
+		return exp.Write(person)
+	})
+	if err != nil {
+		// The status line has already been sent, so the client only sees a
+		// truncated file.
+		h.log(c).Error("Export aborted after %d rows: %v", rows, err)
+		return
+	}
+
+	if err := exp.Close(); err != nil {
+		h.log(c).Error("Failed to finish export: %v", err)
+		return
+	}
+
+	h.log(c).Info("Exported %d people as %s", rows, format)
+}
+
 // @Summary Get person by ID
 // @Description Get person by ID
 // @Tags people
 // @Accept json
 // @Produce json
 // @Param id path int true "Person ID"
+// @Param If-None-Match header string false "ETag of the cached representation"
 // @Success 200 {object} domain.Person
+// @Success 301 "Person was merged into another record"
+// @Success 304 "Person has not changed"
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
@@ -120,15 +265,26 @@ func (h *PersonHandler) GetAll(c *gin.Context) {
 func (h *PersonHandler) GetByID(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		h.logger.Debug("Invalid ID parameter: %v", err)
+		h.log(c).Debug("Invalid ID parameter: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID parameter"})
 		return
 	}
 
-	person, err := h.service.GetByID(id)
+	person, err := h.service.GetByID(c.Request.Context(), id)
+	var moved *domain.MovedError
+	if errors.As(err, &moved) {
+		c.Redirect(http.StatusMovedPermanently, fmt.Sprintf("/api/v1/people/%d", moved.ID))
+		return
+	}
 	if err != nil {
-		h.logger.Error("Failed to get person by ID %d: %v", id, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get person"})
+		h.log(c).Error("Failed to get person by ID %d: %v", id, err)
+		errorResponse(c, err, "Failed to get person")
+		return
+	}
+
+	c.Header("ETag", etag(person.Version))
+	if notModified(c, person.Version) {
+		c.Status(http.StatusNotModified)
 		return
 	}
 
@@ -141,65 +297,314 @@ func (h *PersonHandler) GetByID(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "Person ID"
+// @Param If-Match header string false "ETags the update is conditional on, comma-separated"
 // @Param input body domain.PersonInput true "Person input"
 // @Success 200 {object} domain.Person
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /people/{id} [put]
 func (h *PersonHandler) Update(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		h.logger.Debug("Invalid ID parameter: %v", err)
+		h.log(c).Debug("Invalid ID parameter: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID parameter"})
 		return
 	}
 
+	versions, ok := h.ifMatch(c)
+	if !ok {
+		return
+	}
+
 	var input domain.PersonInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		h.logger.Debug("Invalid request body: %v", err)
+		h.log(c).Debug("Invalid request body: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	person, err := h.service.Update(c.Request.Context(), id, input, versions)
+	if err != nil {
+		h.log(c).Error("Failed to update person with ID %d: %v", id, err)
+		errorResponse(c, err, "Failed to update person")
+		return
+	}
+
+	c.Header("ETag", etag(person.Version))
+	c.JSON(http.StatusOK, person)
+}
+
+// @Summary Patch person
+// @Description Partially update person by ID. Age, gender and nationality are re-enriched when the name changes. A null patronymic clears it.
+// @Tags people
+// @Accept json
+// @Produce json
+// @Param id path int true "Person ID"
+// @Param If-Match header string false "ETags the update is conditional on, comma-separated"
+// @Param input body domain.PersonPatch true "Fields to change"
+// @Success 200 {object} domain.Person
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /people/{id} [patch]
+func (h *PersonHandler) Patch(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.log(c).Debug("Invalid ID parameter: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID parameter"})
+		return
+	}
+
+	versions, ok := h.ifMatch(c)
+	if !ok {
+		return
+	}
+
+	var patch domain.PersonPatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		h.log(c).Debug("Invalid request body: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
 
-	person, err := h.service.Update(id, input)
+	if (patch.Name != nil && *patch.Name == "") || (patch.Surname != nil && *patch.Surname == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name and surname cannot be empty"})
+		return
+	}
+
+	person, err := h.service.Patch(c.Request.Context(), id, patch, versions)
 	if err != nil {
-		h.logger.Error("Failed to update person with ID %d: %v", id, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update person"})
+		h.log(c).Error("Failed to patch person with ID %d: %v", id, err)
+		errorResponse(c, err, "Failed to update person")
 		return
 	}
 
+	c.Header("ETag", etag(person.Version))
 	c.JSON(http.StatusOK, person)
 }
 
 // @Summary Delete person
-// @Description Delete person by ID
+// @Description Soft-delete person by ID. Deleted people can be restored until they are purged.
 // @Tags people
 // @Accept json
 // @Produce json
 // @Param id path int true "Person ID"
+// @Param If-Match header string false "ETags the deletion is conditional on, comma-separated"
 // @Success 204
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /people/{id} [delete]
 func (h *PersonHandler) Delete(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		h.logger.Debug("Invalid ID parameter: %v", err)
+		h.log(c).Debug("Invalid ID parameter: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID parameter"})
 		return
 	}
 
-	if err := h.service.Delete(id); err != nil {
-		h.logger.Error("Failed to delete person with ID %d: %v", id, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete person"})
+	versions, ok := h.ifMatch(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), id, versions); err != nil {
+		h.log(c).Error("Failed to delete person with ID %d: %v", id, err)
+		errorResponse(c, err, "Failed to delete person")
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
 
+// @Summary Merge people
+// @Description Merge the source person into the person with the given ID. The source ID redirects to the target afterwards.
+// @Tags people
+// @Accept json
+// @Produce json
+// @Param id path int true "Target person ID"
+// @Param input body domain.MergeInput true "Merge input"
+// @Param If-Match header string false "ETags of the target the merge is conditional on, comma-separated"
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
+// @Success 200 {object} domain.Person
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /people/{id}/merge [post]
+func (h *PersonHandler) Merge(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.log(c).Debug("Invalid ID parameter: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID parameter"})
+		return
+	}
+
+	var input domain.MergeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		h.log(c).Debug("Invalid request body: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	versions, ok := h.ifMatch(c)
+	if !ok {
+		return
+	}
+
+	person, err := h.service.Merge(c.Request.Context(), id, input.SourceID, versions)
+	if err != nil {
+		h.log(c).Error("Failed to merge person %d into %d: %v", input.SourceID, id, err)
+		errorResponse(c, err, "Failed to merge people")
+		return
+	}
+
+	c.Header("ETag", etag(person.Version))
+	c.JSON(http.StatusOK, person)
+}
+
+// @Summary Restore person
+// @Description Restore a soft-deleted person by ID
+// @Tags people
+// @Produce json
+// @Param id path int true "Person ID"
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
+// @Success 200 {object} domain.Person
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /people/{id}/restore [post]
+func (h *PersonHandler) Restore(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.log(c).Debug("Invalid ID parameter: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID parameter"})
+		return
+	}
+
+	person, err := h.service.Restore(c.Request.Context(), id)
+	if err != nil {
+		h.log(c).Error("Failed to restore person with ID %d: %v", id, err)
+		errorResponse(c, err, "Failed to restore person")
+		return
+	}
+
+	c.JSON(http.StatusOK, person)
+}
+
+// @Summary Re-enrich person
+// @Description Fetch age, gender and nationality again for person by ID
+// @Tags people
+// @Produce json
+// @Param id path int true "Person ID"
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
+// @Success 200 {object} domain.Person
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /people/{id}/enrich [post]
+func (h *PersonHandler) Enrich(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.log(c).Debug("Invalid ID parameter: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID parameter"})
+		return
+	}
+
+	person, err := h.service.Enrich(c.Request.Context(), id)
+	if err != nil {
+		h.log(c).Error("Failed to enrich person with ID %d: %v", id, err)
+		errorResponse(c, err, "Failed to enrich person")
+		return
+	}
+
+	c.JSON(http.StatusOK, person)
+}
+
+// @Summary Get person history
+// @Description Get the audit trail of every change made to person by ID
+// @Tags people
+// @Produce json
+// @Param id path int true "Person ID"
+// @Success 200 {array} domain.AuditEntry
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /people/{id}/history [get]
+func (h *PersonHandler) History(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.log(c).Debug("Invalid ID parameter: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID parameter"})
+		return
+	}
+
+	entries, err := h.service.History(c.Request.Context(), id)
+	if err != nil {
+		h.log(c).Error("Failed to get history of person with ID %d: %v", id, err)
+		errorResponse(c, err, "Failed to get person history")
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// parseFilter reads the list filters from the query string. It writes a
+// 400 response and returns false when one of them is invalid.
+func (h *PersonHandler) parseFilter(c *gin.Context) (domain.PersonFilter, bool) {
+	filter := domain.PersonFilter{
+		Name:        getStringPointer(c.Query("name")),
+		Surname:     getStringPointer(c.Query("surname")),
+		Patronymic:  getStringPointer(c.Query("patronymic")),
+		Gender:      getStringPointer(c.Query("gender")),
+		Nationality: getStringPointer(c.Query("nationality")),
+		Deleted:     c.DefaultQuery("deleted", domain.DeletedExclude),
+	}
+
+	switch filter.Deleted {
+	case domain.DeletedExclude, domain.DeletedOnly, domain.DeletedInclude:
+	default:
+		h.log(c).Debug("Invalid deleted parameter: %s", filter.Deleted)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deleted parameter"})
+		return domain.PersonFilter{}, false
+	}
+
+	if ageStr := c.Query("age"); ageStr != "" {
+		age, err := strconv.Atoi(ageStr)
+		if err != nil {
+			h.log(c).Debug("Invalid age parameter: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid age parameter"})
+			return domain.PersonFilter{}, false
+		}
+		filter.Age = &age
+	}
+
+	if createdAfter := c.Query("created_after"); createdAfter != "" {
+		t, err := time.Parse(time.RFC3339, createdAfter)
+		if err != nil {
+			h.log(c).Debug("Invalid created_after parameter: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid created_after parameter"})
+			return domain.PersonFilter{}, false
+		}
+		filter.CreatedAfter = &t
+	}
+
+	if updatedSince := c.Query("updated_since"); updatedSince != "" {
+		t, err := time.Parse(time.RFC3339, updatedSince)
+		if err != nil {
+			h.log(c).Debug("Invalid updated_since parameter: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid updated_since parameter"})
+			return domain.PersonFilter{}, false
+		}
+		filter.UpdatedSince = &t
+	}
+
+	return filter, true
+}
+
 func getStringPointer(value string) *string {
 	if value == "" {
 		return nil
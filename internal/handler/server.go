@@ -1,28 +1,76 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"github.com/RakhimovAns/Person-Service/internal/config"
+	"github.com/RakhimovAns/Person-Service/internal/graph"
+	"github.com/RakhimovAns/Person-Service/internal/service"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/RakhimovAns/Person-Service/pkg/metrics"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"net/http"
+	"time"
 )
 
 type Server struct {
-	cfg     *config.Config
-	handler *PersonHandler
-	router  *gin.Engine
+	cfg            *config.Config
+	handler        *PersonHandler
+	importHandler  *ImportHandler
+	webhookHandler *WebhookHandler
+	graphHandler   *graph.Handler
+	adminHandler   *AdminHandler
+	healthHandler  *HealthHandler
+	router         *gin.Engine
+	httpServer     *http.Server
 }
 
-func NewServer(cfg *config.Config, handler *PersonHandler) *Server {
-	router := gin.Default()
-	router.Use(cors.Default())
+func NewServer(cfg *config.Config, handler *PersonHandler, importHandler *ImportHandler, webhookHandler *WebhookHandler, graphHandler *graph.Handler, adminHandler *AdminHandler, healthHandler *HealthHandler, idempotencyService service.IdempotencyService, logger logging.Logger) *Server {
+	router := gin.New()
+	router.Use(otelgin.Middleware(cfg.Tracing.ServiceName, otelgin.WithFilter(func(r *http.Request) bool {
+		switch r.URL.Path {
+		case "/metrics", "/healthz", "/readyz":
+			return false
+		}
+		return true
+	})))
+	// Panics are recovered inside requestContext so that their logs and
+	// responses carry the request ID.
+	router.Use(requestContext(logger))
+	router.Use(recovery(logger))
+	router.Use(instrument())
+	corsConfig := cors.DefaultConfig()
+	corsConfig.AllowAllOrigins = true
+	corsConfig.AllowHeaders = append(corsConfig.AllowHeaders, actorHeader, requestIDHeader, idempotencyKeyHeader, lastEventIDHeader, "Authorization", "If-Match", "If-None-Match")
+	corsConfig.ExposeHeaders = []string{"ETag", "Location", requestIDHeader, idempotentReplayedHeader}
+	router.Use(cors.New(corsConfig))
+	router.Use(accessLog(logger))
+	router.Use(idempotency(idempotencyService, cfg.Idempotency.MaxBodyBytes, logger))
 
 	server := &Server{
-		cfg:     cfg,
-		handler: handler,
-		router:  router,
+		cfg:            cfg,
+		handler:        handler,
+		importHandler:  importHandler,
+		webhookHandler: webhookHandler,
+		graphHandler:   graphHandler,
+		adminHandler:   adminHandler,
+		healthHandler:  healthHandler,
+		router:         router,
+		httpServer: &http.Server{
+			Addr:              ":" + cfg.Port,
+			Handler:           router,
+			ReadTimeout:       cfg.HTTP.ReadTimeout,
+			ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+			WriteTimeout:      cfg.HTTP.WriteTimeout,
+			IdleTimeout:       cfg.HTTP.IdleTimeout,
+			MaxHeaderBytes:    cfg.HTTP.MaxHeaderBytes,
+		},
 	}
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(
 		swaggerFiles.Handler,
 		ginSwagger.PersistAuthorization(true),
@@ -36,8 +84,30 @@ func NewServer(cfg *config.Config, handler *PersonHandler) *Server {
 func (s *Server) configureRouter() {
 
 	s.handler.RegisterRoutes(s.router)
+	s.importHandler.RegisterRoutes(s.router)
+	s.webhookHandler.RegisterRoutes(s.router)
+	s.graphHandler.RegisterRoutes(s.router)
+	s.adminHandler.RegisterRoutes(s.router)
+	s.healthHandler.RegisterRoutes(s.router)
 }
 
+// Run serves until Shutdown is called, in which case it returns nil.
 func (s *Server) Run() error {
-	return s.router.Run(":" + s.cfg.Port)
+	if err := s.httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops accepting connections and waits for the requests in flight
+// until ctx is done. Streams are not waited for: they end when the event
+// broker is closed.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
+
+// clearWriteDeadline lifts the server write timeout for a response that
+// streams for longer than it.
+func clearWriteDeadline(c *gin.Context) {
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
 }
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const lastEventIDHeader = "Last-Event-ID"
+
+// @Summary Stream person changes
+// @Description Server-Sent Events stream of person changes matching the list filters. Every event carries its ID; send it back in Last-Event-ID, or last_event_id for clients that cannot set headers, to resume after a reconnect. Only recent events can be resumed.
+// @Tags people
+// @Produce text/event-stream
+// @Param types query string false "Comma-separated event types, e.g. person.created,person.deleted"
+// @Param Last-Event-ID header int false "ID of the last event received"
+// @Param last_event_id query int false "ID of the last event received"
+// @Param name query string false "Filter by name"
+// @Param surname query string false "Filter by surname"
+// @Param patronymic query string false "Filter by patronymic"
+// @Param age query int false "Filter by age"
+// @Param gender query string false "Filter by gender"
+// @Param nationality query string false "Filter by nationality"
+// @Param created_after query string false "Only people created after this RFC 3339 timestamp"
+// @Param updated_since query string false "Only people updated at or after this RFC 3339 timestamp"
+// @Param deleted query string false "Soft-deleted rows: exclude (default), only or include" Enums(exclude, only, include)
+// @Success 200 {object} domain.Event
+// @Failure 400 {object} map[string]string
+// @Router /people/stream [get]
+func (h *PersonHandler) Stream(c *gin.Context) {
+	filter, ok := h.parseFilter(c)
+	if !ok {
+		return
+	}
+
+	types, ok := h.parseEventTypes(c)
+	if !ok {
+		return
+	}
+
+	lastEventID := int64(0)
+	value := c.GetHeader(lastEventIDHeader)
+	if value == "" {
+		value = c.Query("last_event_id")
+	}
+	if value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id < 0 {
+			h.log(c).Debug("Invalid Last-Event-ID: %s", value)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Last-Event-ID"})
+			return
+		}
+		lastEventID = id
+	}
+
+	ctx := c.Request.Context()
+	events := h.service.Watch(ctx, filter, lastEventID)
+
+	clearWriteDeadline(c)
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			if len(types) > 0 && !types[event.Type] {
+				return true
+			}
+			c.Render(-1, sse.Event{
+				Id:    strconv.FormatInt(event.ID, 10),
+				Event: event.Type,
+				Data:  event,
+			})
+			return true
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
+
+func (h *PersonHandler) parseEventTypes(c *gin.Context) (map[string]bool, bool) {
+	value := c.Query("types")
+	if value == "" {
+		return nil, true
+	}
+
+	known := make(map[string]bool, len(domain.EventTypes))
+	for _, eventType := range domain.EventTypes {
+		known[eventType] = true
+	}
+
+	types := make(map[string]bool)
+	for _, eventType := range strings.Split(value, ",") {
+		eventType = strings.TrimSpace(eventType)
+		if !known[eventType] {
+			h.log(c).Debug("Invalid types parameter: %s", value)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid types parameter"})
+			return nil, false
+		}
+		types[eventType] = true
+	}
+
+	return types, true
+}
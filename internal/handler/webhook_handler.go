@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/internal/service"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+type WebhookHandler struct {
+	service service.WebhookService
+	logger  logging.Logger
+}
+
+func NewWebhookHandler(service service.WebhookService, logger logging.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (h *WebhookHandler) log(c *gin.Context) logging.Logger {
+	return logging.FromContext(c.Request.Context(), h.logger)
+}
+
+func (h *WebhookHandler) RegisterRoutes(router *gin.Engine) {
+	api := router.Group("/api/v1")
+	{
+		api.POST("/webhooks", h.Create)
+		api.GET("/webhooks", h.GetAll)
+		api.GET("/webhooks/:id", h.GetByID)
+		api.DELETE("/webhooks/:id", h.Delete)
+		api.GET("/webhooks/:id/deliveries", h.Deliveries)
+		api.POST("/webhooks/:id/deliveries/:delivery_id/replay", h.Replay)
+	}
+}
+
+// @Summary Register a webhook
+// @Description Subscribe a URL to person change events. Deliveries are signed with HMAC-SHA256 of "<X-Webhook-Timestamp>.<body>" in the X-Webhook-Signature header. When no secret is given, one is generated and returned here, once. URLs that resolve to loopback, private or link-local addresses are rejected.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param input body domain.WebhookInput true "Webhook input"
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
+// @Success 201 {object} domain.WebhookSubscription
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /webhooks [post]
+func (h *WebhookHandler) Create(c *gin.Context) {
+	var input domain.WebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		h.log(c).Debug("Invalid request body: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	subscription, err := h.service.Create(c.Request.Context(), input)
+	if errors.Is(err, domain.ErrInvalidWebhook) {
+		h.log(c).Debug("Invalid webhook: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, domain.ErrWebhooksDisabled) {
+		h.log(c).Debug("Webhooks are disabled: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Webhooks are disabled"})
+		return
+	}
+	if err != nil {
+		h.log(c).Error("Failed to create webhook: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("/api/v1/webhooks/%d", subscription.ID))
+	c.JSON(http.StatusCreated, subscription)
+}
+
+// @Summary Get webhooks
+// @Description Get all webhook subscriptions
+// @Tags webhooks
+// @Produce json
+// @Success 200 {array} domain.WebhookSubscription
+// @Failure 500 {object} map[string]string
+// @Router /webhooks [get]
+func (h *WebhookHandler) GetAll(c *gin.Context) {
+	subscriptions, err := h.service.GetAll(c.Request.Context())
+	if err != nil {
+		h.log(c).Error("Failed to get webhooks: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get webhooks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+// @Summary Get webhook
+// @Description Get webhook subscription by ID
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} domain.WebhookSubscription
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /webhooks/{id} [get]
+func (h *WebhookHandler) GetByID(c *gin.Context) {
+	id, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	subscription, err := h.service.GetByID(c.Request.Context(), id)
+	if err != nil {
+		h.log(c).Error("Failed to get webhook %d: %v", id, err)
+		errorResponse(c, err, "Failed to get webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+// @Summary Delete webhook
+// @Description Delete webhook subscription by ID along with its delivery log
+// @Tags webhooks
+// @Param id path int true "Webhook ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /webhooks/{id} [delete]
+func (h *WebhookHandler) Delete(c *gin.Context) {
+	id, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), id); err != nil {
+		h.log(c).Error("Failed to delete webhook %d: %v", id, err)
+		errorResponse(c, err, "Failed to delete webhook")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Get webhook deliveries
+// @Description Get the most recent deliveries of a webhook, newest first
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param limit query int false "Number of deliveries" default(50)
+// @Success 200 {array} domain.WebhookDelivery
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) Deliveries(c *gin.Context) {
+	id, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		limit = 50
+	}
+
+	deliveries, err := h.service.Deliveries(c.Request.Context(), id, limit)
+	if err != nil {
+		h.log(c).Error("Failed to get deliveries of webhook %d: %v", id, err)
+		errorResponse(c, err, "Failed to get webhook deliveries")
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// @Summary Replay webhook delivery
+// @Description Send a delivery again, resetting its attempts
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param delivery_id path int true "Delivery ID"
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
+// @Success 202 {object} domain.WebhookDelivery
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /webhooks/{id}/deliveries/{delivery_id}/replay [post]
+func (h *WebhookHandler) Replay(c *gin.Context) {
+	id, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	deliveryID, ok := h.parseID(c, "delivery_id")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.service.Replay(c.Request.Context(), id, deliveryID)
+	if err != nil {
+		h.log(c).Error("Failed to replay delivery %d of webhook %d: %v", deliveryID, id, err)
+		errorResponse(c, err, "Failed to replay webhook delivery")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+func (h *WebhookHandler) parseID(c *gin.Context, param string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(param), 10, 64)
+	if err != nil {
+		h.log(c).Debug("Invalid ID parameter: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID parameter"})
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"time"
+)
+
+type AuditRepository interface {
+	Create(ctx context.Context, entry domain.AuditEntry) error
+	GetByPersonID(ctx context.Context, personID int) ([]domain.AuditEntry, error)
+	// GetByPersonIDs returns the entries of every given person, keyed by
+	// person ID, in one query.
+	GetByPersonIDs(ctx context.Context, personIDs []int) (map[int][]domain.AuditEntry, error)
+}
+
+type auditRepository struct {
+	db     *sqlx.DB
+	logger logging.Logger
+}
+
+func NewAuditRepository(db *sqlx.DB, logger logging.Logger) AuditRepository {
+	return &auditRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *auditRepository) log(ctx context.Context) logging.Logger {
+	return logging.FromContext(ctx, r.logger)
+}
+
+type auditRow struct {
+	ID        int64     `db:"id"`
+	PersonID  int       `db:"person_id"`
+	Action    string    `db:"action"`
+	Actor     string    `db:"actor"`
+	RequestID string    `db:"request_id"`
+	Source    string    `db:"source"`
+	OldData   []byte    `db:"old_data"`
+	NewData   []byte    `db:"new_data"`
+	Diff      []byte    `db:"diff"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (r *auditRepository) Create(ctx context.Context, entry domain.AuditEntry) error {
+	query := `INSERT INTO person_audit (person_id, action, actor, request_id, source, old_data, new_data, diff) 
+	          VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8)`
+
+	diff, err := json.Marshal(entry.Diff)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn(ctx, r.db).ExecContext(ctx, query,
+		entry.PersonID,
+		entry.Action,
+		entry.Actor,
+		entry.RequestID,
+		entry.Source,
+		nullJSON(entry.OldData),
+		nullJSON(entry.NewData),
+		diff,
+	)
+	if err != nil {
+		r.log(ctx).Error("Failed to write audit entry for person %d: %v", entry.PersonID, err)
+		return err
+	}
+
+	return nil
+}
+
+const auditColumns = `id, person_id, action, actor, COALESCE(request_id, '') AS request_id, source,
+	COALESCE(old_data, 'null') AS old_data, COALESCE(new_data, 'null') AS new_data, diff, created_at`
+
+func (r *auditRepository) GetByPersonID(ctx context.Context, personID int) ([]domain.AuditEntry, error) {
+	query := `SELECT ` + auditColumns + ` FROM person_audit WHERE person_id = $1 ORDER BY id`
+
+	var rows []auditRow
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &rows, query, personID); err != nil {
+		r.log(ctx).Error("Failed to get audit entries for person %d: %v", personID, err)
+		return nil, err
+	}
+
+	return auditEntries(rows)
+}
+
+func (r *auditRepository) GetByPersonIDs(ctx context.Context, personIDs []int) (map[int][]domain.AuditEntry, error) {
+	query := `SELECT ` + auditColumns + ` FROM person_audit WHERE person_id = ANY($1) ORDER BY id`
+
+	ids := make([]int64, len(personIDs))
+	for i, id := range personIDs {
+		ids[i] = int64(id)
+	}
+
+	var rows []auditRow
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &rows, query, pq.Array(ids)); err != nil {
+		r.log(ctx).Error("Failed to get audit entries for %d people: %v", len(personIDs), err)
+		return nil, err
+	}
+
+	entries, err := auditEntries(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	byPerson := make(map[int][]domain.AuditEntry, len(personIDs))
+	for _, entry := range entries {
+		byPerson[entry.PersonID] = append(byPerson[entry.PersonID], entry)
+	}
+	return byPerson, nil
+}
+
+func auditEntries(rows []auditRow) ([]domain.AuditEntry, error) {
+	entries := make([]domain.AuditEntry, 0, len(rows))
+	for _, row := range rows {
+		entry := domain.AuditEntry{
+			ID:        row.ID,
+			PersonID:  row.PersonID,
+			Action:    row.Action,
+			Actor:     row.Actor,
+			RequestID: row.RequestID,
+			Source:    row.Source,
+			OldData:   row.OldData,
+			NewData:   row.NewData,
+			CreatedAt: row.CreatedAt,
+		}
+		if err := json.Unmarshal(row.Diff, &entry.Diff); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func nullJSON(data json.RawMessage) interface{} {
+	if len(data) == 0 || string(data) == "null" {
+		return nil
+	}
+	return []byte(data)
+}
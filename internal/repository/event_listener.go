@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"github.com/RakhimovAns/Person-Service/internal/config"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/lib/pq"
+	"strconv"
+	"time"
+)
+
+// listenerPingInterval is how often an idle listener checks its connection,
+// so that a silently dropped connection is noticed and re-established.
+const listenerPingInterval = 90 * time.Second
+
+// EventListener receives the IDs of events committed to the outbox by any
+// instance, through LISTEN on EventsChannel.
+type EventListener struct {
+	listener *pq.Listener
+	logger   logging.Logger
+}
+
+func NewEventListener(cfg config.DBConfig, minReconnect, maxReconnect time.Duration, logger logging.Logger) *EventListener {
+	l := &EventListener{logger: logger}
+	l.listener = pq.NewListener(connString(cfg), minReconnect, maxReconnect, l.logEvent)
+	return l
+}
+
+// Listen calls fn with the ID of every notified event until ctx is
+// cancelled. Notifications sent while the connection was down are lost, so
+// fn is called with zero after every reconnect to let the caller catch up.
+func (l *EventListener) Listen(ctx context.Context, fn func(eventID int64)) error {
+	defer l.listener.Close()
+
+	if err := l.listener.Listen(EventsChannel); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(listenerPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case notification := <-l.listener.Notify:
+			if notification == nil {
+				fn(0)
+				continue
+			}
+			id, err := strconv.ParseInt(notification.Extra, 10, 64)
+			if err != nil {
+				l.logger.Warn("Ignoring malformed notification %q: %v", notification.Extra, err)
+				continue
+			}
+			fn(id)
+		case <-ticker.C:
+			go func() {
+				if err := l.listener.Ping(); err != nil {
+					l.logger.Warn("Event listener ping failed: %v", err)
+				}
+			}()
+		}
+	}
+}
+
+func (l *EventListener) logEvent(event pq.ListenerEventType, err error) {
+	switch event {
+	case pq.ListenerEventConnected:
+		l.logger.Info("Listening for events on %s", EventsChannel)
+	case pq.ListenerEventDisconnected:
+		l.logger.Warn("Event listener disconnected: %v", err)
+	case pq.ListenerEventReconnected:
+		l.logger.Info("Event listener reconnected")
+	case pq.ListenerEventConnectionAttemptFailed:
+		l.logger.Error("Event listener failed to connect: %v", err)
+	}
+}
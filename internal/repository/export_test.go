@@ -0,0 +1,3 @@
+package repository
+
+var LoadMigrations = loadMigrations
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/jmoiron/sqlx"
+)
+
+type HealthRepository interface {
+	Ping(ctx context.Context) error
+}
+
+type healthRepository struct {
+	db     *sqlx.DB
+	logger logging.Logger
+}
+
+func NewHealthRepository(db *sqlx.DB, logger logging.Logger) HealthRepository {
+	return &healthRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *healthRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
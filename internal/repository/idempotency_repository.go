@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type IdempotencyRepository interface {
+	// Reserve claims key for a new request under token. When the key is
+	// already taken by a live record, the record is returned and reserved is
+	// false. Expired records, and reservations left without a response for
+	// lockTimeout, are taken over as if they did not exist.
+	Reserve(ctx context.Context, key, requestHash, token string, ttl, lockTimeout time.Duration) (record domain.IdempotencyRecord, reserved bool, err error)
+	// Complete and Release only apply to the reservation made with token.
+	// Once it has been taken over, Complete fails with
+	// domain.ErrIdempotencyKeyLost and Release does nothing.
+	Complete(ctx context.Context, key, token string, statusCode int, headers map[string]string, body []byte) error
+	Release(ctx context.Context, key, token string) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type idempotencyRepository struct {
+	db     *sqlx.DB
+	logger logging.Logger
+}
+
+func NewIdempotencyRepository(db *sqlx.DB, logger logging.Logger) IdempotencyRepository {
+	return &idempotencyRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *idempotencyRepository) log(ctx context.Context) logging.Logger {
+	return logging.FromContext(ctx, r.logger)
+}
+
+type idempotencyRow struct {
+	Key          string    `db:"key"`
+	RequestHash  string    `db:"request_hash"`
+	StatusCode   *int      `db:"status_code"`
+	Headers      []byte    `db:"headers"`
+	ResponseBody []byte    `db:"response_body"`
+	CreatedAt    time.Time `db:"created_at"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
+
+func (r *idempotencyRepository) Reserve(ctx context.Context, key, requestHash, token string, ttl, lockTimeout time.Duration) (domain.IdempotencyRecord, bool, error) {
+	query := `INSERT INTO idempotency_keys (key, request_hash, token, expires_at)
+	          VALUES ($1, $2, $5, CURRENT_TIMESTAMP + make_interval(secs => $3))
+	          ON CONFLICT (key) DO UPDATE SET request_hash = EXCLUDED.request_hash, token = EXCLUDED.token, status_code = NULL,
+	                 headers = NULL, response_body = NULL, created_at = CURRENT_TIMESTAMP, expires_at = EXCLUDED.expires_at
+	          WHERE idempotency_keys.expires_at <= CURRENT_TIMESTAMP
+	             OR (idempotency_keys.status_code IS NULL AND idempotency_keys.created_at <= CURRENT_TIMESTAMP - make_interval(secs => $4))
+	          RETURNING key, request_hash, status_code, headers, response_body, created_at, expires_at`
+
+	var row idempotencyRow
+	err := sqlx.GetContext(ctx, conn(ctx, r.db), &row, query, key, requestHash, ttl.Seconds(), lockTimeout.Seconds(), token)
+	if err == nil {
+		record, err := row.record()
+		return record, true, err
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		r.log(ctx).Error("Failed to reserve idempotency key: %v", err)
+		return domain.IdempotencyRecord{}, false, err
+	}
+
+	query = `SELECT key, request_hash, status_code, headers, response_body, created_at, expires_at
+	         FROM idempotency_keys WHERE key = $1`
+
+	if err := sqlx.GetContext(ctx, conn(ctx, r.db), &row, query, key); err != nil {
+		r.log(ctx).Error("Failed to get idempotency key: %v", err)
+		return domain.IdempotencyRecord{}, false, err
+	}
+
+	record, err := row.record()
+	return record, false, err
+}
+
+func (r *idempotencyRepository) Complete(ctx context.Context, key, token string, statusCode int, headers map[string]string, body []byte) error {
+	query := `UPDATE idempotency_keys SET status_code = $3, headers = $4, response_body = $5 
+	          WHERE key = $1 AND token = $2 AND status_code IS NULL`
+
+	encoded, err := json.Marshal(headers)
+	if err != nil {
+		return err
+	}
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, key, token, statusCode, encoded, body)
+	if err != nil {
+		r.log(ctx).Error("Failed to store idempotent response: %v", err)
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrIdempotencyKeyLost
+	}
+	return nil
+}
+
+func (r *idempotencyRepository) Release(ctx context.Context, key, token string) error {
+	query := `DELETE FROM idempotency_keys WHERE key = $1 AND token = $2 AND status_code IS NULL`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, key, token); err != nil {
+		r.log(ctx).Error("Failed to release idempotency key: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *idempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	query := `DELETE FROM idempotency_keys WHERE expires_at <= CURRENT_TIMESTAMP`
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query)
+	if err != nil {
+		r.log(ctx).Error("Failed to delete expired idempotency keys: %v", err)
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (row idempotencyRow) record() (domain.IdempotencyRecord, error) {
+	record := domain.IdempotencyRecord{
+		Key:         row.Key,
+		RequestHash: row.RequestHash,
+		StatusCode:  row.StatusCode,
+		Body:        row.ResponseBody,
+		CreatedAt:   row.CreatedAt,
+		ExpiresAt:   row.ExpiresAt,
+	}
+	if len(row.Headers) > 0 {
+		if err := json.Unmarshal(row.Headers, &record.Headers); err != nil {
+			return domain.IdempotencyRecord{}, err
+		}
+	}
+	return record, nil
+}
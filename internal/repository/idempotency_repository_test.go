@@ -0,0 +1,70 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/internal/repository"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyTakeover(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.New("error", "text")
+	db := openTestDB(t, logger)
+	if _, err := db.ExecContext(ctx, `TRUNCATE idempotency_keys`); err != nil {
+		t.Fatal(err)
+	}
+	repo := repository.NewIdempotencyRepository(db, logger)
+
+	key, hash := "key", strings.Repeat("a", 64)
+	first, second, third := strings.Repeat("1", 32), strings.Repeat("2", 32), strings.Repeat("3", 32)
+
+	reserve := func(token string, lockTimeout time.Duration) (domain.IdempotencyRecord, bool) {
+		t.Helper()
+		record, reserved, err := repo.Reserve(ctx, key, hash, token, time.Hour, lockTimeout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return record, reserved
+	}
+
+	if _, reserved := reserve(first, time.Hour); !reserved {
+		t.Fatal("new key was not reserved")
+	}
+	if record, reserved := reserve(second, time.Hour); reserved || record.StatusCode != nil {
+		t.Fatalf("got %+v, reserved %v; want the reservation in progress", record, reserved)
+	}
+
+	// The first request is now past the lock timeout.
+	if _, reserved := reserve(second, 0); !reserved {
+		t.Fatal("stale reservation was not taken over")
+	}
+
+	// The first request finishing late must leave the new reservation alone.
+	if err := repo.Complete(ctx, key, first, 200, nil, []byte("first")); !errors.Is(err, domain.ErrIdempotencyKeyLost) {
+		t.Errorf("Complete with the old token: got %v, want %v", err, domain.ErrIdempotencyKeyLost)
+	}
+	if err := repo.Release(ctx, key, first); err != nil {
+		t.Fatal(err)
+	}
+	if record, reserved := reserve(third, time.Hour); reserved || record.StatusCode != nil {
+		t.Fatalf("got %+v, reserved %v; want the second reservation still in progress", record, reserved)
+	}
+
+	if err := repo.Complete(ctx, key, second, 201, map[string]string{"Location": "/api/v1/people/1"}, []byte("second")); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Release(ctx, key, second); err != nil {
+		t.Fatal(err)
+	}
+
+	record, reserved := reserve(third, 0)
+	if reserved || record.StatusCode == nil || *record.StatusCode != 201 || string(record.Body) != "second" ||
+		record.Headers["Location"] != "/api/v1/people/1" {
+		t.Errorf("got %+v, reserved %v; want the response of the second request", record, reserved)
+	}
+}
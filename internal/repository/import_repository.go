@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+const importJobColumns = `id, status, format, filename, actor, total, processed, succeeded, skipped, failed, error, 
+	created_at, started_at, finished_at`
+
+type ImportRepository interface {
+	Create(ctx context.Context, job domain.ImportJob, payload []byte) (domain.ImportJob, error)
+	GetByID(ctx context.Context, id int64) (domain.ImportJob, error)
+	GetPayload(ctx context.Context, id int64) ([]byte, error)
+	// Claim leases the oldest unfinished job whose lease has expired to
+	// owner, so that jobs interrupted by a restart are picked up again.
+	Claim(ctx context.Context, owner string, lease time.Duration) (domain.ImportJob, error)
+	// RenewLease, the Record methods and Finish only apply while owner holds
+	// the lease on the job, and fail with domain.ErrImportLeaseLost once
+	// another worker has claimed it.
+	RenewLease(ctx context.Context, id int64, owner string, lease time.Duration) error
+	RecordSuccess(ctx context.Context, id int64, owner string, lease time.Duration) error
+	RecordSkipped(ctx context.Context, id int64, owner string, lease time.Duration) error
+	RecordFailure(ctx context.Context, id int64, owner string, lease time.Duration, importErr domain.ImportError) error
+	Finish(ctx context.Context, id int64, owner string, status string, message *string) error
+	GetErrors(ctx context.Context, id int64) ([]domain.ImportError, error)
+}
+
+type importRepository struct {
+	db     *sqlx.DB
+	logger logging.Logger
+}
+
+func NewImportRepository(db *sqlx.DB, logger logging.Logger) ImportRepository {
+	return &importRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *importRepository) log(ctx context.Context) logging.Logger {
+	return logging.FromContext(ctx, r.logger)
+}
+
+func (r *importRepository) Create(ctx context.Context, job domain.ImportJob, payload []byte) (domain.ImportJob, error) {
+	query := `INSERT INTO import_jobs (format, filename, actor, total, payload) 
+	          VALUES ($1, $2, $3, $4, $5) RETURNING ` + importJobColumns
+
+	var created domain.ImportJob
+	err := sqlx.GetContext(ctx, conn(ctx, r.db), &created, query,
+		job.Format,
+		job.Filename,
+		job.Actor,
+		job.Total,
+		payload,
+	)
+	if err != nil {
+		r.log(ctx).Error("Failed to create import job: %v", err)
+		return domain.ImportJob{}, err
+	}
+
+	return created, nil
+}
+
+func (r *importRepository) GetByID(ctx context.Context, id int64) (domain.ImportJob, error) {
+	query := `SELECT ` + importJobColumns + ` FROM import_jobs WHERE id = $1`
+
+	var job domain.ImportJob
+	err := sqlx.GetContext(ctx, conn(ctx, r.db), &job, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ImportJob{}, domain.ErrImportNotFound
+	}
+	if err != nil {
+		r.log(ctx).Error("Failed to get import job %d: %v", id, err)
+		return domain.ImportJob{}, err
+	}
+
+	return job, nil
+}
+
+func (r *importRepository) GetPayload(ctx context.Context, id int64) ([]byte, error) {
+	var payload []byte
+	err := sqlx.GetContext(ctx, conn(ctx, r.db), &payload, `SELECT payload FROM import_jobs WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrImportNotFound
+	}
+	if err != nil {
+		r.log(ctx).Error("Failed to get payload of import job %d: %v", id, err)
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+func (r *importRepository) Claim(ctx context.Context, owner string, lease time.Duration) (domain.ImportJob, error) {
+	query := `UPDATE import_jobs SET status = 'running', lease_owner = $2, 
+	                 started_at = COALESCE(started_at, CURRENT_TIMESTAMP), 
+	                 lease_until = CURRENT_TIMESTAMP + make_interval(secs => $1) 
+	          WHERE id = (
+	              SELECT id FROM import_jobs 
+	              WHERE status IN ('pending', 'running') AND (lease_until IS NULL OR lease_until < CURRENT_TIMESTAMP) 
+	              ORDER BY id LIMIT 1 FOR UPDATE SKIP LOCKED
+	          ) RETURNING ` + importJobColumns
+
+	var job domain.ImportJob
+	err := sqlx.GetContext(ctx, conn(ctx, r.db), &job, query, lease.Seconds(), owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ImportJob{}, domain.ErrImportNotFound
+	}
+	if err != nil {
+		r.log(ctx).Error("Failed to claim import job: %v", err)
+		return domain.ImportJob{}, err
+	}
+
+	return job, nil
+}
+
+func (r *importRepository) RenewLease(ctx context.Context, id int64, owner string, lease time.Duration) error {
+	query := `UPDATE import_jobs SET lease_until = CURRENT_TIMESTAMP + make_interval(secs => $3) 
+	          WHERE id = $1 AND lease_owner = $2 AND status = 'running'`
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, id, owner, lease.Seconds())
+	if err != nil {
+		r.log(ctx).Error("Failed to renew lease of import job %d: %v", id, err)
+		return err
+	}
+
+	return checkLeased(res)
+}
+
+func (r *importRepository) RecordSuccess(ctx context.Context, id int64, owner string, lease time.Duration) error {
+	query := `UPDATE import_jobs SET processed = processed + 1, succeeded = succeeded + 1, 
+	                 lease_until = CURRENT_TIMESTAMP + make_interval(secs => $3) 
+	          WHERE id = $1 AND lease_owner = $2 AND status = 'running'`
+
+	return r.recordProgress(ctx, id, query, owner, lease)
+}
+
+func (r *importRepository) RecordSkipped(ctx context.Context, id int64, owner string, lease time.Duration) error {
+	query := `UPDATE import_jobs SET processed = processed + 1, skipped = skipped + 1, 
+	                 lease_until = CURRENT_TIMESTAMP + make_interval(secs => $3) 
+	          WHERE id = $1 AND lease_owner = $2 AND status = 'running'`
+
+	return r.recordProgress(ctx, id, query, owner, lease)
+}
+
+func (r *importRepository) RecordFailure(ctx context.Context, id int64, owner string, lease time.Duration, importErr domain.ImportError) error {
+	return withinTx(ctx, r.db, func(ctx context.Context) error {
+		query := `UPDATE import_jobs SET processed = processed + 1, failed = failed + 1, 
+		                 lease_until = CURRENT_TIMESTAMP + make_interval(secs => $3) 
+		          WHERE id = $1 AND lease_owner = $2 AND status = 'running'`
+		if err := r.recordProgress(ctx, id, query, owner, lease); err != nil {
+			return err
+		}
+
+		if _, err := conn(ctx, r.db).ExecContext(ctx,
+			`INSERT INTO import_job_errors (job_id, line, raw, error) VALUES ($1, $2, $3, $4)`,
+			id, importErr.Line, importErr.Raw, importErr.Error,
+		); err != nil {
+			r.log(ctx).Error("Failed to record error of import job %d: %v", id, err)
+			return err
+		}
+
+		return nil
+	})
+}
+
+// recordProgress runs an update of the counters of job id, which also
+// checks that owner still holds the lease.
+func (r *importRepository) recordProgress(ctx context.Context, id int64, query string, owner string, lease time.Duration) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, id, owner, lease.Seconds())
+	if err != nil {
+		r.log(ctx).Error("Failed to record progress of import job %d: %v", id, err)
+		return err
+	}
+
+	return checkLeased(res)
+}
+
+func (r *importRepository) Finish(ctx context.Context, id int64, owner string, status string, message *string) error {
+	query := `UPDATE import_jobs SET status = $3, error = $4, lease_owner = NULL, lease_until = NULL, finished_at = CURRENT_TIMESTAMP 
+	          WHERE id = $1 AND lease_owner = $2 AND status = 'running'`
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, id, owner, status, message)
+	if err != nil {
+		r.log(ctx).Error("Failed to finish import job %d: %v", id, err)
+		return err
+	}
+
+	return checkLeased(res)
+}
+
+// checkLeased maps an update that matched no job to domain.ErrImportLeaseLost.
+func checkLeased(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrImportLeaseLost
+	}
+	return nil
+}
+
+func (r *importRepository) GetErrors(ctx context.Context, id int64) ([]domain.ImportError, error) {
+	query := `SELECT line, raw, error FROM import_job_errors WHERE job_id = $1 ORDER BY line`
+
+	var importErrors []domain.ImportError
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &importErrors, query, id); err != nil {
+		r.log(ctx).Error("Failed to get errors of import job %d: %v", id, err)
+		return nil, err
+	}
+
+	return importErrors, nil
+}
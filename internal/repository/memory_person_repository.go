@@ -0,0 +1,457 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MemoryDB keeps people in memory, for tests and demos. Nothing survives a
+// restart. Every read and write locks the database on its own; transactions
+// keep their writes to themselves until they commit.
+type MemoryDB struct {
+	mu     sync.Mutex
+	lastID int
+	people map[int]domain.Person
+	// merges maps the ID of a merged person to the person it was merged into.
+	merges map[int]int
+}
+
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{
+		people: map[int]domain.Person{},
+		merges: map[int]int{},
+	}
+}
+
+// run calls fn with the database locked and the transaction bound to ctx,
+// or nil when there is none.
+func (db *MemoryDB) run(ctx context.Context, fn func(tx *memoryTx) error) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return fn(db.tx(ctx))
+}
+
+// person returns the person stored under id as tx sees it.
+func (db *MemoryDB) person(tx *memoryTx, id int) (domain.Person, bool) {
+	if tx != nil {
+		if person, ok := tx.people[id]; ok {
+			if person == nil {
+				return domain.Person{}, false
+			}
+			return *person, true
+		}
+	}
+	person, ok := db.people[id]
+	return person, ok
+}
+
+// eachPerson calls fn with every person as tx sees them, in no particular
+// order.
+func (db *MemoryDB) eachPerson(tx *memoryTx, fn func(person domain.Person)) {
+	for id, person := range db.people {
+		if tx != nil {
+			if _, ok := tx.people[id]; ok {
+				continue
+			}
+		}
+		fn(person)
+	}
+	if tx != nil {
+		for _, person := range tx.people {
+			if person != nil {
+				fn(*person)
+			}
+		}
+	}
+}
+
+// mergeTarget returns the person sourceID was merged into as tx sees it.
+func (db *MemoryDB) mergeTarget(tx *memoryTx, sourceID int) (int, bool) {
+	if tx != nil {
+		if targetID, ok := tx.merges[sourceID]; ok {
+			return targetID, targetID != 0
+		}
+	}
+	targetID, ok := db.merges[sourceID]
+	return targetID, ok
+}
+
+// mergesInto returns the people merged into targetID as tx sees them.
+func (db *MemoryDB) mergesInto(tx *memoryTx, targetID int) []int {
+	var sourceIDs []int
+	for sourceID := range db.merges {
+		if mergedInto, ok := db.mergeTarget(tx, sourceID); ok && mergedInto == targetID {
+			sourceIDs = append(sourceIDs, sourceID)
+		}
+	}
+	if tx != nil {
+		for sourceID, mergedInto := range tx.merges {
+			if _, stored := db.merges[sourceID]; !stored && mergedInto == targetID {
+				sourceIDs = append(sourceIDs, sourceID)
+			}
+		}
+	}
+	return sourceIDs
+}
+
+// setPerson stores person under id, or removes it when person is nil, as
+// part of tx.
+func (db *MemoryDB) setPerson(tx *memoryTx, id int, person *domain.Person) {
+	var stored *domain.Person
+	if person != nil {
+		clone := clonePerson(*person)
+		stored = &clone
+	}
+
+	if tx == nil {
+		if stored == nil {
+			delete(db.people, id)
+		} else {
+			db.people[id] = *stored
+		}
+		return
+	}
+
+	if _, ok := tx.basePeople[id]; !ok {
+		var base *domain.Person
+		if old, ok := db.people[id]; ok {
+			base = &old
+		}
+		tx.basePeople[id] = base
+	}
+	tx.people[id] = stored
+}
+
+// setMerge records that sourceID was merged into targetID, or forgets it
+// when targetID is zero, as part of tx.
+func (db *MemoryDB) setMerge(tx *memoryTx, sourceID, targetID int) {
+	if tx == nil {
+		if targetID == 0 {
+			delete(db.merges, sourceID)
+		} else {
+			db.merges[sourceID] = targetID
+		}
+		return
+	}
+
+	if _, ok := tx.baseMerges[sourceID]; !ok {
+		tx.baseMerges[sourceID] = db.merges[sourceID]
+	}
+	tx.merges[sourceID] = targetID
+}
+
+// deletePerson removes a person and, as the foreign key on person_merges
+// does in SQL, the merges into it.
+func (db *MemoryDB) deletePerson(tx *memoryTx, id int) {
+	db.setPerson(tx, id, nil)
+	for _, sourceID := range db.mergesInto(tx, id) {
+		db.setMerge(tx, sourceID, 0)
+	}
+}
+
+// clonePerson copies person so that the stored value shares no pointers with
+// the caller's.
+func clonePerson(person domain.Person) domain.Person {
+	if person.Patronymic != nil {
+		patronymic := *person.Patronymic
+		person.Patronymic = &patronymic
+	}
+	if person.DeletedAt != nil {
+		deletedAt := *person.DeletedAt
+		person.DeletedAt = &deletedAt
+	}
+	return person
+}
+
+type memoryPersonRepository struct {
+	db *MemoryDB
+}
+
+func NewMemoryPersonRepository(db *MemoryDB) PersonRepository {
+	return &memoryPersonRepository{db: db}
+}
+
+func (r *memoryPersonRepository) Create(ctx context.Context, person domain.Person) (domain.Person, error) {
+	var created domain.Person
+	err := r.db.run(ctx, func(tx *memoryTx) error {
+		now := time.Now().UTC()
+
+		// Like a serial column, IDs are not given back on rollback.
+		r.db.lastID++
+		id := r.db.lastID
+
+		created = domain.Person{
+			ID:          id,
+			Name:        person.Name,
+			Surname:     person.Surname,
+			Patronymic:  person.Patronymic,
+			Age:         person.Age,
+			Gender:      person.Gender,
+			Nationality: person.Nationality,
+			Version:     1,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		r.db.setPerson(tx, id, &created)
+		created = clonePerson(created)
+		return nil
+	})
+
+	return created, err
+}
+
+func (r *memoryPersonRepository) GetAll(ctx context.Context, filter domain.PersonFilter, page, limit int) ([]domain.Person, error) {
+	people := r.find(ctx, filter)
+
+	offset := (page - 1) * limit
+	if limit <= 0 || offset < 0 || offset >= len(people) {
+		return nil, nil
+	}
+	return people[offset:min(offset+limit, len(people))], nil
+}
+
+func (r *memoryPersonRepository) Export(ctx context.Context, filter domain.PersonFilter, fn func(person domain.Person) error) error {
+	for _, person := range r.find(ctx, filter) {
+		if err := fn(person); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// find returns copies of the people matching filter, in the order
+// orderClause gives them in SQL.
+func (r *memoryPersonRepository) find(ctx context.Context, filter domain.PersonFilter) []domain.Person {
+	var people []domain.Person
+	_ = r.db.run(ctx, func(tx *memoryTx) error {
+		r.db.eachPerson(tx, func(person domain.Person) {
+			if filter.Matches(person) {
+				people = append(people, clonePerson(person))
+			}
+		})
+		return nil
+	})
+
+	field, descending := "id", false
+	switch {
+	case filter.Sort != "" && domain.ValidSort(filter.Sort):
+		field = strings.TrimPrefix(filter.Sort, "-")
+		descending = strings.HasPrefix(filter.Sort, "-")
+	case filter.UpdatedSince != nil:
+		field = "updated_at"
+	}
+
+	slices.SortFunc(people, func(a, b domain.Person) int {
+		order := comparePeople(a, b, field)
+		if order == 0 {
+			order = cmp.Compare(a.ID, b.ID)
+		}
+		if descending {
+			return -order
+		}
+		return order
+	})
+	return people
+}
+
+// comparePeople compares a and b by one of domain.SortFields.
+func comparePeople(a, b domain.Person, field string) int {
+	switch field {
+	case "name":
+		return strings.Compare(a.Name, b.Name)
+	case "surname":
+		return strings.Compare(a.Surname, b.Surname)
+	case "age":
+		return cmp.Compare(a.Age, b.Age)
+	case "created_at":
+		return a.CreatedAt.Compare(b.CreatedAt)
+	case "updated_at":
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	default:
+		return cmp.Compare(a.ID, b.ID)
+	}
+}
+
+func (r *memoryPersonRepository) GetByID(ctx context.Context, id int) (domain.Person, error) {
+	var person domain.Person
+	err := r.db.run(ctx, func(tx *memoryTx) error {
+		stored, ok := r.db.person(tx, id)
+		if !ok || stored.DeletedAt != nil {
+			return domain.ErrNotFound
+		}
+		person = clonePerson(stored)
+		return nil
+	})
+
+	return person, err
+}
+
+func (r *memoryPersonRepository) Update(ctx context.Context, id int, person domain.Person, expectedVersion int) (domain.Person, error) {
+	var updated domain.Person
+	err := r.db.run(ctx, func(tx *memoryTx) error {
+		stored, err := r.writable(tx, id, expectedVersion)
+		if err != nil {
+			return err
+		}
+
+		stored.Name = person.Name
+		stored.Surname = person.Surname
+		stored.Patronymic = person.Patronymic
+		stored.Age = person.Age
+		stored.Gender = person.Gender
+		stored.Nationality = person.Nationality
+		stored.Version++
+		stored.UpdatedAt = time.Now().UTC()
+		r.db.setPerson(tx, id, &stored)
+
+		updated = clonePerson(stored)
+		return nil
+	})
+
+	return updated, err
+}
+
+func (r *memoryPersonRepository) Delete(ctx context.Context, id int, expectedVersion int) error {
+	return r.db.run(ctx, func(tx *memoryTx) error {
+		stored, err := r.writable(tx, id, expectedVersion)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		stored.DeletedAt = &now
+		stored.Version++
+		stored.UpdatedAt = now
+		r.db.setPerson(tx, id, &stored)
+		return nil
+	})
+}
+
+// writable returns the person a conditional write applies to, telling apart
+// a missing person from a stale version like versionConflict does. The
+// database must be locked.
+func (r *memoryPersonRepository) writable(tx *memoryTx, id int, expectedVersion int) (domain.Person, error) {
+	stored, ok := r.db.person(tx, id)
+	if !ok || stored.DeletedAt != nil {
+		return domain.Person{}, domain.ErrNotFound
+	}
+	if expectedVersion != 0 && stored.Version != expectedVersion {
+		return domain.Person{}, domain.ErrVersionMismatch
+	}
+	return stored, nil
+}
+
+func (r *memoryPersonRepository) FindDuplicateCandidates(ctx context.Context, name, surname string, afterID, limit int) ([]domain.Person, error) {
+	var people []domain.Person
+	_ = r.db.run(ctx, func(tx *memoryTx) error {
+		r.db.eachPerson(tx, func(person domain.Person) {
+			if person.ID > afterID && person.DeletedAt == nil && initial(person.Name) == initial(name) && initial(person.Surname) == initial(surname) {
+				people = append(people, clonePerson(person))
+			}
+		})
+		return nil
+	})
+
+	slices.SortFunc(people, func(a, b domain.Person) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	if len(people) > limit {
+		people = people[:limit]
+	}
+	return people, nil
+}
+
+// initial returns the lower-cased first letter of s, or an empty string when
+// s is empty.
+func initial(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 {
+		return ""
+	}
+	return string(unicode.ToLower(r))
+}
+
+func (r *memoryPersonRepository) Merge(ctx context.Context, targetID, sourceID int, merged domain.Person, targetVersion, sourceVersion int) error {
+	return r.db.withinTx(ctx, func(ctx context.Context) error {
+		return r.db.run(ctx, func(tx *memoryTx) error {
+			if _, err := r.writable(tx, sourceID, sourceVersion); err != nil {
+				return err
+			}
+
+			target, err := r.writable(tx, targetID, targetVersion)
+			if err != nil {
+				return err
+			}
+			target.Name = merged.Name
+			target.Surname = merged.Surname
+			target.Patronymic = merged.Patronymic
+			target.Age = merged.Age
+			target.Gender = merged.Gender
+			target.Nationality = merged.Nationality
+			target.Version++
+			target.UpdatedAt = time.Now().UTC()
+			r.db.setPerson(tx, targetID, &target)
+
+			r.db.setMerge(tx, sourceID, targetID)
+			for _, mergedID := range r.db.mergesInto(tx, sourceID) {
+				r.db.setMerge(tx, mergedID, targetID)
+			}
+
+			r.db.deletePerson(tx, sourceID)
+			return nil
+		})
+	})
+}
+
+func (r *memoryPersonRepository) GetMergeTarget(ctx context.Context, id int) (int, error) {
+	var targetID int
+	err := r.db.run(ctx, func(tx *memoryTx) error {
+		var ok bool
+		targetID, ok = r.db.mergeTarget(tx, id)
+		if !ok {
+			return domain.ErrNotFound
+		}
+		return nil
+	})
+
+	return targetID, err
+}
+
+func (r *memoryPersonRepository) Restore(ctx context.Context, id int) error {
+	return r.db.run(ctx, func(tx *memoryTx) error {
+		stored, ok := r.db.person(tx, id)
+		if !ok || stored.DeletedAt == nil {
+			return domain.ErrNotFound
+		}
+
+		stored.DeletedAt = nil
+		stored.Version++
+		stored.UpdatedAt = time.Now().UTC()
+		r.db.setPerson(tx, id, &stored)
+		return nil
+	})
+}
+
+func (r *memoryPersonRepository) Purge(ctx context.Context, deletedBefore time.Time) ([]domain.Person, error) {
+	var purged []domain.Person
+	_ = r.db.run(ctx, func(tx *memoryTx) error {
+		r.db.eachPerson(tx, func(person domain.Person) {
+			if person.DeletedAt != nil && person.DeletedAt.Before(deletedBefore) {
+				purged = append(purged, clonePerson(person))
+			}
+		})
+		for _, person := range purged {
+			r.db.deletePerson(tx, person.ID)
+		}
+		return nil
+	})
+
+	return purged, nil
+}
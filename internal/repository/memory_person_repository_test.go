@@ -0,0 +1,21 @@
+package repository_test
+
+import (
+	"context"
+	"github.com/RakhimovAns/Person-Service/internal/repository"
+	"github.com/RakhimovAns/Person-Service/internal/repository/repotest"
+	"testing"
+)
+
+func TestMemoryPersonRepository(t *testing.T) {
+	err := repotest.TestPersonRepository(context.Background(), func() (repotest.Store, error) {
+		db := repository.NewMemoryDB()
+		return repotest.Store{
+			People:     repository.NewMemoryPersonRepository(db),
+			Transactor: repository.NewMemoryTransactor(db),
+		}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
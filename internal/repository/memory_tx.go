@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+)
+
+type memoryTxKey struct{}
+
+// memoryTx is a transaction on a MemoryDB: the writes it keeps to itself
+// until it commits, what was stored when it first wrote each of them, and
+// the callbacks waiting for it to commit. A nil memoryTx is no transaction,
+// and writes made through it are applied right away.
+type memoryTx struct {
+	db *MemoryDB
+	// people holds the people written, nil for a deleted one; merges the
+	// merges written, zero for a forgotten one.
+	people map[int]*domain.Person
+	merges map[int]int
+	// basePeople and baseMerges hold what was stored before the first write
+	// of each, to tell whether another write got there first.
+	basePeople  map[int]*domain.Person
+	baseMerges  map[int]int
+	afterCommit []func()
+}
+
+func newMemoryTx(db *MemoryDB) *memoryTx {
+	return &memoryTx{
+		db:         db,
+		people:     map[int]*domain.Person{},
+		merges:     map[int]int{},
+		basePeople: map[int]*domain.Person{},
+		baseMerges: map[int]int{},
+	}
+}
+
+// commit applies the writes of tx, unless a person or merge it wrote has
+// changed since. The database must be locked.
+func (tx *memoryTx) commit() error {
+	db := tx.db
+	for id, base := range tx.basePeople {
+		stored, ok := db.people[id]
+		if ok != (base != nil) || (ok && stored.Version != base.Version) {
+			return fmt.Errorf("%w: person %d was changed by another transaction", domain.ErrVersionMismatch, id)
+		}
+	}
+	for sourceID, base := range tx.baseMerges {
+		if db.merges[sourceID] != base {
+			return fmt.Errorf("%w: merge of person %d was changed by another transaction", domain.ErrVersionMismatch, sourceID)
+		}
+	}
+
+	for id, person := range tx.people {
+		db.setPerson(nil, id, person)
+	}
+	for sourceID, targetID := range tx.merges {
+		db.setMerge(nil, sourceID, targetID)
+	}
+	return nil
+}
+
+// tx returns the transaction on db bound to ctx, or nil when there is none.
+func (db *MemoryDB) tx(ctx context.Context) *memoryTx {
+	if tx, ok := ctx.Value(memoryTxKey{}).(*memoryTx); ok && tx.db == db {
+		return tx
+	}
+	return nil
+}
+
+// withinTx runs fn in a transaction, committing its writes when it succeeds.
+// The database is only locked by the reads and writes fn makes and by the
+// commit, so fn may take its time. Nested calls join the transaction bound
+// to ctx.
+func (db *MemoryDB) withinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if db.tx(ctx) != nil {
+		return fn(ctx)
+	}
+
+	tx := newMemoryTx(db)
+	if err := fn(context.WithValue(ctx, memoryTxKey{}, tx)); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	err := tx.commit()
+	db.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, callback := range tx.afterCommit {
+		callback()
+	}
+	return nil
+}
+
+type memoryTransactor struct {
+	db *MemoryDB
+}
+
+// NewMemoryTransactor runs transactions on db. fn runs once; a transaction
+// that wrote a person changed by another one since fails to commit with
+// domain.ErrVersionMismatch.
+func NewMemoryTransactor(db *MemoryDB) Transactor {
+	return &memoryTransactor{db: db}
+}
+
+func (t *memoryTransactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return t.db.withinTx(ctx, fn)
+}
+
+func (t *memoryTransactor) AfterCommit(ctx context.Context, fn func()) {
+	if tx := t.db.tx(ctx); tx != nil {
+		tx.afterCommit = append(tx.afterCommit, fn)
+		return
+	}
+	fn()
+}
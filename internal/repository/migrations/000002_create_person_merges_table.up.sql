@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS person_merges (
+    source_id INTEGER PRIMARY KEY,
+    target_id INTEGER NOT NULL REFERENCES people (id) ON DELETE CASCADE,
+    snapshot JSONB NOT NULL,
+    merged_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_person_merges_target_id ON person_merges (target_id);
@@ -0,0 +1,5 @@
+ALTER TABLE people ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_people_deleted_at ON people (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_people_initials ON people (lower(left(name, 1)), lower(left(surname, 1)), id) WHERE deleted_at IS NULL;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS person_audit (
+    id BIGSERIAL PRIMARY KEY,
+    person_id INTEGER NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    request_id VARCHAR(255),
+    source VARCHAR(50) NOT NULL,
+    old_data JSONB,
+    new_data JSONB,
+    diff JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_person_audit_person_id ON person_audit (person_id, id);
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_people_updated_at;
+DROP INDEX IF EXISTS idx_people_created_at;
+
+DROP TRIGGER IF EXISTS people_set_updated_at ON people;
+DROP FUNCTION IF EXISTS set_updated_at();
+
+ALTER TABLE people DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE people ALTER COLUMN created_at DROP NOT NULL;
+ALTER TABLE people ALTER COLUMN created_at SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE people ALTER COLUMN created_at TYPE TIMESTAMP;
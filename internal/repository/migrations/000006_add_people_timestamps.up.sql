@@ -0,0 +1,29 @@
+UPDATE people SET created_at = CURRENT_TIMESTAMP WHERE created_at IS NULL;
+
+ALTER TABLE people ALTER COLUMN created_at TYPE TIMESTAMPTZ;
+ALTER TABLE people ALTER COLUMN created_at SET DEFAULT clock_timestamp();
+ALTER TABLE people ALTER COLUMN created_at SET NOT NULL;
+
+ALTER TABLE people ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+
+UPDATE people SET updated_at = created_at WHERE updated_at IS NULL;
+
+ALTER TABLE people ALTER COLUMN updated_at SET DEFAULT clock_timestamp();
+ALTER TABLE people ALTER COLUMN updated_at SET NOT NULL;
+
+CREATE OR REPLACE FUNCTION set_updated_at() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = clock_timestamp();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS people_set_updated_at ON people;
+
+CREATE TRIGGER people_set_updated_at
+    BEFORE UPDATE ON people
+    FOR EACH ROW
+    EXECUTE FUNCTION set_updated_at();
+
+CREATE INDEX IF NOT EXISTS idx_people_created_at ON people (created_at);
+CREATE INDEX IF NOT EXISTS idx_people_updated_at ON people (updated_at, id);
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS import_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    format VARCHAR(10) NOT NULL,
+    filename VARCHAR(255) NOT NULL DEFAULT '',
+    payload BYTEA NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    total INTEGER NOT NULL DEFAULT 0,
+    processed INTEGER NOT NULL DEFAULT 0,
+    succeeded INTEGER NOT NULL DEFAULT 0,
+    skipped INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    lease_owner VARCHAR(64),
+    lease_until TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_jobs_pending ON import_jobs (id) WHERE status IN ('pending', 'running');
+
+CREATE TABLE IF NOT EXISTS import_job_errors (
+    id BIGSERIAL PRIMARY KEY,
+    job_id BIGINT NOT NULL REFERENCES import_jobs (id) ON DELETE CASCADE,
+    line INTEGER NOT NULL,
+    raw TEXT NOT NULL,
+    error TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_job_errors_job_id ON import_job_errors (job_id, line);
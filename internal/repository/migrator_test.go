@@ -0,0 +1,125 @@
+package repository_test
+
+import (
+	"context"
+	"github.com/RakhimovAns/Person-Service/internal/repository"
+	"github.com/RakhimovAns/Person-Service/internal/repository/migrations"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"10_add_index.up.sql":          {Data: []byte("CREATE INDEX")},
+		"10_add_index.down.sql":        {Data: []byte("DROP INDEX")},
+		"000002_add_column.up.sql":     {Data: []byte("ALTER TABLE ADD")},
+		"000002_add_column.down.sql":   {Data: []byte("ALTER TABLE DROP")},
+		"000001_create_table.up.sql":   {Data: []byte("CREATE TABLE")},
+		"000001_create_table.down.sql": {Data: []byte("DROP TABLE")},
+		"README.md":                    {Data: []byte("not a migration")},
+		"000003_draft.sql":             {Data: []byte("not a migration either")},
+	}
+
+	list, err := repository.LoadMigrations(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []repository.Migration{
+		{Version: 1, Name: "create_table", Up: "CREATE TABLE", Down: "DROP TABLE"},
+		{Version: 2, Name: "add_column", Up: "ALTER TABLE ADD", Down: "ALTER TABLE DROP"},
+		{Version: 10, Name: "add_index", Up: "CREATE INDEX", Down: "DROP INDEX"},
+	}
+	if len(list) != len(want) {
+		t.Fatalf("got %+v, want %+v", list, want)
+	}
+	for i := range want {
+		if list[i] != want[i] {
+			t.Errorf("migration %d: got %+v, want %+v", i, list[i], want[i])
+		}
+	}
+}
+
+func TestLoadMigrationsIncomplete(t *testing.T) {
+	for name, fsys := range map[string]fstest.MapFS{
+		"no down": {"000001_create_table.up.sql": {Data: []byte("CREATE TABLE")}},
+		"no up":   {"000001_create_table.down.sql": {Data: []byte("DROP TABLE")}},
+	} {
+		if _, err := repository.LoadMigrations(fsys); err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("%s: got %v, want an error saying so", name, err)
+		}
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	list, err := repository.LoadMigrations(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	for i, migration := range list {
+		if strings.TrimSpace(migration.Up) == "" || strings.TrimSpace(migration.Down) == "" {
+			t.Errorf("migration %d_%s is empty", migration.Version, migration.Name)
+		}
+		if i > 0 && migration.Version <= list[i-1].Version {
+			t.Errorf("migration %d_%s is out of order", migration.Version, migration.Name)
+		}
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.New("error", "text")
+	db := openTestDB(t, logger)
+
+	migrator, err := repository.NewMigrator(db, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied := func() []bool {
+		t.Helper()
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var applied []bool
+		for _, status := range statuses {
+			applied = append(applied, status.Applied)
+		}
+		return applied
+	}
+
+	all := applied()
+	for i, ok := range all {
+		if !ok {
+			t.Fatalf("migration %d is not applied after Up", i)
+		}
+	}
+
+	if err := migrator.Down(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	afterDown := applied()
+	for i, ok := range afterDown {
+		if want := i < len(all)-2; ok != want {
+			t.Errorf("migration %d: got applied %v after rolling back two, want %v", i, ok, want)
+		}
+	}
+
+	// Up is idempotent and brings back what was rolled back.
+	for range 2 {
+		if err := migrator.Up(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, ok := range applied() {
+		if !ok {
+			t.Errorf("migration %d is not applied after Up", i)
+		}
+	}
+}
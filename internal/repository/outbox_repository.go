@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"time"
+)
+
+// EventsChannel is the channel notified with the ID of every event written
+// to the outbox, once the transaction writing it commits.
+const EventsChannel = "person_events"
+
+const outboxColumns = `id, type, person_id, actor, COALESCE(request_id, '') AS request_id, source, person, changes,
+	attempts, created_at`
+
+type OutboxRepository interface {
+	// Create stores an event and notifies EventsChannel. It must be called in
+	// the transaction that makes the change the event describes.
+	Create(ctx context.Context, event domain.Event) error
+	GetByID(ctx context.Context, id int64) (domain.Event, error)
+	// Since returns up to limit events created at or after createdSince with
+	// an ID above afterID, in ID order.
+	Since(ctx context.Context, createdSince time.Time, afterID int64, limit int) ([]domain.Event, error)
+	// Recent returns the last limit events, in ID order.
+	Recent(ctx context.Context, limit int) ([]domain.Event, error)
+	// Claim leases up to limit unpublished events that are due, in ID order,
+	// by pushing their next attempt lease into the future, so that they can
+	// be published outside of a transaction without other relays taking
+	// them.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]domain.Event, error)
+	// Release makes claimed events due again right away.
+	Release(ctx context.Context, ids []int64) error
+	MarkPublished(ctx context.Context, id int64) error
+	MarkFailed(ctx context.Context, id int64, message string, retryIn time.Duration) error
+	// DeletePublished deletes events published more than olderThan ago.
+	DeletePublished(ctx context.Context, olderThan time.Duration) (int64, error)
+}
+
+type outboxRepository struct {
+	db     *sqlx.DB
+	logger logging.Logger
+}
+
+func NewOutboxRepository(db *sqlx.DB, logger logging.Logger) OutboxRepository {
+	return &outboxRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *outboxRepository) log(ctx context.Context) logging.Logger {
+	return logging.FromContext(ctx, r.logger)
+}
+
+type outboxRow struct {
+	ID        int64     `db:"id"`
+	Type      string    `db:"type"`
+	PersonID  int       `db:"person_id"`
+	Actor     string    `db:"actor"`
+	RequestID string    `db:"request_id"`
+	Source    string    `db:"source"`
+	Person    []byte    `db:"person"`
+	Changes   []byte    `db:"changes"`
+	Attempts  int       `db:"attempts"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (r *outboxRepository) Create(ctx context.Context, event domain.Event) error {
+	query := `WITH inserted AS (
+	              INSERT INTO outbox_events (type, person_id, actor, request_id, source, person, changes)
+	              VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7) RETURNING id
+	          )
+	          SELECT pg_notify('` + EventsChannel + `', id::TEXT) FROM inserted`
+
+	person, err := json.Marshal(event.Person)
+	if err != nil {
+		return err
+	}
+
+	changes, err := json.Marshal(event.Changes)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn(ctx, r.db).ExecContext(ctx, query,
+		event.Type,
+		event.PersonID,
+		event.Actor,
+		event.RequestID,
+		event.Source,
+		person,
+		changes,
+	)
+	if err != nil {
+		r.log(ctx).Error("Failed to write %s event for person %d: %v", event.Type, event.PersonID, err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *outboxRepository) GetByID(ctx context.Context, id int64) (domain.Event, error) {
+	query := `SELECT ` + outboxColumns + ` FROM outbox_events WHERE id = $1`
+
+	var row outboxRow
+	if err := sqlx.GetContext(ctx, conn(ctx, r.db), &row, query, id); err != nil {
+		r.log(ctx).Error("Failed to get event %d: %v", id, err)
+		return domain.Event{}, err
+	}
+
+	events, err := outboxEvents([]outboxRow{row})
+	if err != nil {
+		return domain.Event{}, err
+	}
+	return events[0], nil
+}
+
+func (r *outboxRepository) Since(ctx context.Context, createdSince time.Time, afterID int64, limit int) ([]domain.Event, error) {
+	query := `SELECT ` + outboxColumns + ` FROM outbox_events
+	          WHERE created_at >= $1 AND id > $2 ORDER BY id LIMIT $3`
+
+	var rows []outboxRow
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &rows, query, createdSince, afterID, limit); err != nil {
+		r.log(ctx).Error("Failed to get events since %s: %v", createdSince, err)
+		return nil, err
+	}
+
+	return outboxEvents(rows)
+}
+
+func (r *outboxRepository) Recent(ctx context.Context, limit int) ([]domain.Event, error) {
+	query := `SELECT * FROM (
+	              SELECT ` + outboxColumns + ` FROM outbox_events ORDER BY id DESC LIMIT $1
+	          ) recent ORDER BY id`
+
+	var rows []outboxRow
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &rows, query, limit); err != nil {
+		r.log(ctx).Error("Failed to get recent events: %v", err)
+		return nil, err
+	}
+
+	return outboxEvents(rows)
+}
+
+func (r *outboxRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]domain.Event, error) {
+	query := `WITH claimed AS (
+	              UPDATE outbox_events SET next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $2)
+	              WHERE id IN (
+	                  SELECT id FROM outbox_events
+	                  WHERE published_at IS NULL AND next_attempt_at <= CURRENT_TIMESTAMP
+	                  ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED
+	              ) RETURNING ` + outboxColumns + `
+	          ) SELECT * FROM claimed ORDER BY id`
+
+	var rows []outboxRow
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &rows, query, limit, lease.Seconds()); err != nil {
+		r.log(ctx).Error("Failed to claim pending events: %v", err)
+		return nil, err
+	}
+
+	return outboxEvents(rows)
+}
+
+func (r *outboxRepository) Release(ctx context.Context, ids []int64) error {
+	query := `UPDATE outbox_events SET next_attempt_at = CURRENT_TIMESTAMP WHERE id = ANY($1) AND published_at IS NULL`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, pq.Array(ids)); err != nil {
+		r.log(ctx).Error("Failed to release events: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *outboxRepository) MarkPublished(ctx context.Context, id int64) error {
+	query := `UPDATE outbox_events SET published_at = CURRENT_TIMESTAMP, last_error = NULL WHERE id = $1`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, id); err != nil {
+		r.log(ctx).Error("Failed to mark event %d as published: %v", id, err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *outboxRepository) MarkFailed(ctx context.Context, id int64, message string, retryIn time.Duration) error {
+	query := `UPDATE outbox_events SET attempts = attempts + 1, last_error = $2,
+	                 next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $3)
+	          WHERE id = $1`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, id, message, retryIn.Seconds()); err != nil {
+		r.log(ctx).Error("Failed to record failure of event %d: %v", id, err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *outboxRepository) DeletePublished(ctx context.Context, olderThan time.Duration) (int64, error) {
+	query := `DELETE FROM outbox_events
+	          WHERE published_at IS NOT NULL AND published_at < CURRENT_TIMESTAMP - make_interval(secs => $1)`
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, olderThan.Seconds())
+	if err != nil {
+		r.log(ctx).Error("Failed to delete published events: %v", err)
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func outboxEvents(rows []outboxRow) ([]domain.Event, error) {
+	events := make([]domain.Event, 0, len(rows))
+	for _, row := range rows {
+		event := domain.Event{
+			ID:        row.ID,
+			Type:      row.Type,
+			PersonID:  row.PersonID,
+			Actor:     row.Actor,
+			RequestID: row.RequestID,
+			Source:    row.Source,
+			Attempts:  row.Attempts,
+			CreatedAt: row.CreatedAt,
+		}
+		if err := json.Unmarshal(row.Person, &event.Person); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(row.Changes, &event.Changes); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
@@ -2,84 +2,260 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/RakhimovAns/Person-Service/internal/config"
 	"github.com/RakhimovAns/Person-Service/internal/domain"
 	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
+	"net/url"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 )
 
+const personColumns = `id, name, surname, patronymic, age, gender, nationality, version, created_at, updated_at, deleted_at`
+
 type PersonRepository interface {
-	Create(ctx context.Context, person domain.Person) (int, error)
+	Create(ctx context.Context, person domain.Person) (domain.Person, error)
 	GetAll(ctx context.Context, filter domain.PersonFilter, page, limit int) ([]domain.Person, error)
+	// Export streams every person matching filter to fn row by row.
+	Export(ctx context.Context, filter domain.PersonFilter, fn func(person domain.Person) error) error
 	GetByID(ctx context.Context, id int) (domain.Person, error)
-	Update(ctx context.Context, id int, person domain.Person) error
-	Delete(ctx context.Context, id int) error
-}
-
-func NewPostgresDB(cfg config.DBConfig) (*sqlx.DB, error) {
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Host,
-		cfg.Port,
-		cfg.User,
-		cfg.Password,
-		cfg.Name,
-		cfg.SSLMode,
-	)
+	// Update and Delete only apply when the stored version equals
+	// expectedVersion; zero skips the check.
+	Update(ctx context.Context, id int, person domain.Person, expectedVersion int) (domain.Person, error)
+	Delete(ctx context.Context, id int, expectedVersion int) error
+	// FindDuplicateCandidates returns up to limit people, in ID order after
+	// afterID, whose name and surname start with the same letters as name
+	// and surname, ignoring case. Callers page through all of them.
+	FindDuplicateCandidates(ctx context.Context, name, surname string, afterID, limit int) ([]domain.Person, error)
+	// Merge only applies when the target and source are still at
+	// targetVersion and sourceVersion; zero skips the check.
+	Merge(ctx context.Context, targetID, sourceID int, merged domain.Person, targetVersion, sourceVersion int) error
+	GetMergeTarget(ctx context.Context, id int) (int, error)
+	Restore(ctx context.Context, id int) error
+	// Purge hard-deletes the people soft-deleted before deletedBefore and
+	// returns them as they were.
+	Purge(ctx context.Context, deletedBefore time.Time) ([]domain.Person, error)
+}
 
-	db, err := sqlx.Connect("postgres", connStr)
+// NewPostgresDB opens the connection pool and waits for the database to
+// accept connections, retrying with backoff up to cfg.ConnectRetries times so
+// that the service can start alongside Postgres.
+func NewPostgresDB(cfg config.DBConfig, logger logging.Logger) (*sqlx.DB, error) {
+	db, err := openPool(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %w", err)
+		return nil, err
 	}
 
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+	delay := cfg.ConnectMinBackoff
+	for attempt := 0; ; attempt++ {
+		err := db.Ping()
+		if err == nil {
+			return db, nil
+		}
+		if attempt >= cfg.ConnectRetries {
+			db.Close()
+			return nil, fmt.Errorf("failed to ping database: %w", err)
+		}
+
+		logger.Warn("Database is not available, retrying in %s: %v", delay, err)
+		time.Sleep(delay)
+		delay = min(delay*2, cfg.ConnectMaxBackoff)
 	}
+}
 
+// openPool opens a connection pool with the pool settings of cfg. It does not
+// connect.
+func openPool(cfg config.DBConfig) (*sqlx.DB, error) {
+	db, err := sqlx.Open("postgres", connString(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 	return db, nil
 }
 
+// connString returns cfg.DSN, or a key/value string built from the
+// individual settings when it is empty, leaving out those that are not set
+// so that the driver defaults apply. The application name and statement
+// timeout are added as run-time parameters.
+func connString(cfg config.DBConfig) string {
+	params := map[string]string{}
+	if cfg.ApplicationName != "" {
+		params["application_name"] = cfg.ApplicationName
+	}
+	if cfg.StatementTimeout > 0 {
+		params["statement_timeout"] = strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)
+	}
+
+	if cfg.DSN != "" {
+		return withParams(cfg.DSN, params)
+	}
+
+	settings := []struct{ key, value string }{
+		{"host", cfg.Host},
+		{"port", cfg.Port},
+		{"user", cfg.User},
+		{"password", cfg.Password},
+		{"dbname", cfg.Name},
+		{"sslmode", cfg.SSLMode},
+	}
+	var pairs []string
+	for _, setting := range settings {
+		if setting.value != "" {
+			pairs = append(pairs, setting.key+"="+quoteParam(setting.value))
+		}
+	}
+	dsn := strings.Join(pairs, " ")
+	return withParams(dsn, params)
+}
+
+// withParams adds params to dsn, as query parameters when it is a URL. A
+// parameter already present in dsn is left alone.
+func withParams(dsn string, params map[string]string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			// Leave it to the driver to report.
+			return dsn
+		}
+		query := u.Query()
+		for key, value := range params {
+			if !query.Has(key) {
+				query.Set(key, value)
+			}
+		}
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		if !strings.Contains(" "+dsn, " "+key+"=") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		dsn = strings.TrimSpace(dsn + " " + key + "=" + quoteParam(params[key]))
+	}
+	return dsn
+}
+
+// quoteParam quotes a key/value connection string value.
+func quoteParam(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
 type personRepository struct {
-	db     *sqlx.DB
-	logger logging.Logger
+	db       *sqlx.DB
+	replicas *ReplicaSet
+	logger   logging.Logger
 }
 
-func NewPersonRepository(db *sqlx.DB, logger logging.Logger) PersonRepository {
+// NewPersonRepository writes to db. Listing and export read from replicas
+// when there are any; replicas may be nil.
+func NewPersonRepository(db *sqlx.DB, replicas *ReplicaSet, logger logging.Logger) PersonRepository {
 	return &personRepository{
-		db:     db,
-		logger: logger,
+		db:       db,
+		replicas: replicas,
+		logger:   logger,
 	}
 }
 
-func (r *personRepository) Create(ctx context.Context, person domain.Person) (int, error) {
+func (r *personRepository) log(ctx context.Context) logging.Logger {
+	return logging.FromContext(ctx, r.logger)
+}
+
+func (r *personRepository) Create(ctx context.Context, person domain.Person) (domain.Person, error) {
 	query := `INSERT INTO people (name, surname, patronymic, age, gender, nationality) 
-	          VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	          VALUES ($1, $2, $3, $4, $5, $6) RETURNING ` + personColumns
 
-	var id int
-	err := r.db.QueryRowContext(ctx, query,
+	var created domain.Person
+	err := sqlx.GetContext(ctx, conn(ctx, r.db), &created, query,
 		person.Name,
 		person.Surname,
 		person.Patronymic,
 		person.Age,
 		person.Gender,
 		person.Nationality,
-	).Scan(&id)
+	)
 
 	if err != nil {
-		r.logger.Error("Failed to create person: %v", err)
-		return 0, err
+		r.log(ctx).Error("Failed to create person: %v", err)
+		return domain.Person{}, err
 	}
 
-	return id, nil
+	return created, nil
 }
 
 func (r *personRepository) GetAll(ctx context.Context, filter domain.PersonFilter, page, limit int) ([]domain.Person, error) {
-	query := `SELECT id, name, surname, patronymic, age, gender, nationality FROM people WHERE 1=1`
+	where, args := filterClause(filter)
+	argPos := len(args) + 1
+
+	query := `SELECT ` + personColumns + ` FROM people WHERE ` + where + orderClause(filter) +
+		` LIMIT $` + strconv.Itoa(argPos) + ` OFFSET $` + strconv.Itoa(argPos+1)
+	args = append(args, limit, (page-1)*limit)
+
+	var people []domain.Person
+	err := sqlx.SelectContext(ctx, conn(ctx, r.replicas.reader(ctx, r.db)), &people, query, args...)
+	if err != nil {
+		r.log(ctx).Error("Failed to get all people: %v", err)
+		return nil, err
+	}
+
+	return people, nil
+}
+
+func (r *personRepository) Export(ctx context.Context, filter domain.PersonFilter, fn func(person domain.Person) error) error {
+	where, args := filterClause(filter)
+	query := `SELECT ` + personColumns + ` FROM people WHERE ` + where + orderClause(filter)
+
+	rows, err := conn(ctx, r.replicas.reader(ctx, r.db)).QueryxContext(ctx, query, args...)
+	if err != nil {
+		r.log(ctx).Error("Failed to export people: %v", err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var person domain.Person
+		if err := rows.StructScan(&person); err != nil {
+			return err
+		}
+		if err := fn(person); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func filterClause(filter domain.PersonFilter) (string, []interface{}) {
+	query := `1=1`
 	args := []interface{}{}
 	argPos := 1
 
+	switch filter.Deleted {
+	case domain.DeletedOnly:
+		query += ` AND deleted_at IS NOT NULL`
+	case domain.DeletedInclude:
+	default:
+		query += ` AND deleted_at IS NULL`
+	}
+
 	if filter.Name != nil {
 		query += ` AND name = $` + strconv.Itoa(argPos)
 		args = append(args, *filter.Name)
@@ -116,36 +292,58 @@ func (r *personRepository) GetAll(ctx context.Context, filter domain.PersonFilte
 		argPos++
 	}
 
-	query += ` LIMIT $` + strconv.Itoa(argPos) + ` OFFSET $` + strconv.Itoa(argPos+1)
-	args = append(args, limit, (page-1)*limit)
+	if filter.CreatedAfter != nil {
+		query += ` AND created_at > $` + strconv.Itoa(argPos)
+		args = append(args, *filter.CreatedAfter)
+		argPos++
+	}
 
-	var people []domain.Person
-	err := r.db.SelectContext(ctx, &people, query, args...)
-	if err != nil {
-		r.logger.Error("Failed to get all people: %v", err)
-		return nil, err
+	if filter.UpdatedSince != nil {
+		query += ` AND updated_at >= $` + strconv.Itoa(argPos)
+		args = append(args, *filter.UpdatedSince)
+		argPos++
 	}
 
-	return people, nil
+	return query, args
+}
+
+func orderClause(filter domain.PersonFilter) string {
+	if filter.Sort != "" && domain.ValidSort(filter.Sort) {
+		field, direction := filter.Sort, ""
+		if strings.HasPrefix(field, "-") {
+			field, direction = field[1:], " DESC"
+		}
+		return ` ORDER BY ` + field + direction + `, id` + direction
+	}
+	if filter.UpdatedSince != nil {
+		return ` ORDER BY updated_at, id`
+	}
+	return ` ORDER BY id`
 }
 
 func (r *personRepository) GetByID(ctx context.Context, id int) (domain.Person, error) {
-	query := `SELECT id, name, surname, patronymic, age, gender, nationality FROM people WHERE id = $1`
+	query := `SELECT ` + personColumns + ` FROM people WHERE id = $1 AND deleted_at IS NULL`
 
 	var person domain.Person
-	err := r.db.GetContext(ctx, &person, query, id)
+	err := sqlx.GetContext(ctx, conn(ctx, r.db), &person, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Person{}, domain.ErrNotFound
+	}
 	if err != nil {
-		r.logger.Error("Failed to get person by ID %d: %v", id, err)
+		r.log(ctx).Error("Failed to get person by ID %d: %v", id, err)
 		return domain.Person{}, err
 	}
 
 	return person, nil
 }
 
-func (r *personRepository) Update(ctx context.Context, id int, person domain.Person) error {
-	query := `UPDATE people SET name = $1, surname = $2, patronymic = $3, age = $4, gender = $5, nationality = $6 WHERE id = $7`
+func (r *personRepository) Update(ctx context.Context, id int, person domain.Person, expectedVersion int) (domain.Person, error) {
+	query := `UPDATE people SET name = $1, surname = $2, patronymic = $3, age = $4, gender = $5, nationality = $6, 
+	          version = version + 1 
+	          WHERE id = $7 AND deleted_at IS NULL AND ($8 = 0 OR version = $8) RETURNING ` + personColumns
 
-	_, err := r.db.ExecContext(ctx, query,
+	var updated domain.Person
+	err := sqlx.GetContext(ctx, conn(ctx, r.db), &updated, query,
 		person.Name,
 		person.Surname,
 		person.Patronymic,
@@ -153,24 +351,184 @@ func (r *personRepository) Update(ctx context.Context, id int, person domain.Per
 		person.Gender,
 		person.Nationality,
 		id,
+		expectedVersion,
 	)
 
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Person{}, r.versionConflict(ctx, id)
+	}
 	if err != nil {
-		r.logger.Error("Failed to update person with ID %d: %v", id, err)
+		r.log(ctx).Error("Failed to update person with ID %d: %v", id, err)
+		return domain.Person{}, err
+	}
+
+	return updated, nil
+}
+
+func (r *personRepository) Delete(ctx context.Context, id int, expectedVersion int) error {
+	query := `UPDATE people SET deleted_at = clock_timestamp(), version = version + 1 
+	          WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR version = $2)`
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, id, expectedVersion)
+	if err != nil {
+		r.log(ctx).Error("Failed to delete person with ID %d: %v", id, err)
 		return err
 	}
 
-	return nil
+	err = checkAffected(res)
+	if errors.Is(err, domain.ErrNotFound) {
+		return r.versionConflict(ctx, id)
+	}
+	return err
+}
+
+// versionConflict tells apart a missing person from a stale version after a
+// conditional write matched no rows.
+func (r *personRepository) versionConflict(ctx context.Context, id int) error {
+	var exists bool
+	err := sqlx.GetContext(ctx, conn(ctx, r.db), &exists,
+		`SELECT EXISTS (SELECT 1 FROM people WHERE id = $1 AND deleted_at IS NULL)`, id)
+	if err != nil {
+		r.log(ctx).Error("Failed to check person with ID %d: %v", id, err)
+		return err
+	}
+	if exists {
+		return domain.ErrVersionMismatch
+	}
+	return domain.ErrNotFound
+}
+
+func (r *personRepository) FindDuplicateCandidates(ctx context.Context, name, surname string, afterID, limit int) ([]domain.Person, error) {
+	query := `SELECT ` + personColumns + ` FROM people 
+	          WHERE deleted_at IS NULL AND lower(left(name, 1)) = lower(left($1, 1)) AND lower(left(surname, 1)) = lower(left($2, 1))
+	          AND id > $3 ORDER BY id LIMIT $4`
+
+	var people []domain.Person
+	err := sqlx.SelectContext(ctx, conn(ctx, r.db), &people, query, name, surname, afterID, limit)
+	if err != nil {
+		r.log(ctx).Error("Failed to find duplicate candidates for %s %s: %v", name, surname, err)
+		return nil, err
+	}
+
+	return people, nil
 }
 
-func (r *personRepository) Delete(ctx context.Context, id int) error {
-	query := `DELETE FROM people WHERE id = $1`
+func (r *personRepository) Merge(ctx context.Context, targetID, sourceID int, merged domain.Person, targetVersion, sourceVersion int) error {
+	return withinTx(ctx, r.db, func(ctx context.Context) error {
+		tx := conn(ctx, r.db)
+
+		var source domain.Person
+		err := sqlx.GetContext(ctx, tx, &source,
+			`SELECT `+personColumns+` FROM people WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR version = $2)
+			 FOR UPDATE`, sourceID, sourceVersion)
+		if errors.Is(err, sql.ErrNoRows) {
+			return r.versionConflict(ctx, sourceID)
+		}
+		if err != nil {
+			r.log(ctx).Error("Failed to lock person with ID %d: %v", sourceID, err)
+			return err
+		}
+
+		snapshot, err := json.Marshal(source)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx,
+			`UPDATE people SET name = $1, surname = $2, patronymic = $3, age = $4, gender = $5, nationality = $6, 
+			 version = version + 1 WHERE id = $7 AND deleted_at IS NULL AND ($8 = 0 OR version = $8)`,
+			merged.Name,
+			merged.Surname,
+			merged.Patronymic,
+			merged.Age,
+			merged.Gender,
+			merged.Nationality,
+			targetID,
+			targetVersion,
+		)
+		if err != nil {
+			r.log(ctx).Error("Failed to update merge target %d: %v", targetID, err)
+			return err
+		}
+		if err := checkAffected(res); errors.Is(err, domain.ErrNotFound) {
+			return r.versionConflict(ctx, targetID)
+		} else if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO person_merges (source_id, target_id, snapshot) VALUES ($1, $2, $3)`,
+			sourceID, targetID, snapshot,
+		); err != nil {
+			r.log(ctx).Error("Failed to record merge of %d into %d: %v", sourceID, targetID, err)
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE person_merges SET target_id = $1 WHERE target_id = $2`,
+			targetID, sourceID,
+		); err != nil {
+			r.log(ctx).Error("Failed to redirect merges of %d to %d: %v", sourceID, targetID, err)
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM people WHERE id = $1`, sourceID); err != nil {
+			r.log(ctx).Error("Failed to delete merged person %d: %v", sourceID, err)
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (r *personRepository) GetMergeTarget(ctx context.Context, id int) (int, error) {
+	query := `SELECT target_id FROM person_merges WHERE source_id = $1`
 
-	_, err := r.db.ExecContext(ctx, query, id)
+	var targetID int
+	err := sqlx.GetContext(ctx, conn(ctx, r.db), &targetID, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, domain.ErrNotFound
+	}
 	if err != nil {
-		r.logger.Error("Failed to delete person with ID %d: %v", id, err)
+		r.log(ctx).Error("Failed to get merge target for ID %d: %v", id, err)
+		return 0, err
+	}
+
+	return targetID, nil
+}
+
+func (r *personRepository) Restore(ctx context.Context, id int) error {
+	query := `UPDATE people SET deleted_at = NULL, version = version + 1 WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, id)
+	if err != nil {
+		r.log(ctx).Error("Failed to restore person with ID %d: %v", id, err)
 		return err
 	}
 
+	return checkAffected(res)
+}
+
+func (r *personRepository) Purge(ctx context.Context, deletedBefore time.Time) ([]domain.Person, error) {
+	query := `DELETE FROM people WHERE deleted_at IS NOT NULL AND deleted_at < $1 RETURNING ` + personColumns
+
+	var people []domain.Person
+	err := sqlx.SelectContext(ctx, conn(ctx, r.db), &people, query, deletedBefore.UTC())
+	if err != nil {
+		r.log(ctx).Error("Failed to purge deleted people: %v", err)
+		return nil, err
+	}
+
+	return people, nil
+}
+
+func checkAffected(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
 	return nil
 }
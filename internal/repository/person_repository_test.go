@@ -0,0 +1,29 @@
+package repository_test
+
+import (
+	"context"
+	"github.com/RakhimovAns/Person-Service/internal/repository"
+	"github.com/RakhimovAns/Person-Service/internal/repository/repotest"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"testing"
+)
+
+func TestPersonRepository(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.New("error", "text")
+	db := openTestDB(t, logger)
+
+	err := repotest.TestPersonRepository(ctx, func() (repotest.Store, error) {
+		if _, err := db.ExecContext(ctx, `TRUNCATE people RESTART IDENTITY CASCADE`); err != nil {
+			return repotest.Store{}, err
+		}
+
+		return repotest.Store{
+			People:     repository.NewPersonRepository(db, nil, logger),
+			Transactor: repository.NewTransactor(db),
+		}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
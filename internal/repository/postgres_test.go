@@ -0,0 +1,41 @@
+package repository_test
+
+import (
+	"context"
+	"github.com/RakhimovAns/Person-Service/internal/repository"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/jmoiron/sqlx"
+	"os"
+	"testing"
+)
+
+// testDSNEnv names the variable holding the DSN of a Postgres database the
+// tests may migrate and wipe.
+const testDSNEnv = "TEST_DATABASE_DSN"
+
+// openTestDB connects to the test database and migrates it, or skips the
+// test when there is none.
+func openTestDB(t *testing.T, logger logging.Logger) *sqlx.DB {
+	t.Helper()
+
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+
+	db, err := sqlx.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := repository.NewMigrator(db, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"github.com/RakhimovAns/Person-Service/internal/config"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/jmoiron/sqlx"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// replicaCheckTimeout bounds a replica lag check, which runs in the path of
+// the read that triggered it.
+const replicaCheckTimeout = time.Second
+
+// replicaLagQuery returns how far a replica is behind its primary, in
+// seconds. A replica that has replayed everything it received is not behind,
+// however old the last transaction is.
+const replicaLagQuery = `SELECT CASE
+	WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+END`
+
+// ReplicaSet routes reads that tolerate lag to read replicas in turn. A
+// replica is skipped while it lags more than the configured maximum or
+// cannot be reached; with none left, reads go to the primary.
+type ReplicaSet struct {
+	replicas      []*replica
+	maxLag        time.Duration
+	checkInterval time.Duration
+	next          atomic.Uint64
+	logger        logging.Logger
+}
+
+type replica struct {
+	index int
+	db    *sqlx.DB
+
+	mu        sync.Mutex
+	checking  bool
+	checkedAt time.Time
+	usable    bool
+}
+
+// NewPostgresReplicas opens a pool for every replica in cfg.ReplicaDSNs, with
+// the pool settings of the primary. Unlike the primary, replicas need not be
+// up at startup: they are used once a lag check succeeds. It returns nil when
+// there are none.
+func NewPostgresReplicas(cfg config.DBConfig, logger logging.Logger) (*ReplicaSet, error) {
+	if len(cfg.ReplicaDSNs) == 0 {
+		return nil, nil
+	}
+
+	set := &ReplicaSet{
+		maxLag:        cfg.MaxReplicaLag,
+		checkInterval: cfg.ReplicaCheckInterval,
+		logger:        logger,
+	}
+	for i, dsn := range cfg.ReplicaDSNs {
+		replicaCfg := cfg
+		replicaCfg.DSN = dsn
+		db, err := openPool(replicaCfg)
+		if err != nil {
+			set.Close()
+			return nil, err
+		}
+		set.replicas = append(set.replicas, &replica{index: i + 1, db: db})
+	}
+
+	return set, nil
+}
+
+// DBs returns the replica pools.
+func (s *ReplicaSet) DBs() []*sqlx.DB {
+	if s == nil {
+		return nil
+	}
+
+	dbs := make([]*sqlx.DB, len(s.replicas))
+	for i, replica := range s.replicas {
+		dbs[i] = replica.db
+	}
+	return dbs
+}
+
+func (s *ReplicaSet) Close() error {
+	if s == nil {
+		return nil
+	}
+
+	var firstErr error
+	for _, replica := range s.replicas {
+		if err := replica.db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// reader returns the pool to read from: the next usable replica, or primary
+// when there is none or ctx is bound to a transaction, which must see its
+// own writes.
+func (s *ReplicaSet) reader(ctx context.Context, primary *sqlx.DB) *sqlx.DB {
+	if s == nil || len(s.replicas) == 0 {
+		return primary
+	}
+	if _, ok := ctx.Value(txKey{db: primary}).(*txState); ok {
+		return primary
+	}
+
+	start := s.next.Add(1)
+	for i := range s.replicas {
+		replica := s.replicas[(int(start)+i)%len(s.replicas)]
+		if s.usable(ctx, replica) {
+			return replica.db
+		}
+	}
+	return primary
+}
+
+// usable reports whether replica may serve reads. Its lag is checked at most
+// once per check interval; reads arriving while a check is running use the
+// previous result.
+func (s *ReplicaSet) usable(ctx context.Context, replica *replica) bool {
+	replica.mu.Lock()
+	usable := replica.usable
+	first := replica.checkedAt.IsZero()
+	check := !replica.checking && time.Since(replica.checkedAt) >= s.checkInterval
+	if check {
+		replica.checking = true
+	}
+	replica.mu.Unlock()
+
+	if !check {
+		return usable
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), replicaCheckTimeout)
+	defer cancel()
+
+	var lagSeconds float64
+	err := replica.db.QueryRowxContext(ctx, replicaLagQuery).Scan(&lagSeconds)
+	lag := time.Duration(lagSeconds * float64(time.Second))
+	nowUsable := err == nil && lag <= s.maxLag
+
+	switch {
+	case err != nil && (usable || first):
+		s.logger.Warn("Read replica %d is unavailable, skipping it: %v", replica.index, err)
+	case err == nil && !nowUsable && (usable || first):
+		s.logger.Warn("Read replica %d is %s behind, skipping it", replica.index, lag.Round(time.Millisecond))
+	case nowUsable && !usable:
+		s.logger.Info("Read replica %d is %s behind, reading from it", replica.index, lag.Round(time.Millisecond))
+	}
+
+	replica.mu.Lock()
+	replica.checking = false
+	replica.checkedAt = time.Now()
+	replica.usable = nowUsable
+	replica.mu.Unlock()
+
+	return nowUsable
+}
@@ -0,0 +1,776 @@
+// Package repotest checks that an implementation of
+// repository.PersonRepository behaves like the others, so that storage
+// backends can be swapped without the service noticing. Call
+// TestPersonRepository from a test of the implementation:
+//
+//	if err := repotest.TestPersonRepository(ctx, newStore); err != nil {
+//		t.Fatal(err)
+//	}
+package repotest
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/internal/repository"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Store is a repository under test and the transactor that goes with it.
+type Store struct {
+	People     repository.PersonRepository
+	Transactor repository.Transactor
+}
+
+// NewStore returns a store with no people in it.
+type NewStore func() (Store, error)
+
+type check struct {
+	name string
+	run  func(ctx context.Context, store Store) error
+}
+
+var checks = []check{
+	{"create", checkCreate},
+	{"not found", checkNotFound},
+	{"filter", checkFilter},
+	{"pagination", checkPagination},
+	{"sort", checkSort},
+	{"updated since", checkUpdatedSince},
+	{"export", checkExport},
+	{"versions", checkVersions},
+	{"soft delete", checkSoftDelete},
+	{"duplicate candidates", checkDuplicateCandidates},
+	{"merge", checkMerge},
+	{"purge", checkPurge},
+	{"transactions", checkTransactions},
+}
+
+// TestPersonRepository runs every check against a store of its own from
+// newStore. It returns an error listing the checks that failed, or nil.
+func TestPersonRepository(ctx context.Context, newStore NewStore) error {
+	var errs []error
+	for _, check := range checks {
+		store, err := newStore()
+		if err != nil {
+			return fmt.Errorf("failed to create store: %w", err)
+		}
+		if err := check.run(ctx, store); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", check.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func checkCreate(ctx context.Context, store Store) error {
+	input := person("Ivan", "Petrov", 30)
+	patronymic := "Sergeevich"
+	input.Patronymic = &patronymic
+
+	created, err := store.People.Create(ctx, input)
+	if err != nil {
+		return err
+	}
+	patronymic = "changed"
+
+	switch {
+	case created.ID <= 0:
+		return fmt.Errorf("got ID %d, want a positive one", created.ID)
+	case created.Version != 1:
+		return fmt.Errorf("got version %d, want 1", created.Version)
+	case created.CreatedAt.IsZero() || !created.UpdatedAt.Equal(created.CreatedAt):
+		return fmt.Errorf("got created_at %v and updated_at %v, want them set and equal", created.CreatedAt, created.UpdatedAt)
+	case created.DeletedAt != nil:
+		return fmt.Errorf("got deleted_at %v, want none", created.DeletedAt)
+	}
+
+	got, err := store.People.GetByID(ctx, created.ID)
+	if err != nil {
+		return err
+	}
+	if err := samePerson(got, created); err != nil {
+		return err
+	}
+	if got.Patronymic == nil || *got.Patronymic != "Sergeevich" {
+		return fmt.Errorf("got patronymic %v, want Sergeevich", got.Patronymic)
+	}
+
+	second, err := store.People.Create(ctx, person("Anna", "Petrova", 25))
+	if err != nil {
+		return err
+	}
+	if second.ID <= created.ID {
+		return fmt.Errorf("got ID %d after %d, want IDs to increase", second.ID, created.ID)
+	}
+	if second.Patronymic != nil {
+		return fmt.Errorf("got patronymic %q, want none", *second.Patronymic)
+	}
+	return nil
+}
+
+func checkNotFound(ctx context.Context, store Store) error {
+	const missing = 1_000_000
+
+	if _, err := store.People.GetByID(ctx, missing); !errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("GetByID: got %v, want %v", err, domain.ErrNotFound)
+	}
+	if _, err := store.People.Update(ctx, missing, person("Ivan", "Petrov", 30), 0); !errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("Update: got %v, want %v", err, domain.ErrNotFound)
+	}
+	if err := store.People.Delete(ctx, missing, 0); !errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("Delete: got %v, want %v", err, domain.ErrNotFound)
+	}
+	if err := store.People.Restore(ctx, missing); !errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("Restore: got %v, want %v", err, domain.ErrNotFound)
+	}
+	if _, err := store.People.GetMergeTarget(ctx, missing); !errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("GetMergeTarget: got %v, want %v", err, domain.ErrNotFound)
+	}
+
+	existing, err := store.People.Create(ctx, person("Ivan", "Petrov", 30))
+	if err != nil {
+		return err
+	}
+	if err := store.People.Merge(ctx, existing.ID, missing, existing, 0, 0); !errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("Merge from a missing person: got %v, want %v", err, domain.ErrNotFound)
+	}
+	if err := store.People.Merge(ctx, missing, existing.ID, existing, 0, 0); !errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("Merge into a missing person: got %v, want %v", err, domain.ErrNotFound)
+	}
+	if _, err := store.People.GetByID(ctx, existing.ID); err != nil {
+		return fmt.Errorf("failed merge removed the source: %w", err)
+	}
+
+	people, err := store.People.GetAll(ctx, domain.PersonFilter{Name: ptr("Nobody")}, 1, 10)
+	if err != nil {
+		return err
+	}
+	if len(people) != 0 {
+		return fmt.Errorf("got %d people for a filter matching none, want 0", len(people))
+	}
+	return nil
+}
+
+func checkFilter(ctx context.Context, store Store) error {
+	ivan := person("Ivan", "Petrov", 30)
+	ivan.Patronymic = ptr("Sergeevich")
+	anna := person("Anna", "Petrova", 25)
+	anna.Gender = "female"
+	anna.Nationality = "KZ"
+	oleg := person("Oleg", "Ivanov", 30)
+
+	ids, err := createAll(ctx, store, ivan, anna, oleg)
+	if err != nil {
+		return err
+	}
+
+	all, err := store.People.GetAll(ctx, domain.PersonFilter{}, 1, 10)
+	if err != nil {
+		return err
+	}
+
+	filters := []struct {
+		name   string
+		filter domain.PersonFilter
+		want   []int
+	}{
+		{"none", domain.PersonFilter{}, ids},
+		{"name", domain.PersonFilter{Name: ptr("Ivan")}, ids[:1]},
+		{"surname", domain.PersonFilter{Surname: ptr("Ivanov")}, ids[2:]},
+		{"patronymic", domain.PersonFilter{Patronymic: ptr("Sergeevich")}, ids[:1]},
+		{"age", domain.PersonFilter{Age: ptr(30)}, []int{ids[0], ids[2]}},
+		{"gender", domain.PersonFilter{Gender: ptr("female")}, ids[1:2]},
+		{"nationality", domain.PersonFilter{Nationality: ptr("RU")}, []int{ids[0], ids[2]}},
+		{"age and surname", domain.PersonFilter{Age: ptr(30), Surname: ptr("Petrov")}, ids[:1]},
+		{"no match", domain.PersonFilter{Name: ptr("ivan")}, nil},
+		{"created after", domain.PersonFilter{CreatedAfter: &all[0].CreatedAt}, idsCreatedAfter(all, all[0].CreatedAt)},
+	}
+	for _, f := range filters {
+		people, err := store.People.GetAll(ctx, f.filter, 1, 10)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.name, err)
+		}
+		if err := sameIDs(people, f.want); err != nil {
+			return fmt.Errorf("%s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+func checkPagination(ctx context.Context, store Store) error {
+	ids, err := createAll(ctx, store,
+		person("A", "One", 1),
+		person("B", "Two", 2),
+		person("C", "Three", 3),
+		person("D", "Four", 4),
+		person("E", "Five", 5),
+	)
+	if err != nil {
+		return err
+	}
+
+	pages := []struct {
+		page, limit int
+		want        []int
+	}{
+		{1, 2, ids[0:2]},
+		{2, 2, ids[2:4]},
+		{3, 2, ids[4:]},
+		{4, 2, nil},
+		{1, 10, ids},
+		{2, 5, nil},
+	}
+	for _, p := range pages {
+		people, err := store.People.GetAll(ctx, domain.PersonFilter{}, p.page, p.limit)
+		if err != nil {
+			return fmt.Errorf("page %d of %d: %w", p.page, p.limit, err)
+		}
+		if err := sameIDs(people, p.want); err != nil {
+			return fmt.Errorf("page %d of %d: %w", p.page, p.limit, err)
+		}
+	}
+	return nil
+}
+
+func checkSort(ctx context.Context, store Store) error {
+	ids, err := createAll(ctx, store,
+		person("carl", "smith", 40),
+		person("anna", "jones", 30),
+		person("bert", "brown", 40),
+	)
+	if err != nil {
+		return err
+	}
+
+	sorts := []struct {
+		sort string
+		want []int
+	}{
+		{"", ids},
+		{"id", ids},
+		{"-id", []int{ids[2], ids[1], ids[0]}},
+		{"name", []int{ids[1], ids[2], ids[0]}},
+		{"-surname", []int{ids[0], ids[1], ids[2]}},
+		// Ties are broken by ID, in the same direction.
+		{"age", []int{ids[1], ids[0], ids[2]}},
+		{"-age", []int{ids[2], ids[0], ids[1]}},
+		{"created_at", ids},
+	}
+	for _, s := range sorts {
+		people, err := store.People.GetAll(ctx, domain.PersonFilter{Sort: s.sort}, 1, 10)
+		if err != nil {
+			return fmt.Errorf("sort %q: %w", s.sort, err)
+		}
+		if err := sameIDs(people, s.want); err != nil {
+			return fmt.Errorf("sort %q: %w", s.sort, err)
+		}
+	}
+
+	// The second page continues the order of the first.
+	people, err := store.People.GetAll(ctx, domain.PersonFilter{Sort: "-age"}, 2, 2)
+	if err != nil {
+		return err
+	}
+	return sameIDs(people, []int{ids[1]})
+}
+
+func checkUpdatedSince(ctx context.Context, store Store) error {
+	ids, err := createAll(ctx, store,
+		person("Ivan", "Petrov", 30),
+		person("Anna", "Petrova", 25),
+		person("Oleg", "Ivanov", 35),
+	)
+	if err != nil {
+		return err
+	}
+
+	first, err := store.People.GetByID(ctx, ids[0])
+	if err != nil {
+		return err
+	}
+	time.Sleep(10 * time.Millisecond)
+	updated, err := store.People.Update(ctx, ids[0], first, first.Version)
+	if err != nil {
+		return err
+	}
+	if !updated.UpdatedAt.After(first.UpdatedAt) {
+		return fmt.Errorf("got updated_at %v after an update, want it later than %v", updated.UpdatedAt, first.UpdatedAt)
+	}
+
+	// Changes come in the order they were made.
+	people, err := store.People.GetAll(ctx, domain.PersonFilter{UpdatedSince: &first.UpdatedAt}, 1, 10)
+	if err != nil {
+		return err
+	}
+	if err := sameIDs(people, []int{ids[1], ids[2], ids[0]}); err != nil {
+		return err
+	}
+
+	people, err = store.People.GetAll(ctx, domain.PersonFilter{UpdatedSince: &updated.UpdatedAt}, 1, 10)
+	if err != nil {
+		return err
+	}
+	return sameIDs(people, ids[:1])
+}
+
+func checkExport(ctx context.Context, store Store) error {
+	ids, err := createAll(ctx, store,
+		person("Ivan", "Petrov", 30),
+		person("Anna", "Petrova", 25),
+		person("Oleg", "Ivanov", 30),
+	)
+	if err != nil {
+		return err
+	}
+
+	var exported []domain.Person
+	err = store.People.Export(ctx, domain.PersonFilter{Age: ptr(30), Sort: "-id"}, func(person domain.Person) error {
+		exported = append(exported, person)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := sameIDs(exported, []int{ids[2], ids[0]}); err != nil {
+		return err
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = store.People.Export(ctx, domain.PersonFilter{}, func(person domain.Person) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		return fmt.Errorf("got %v after %d calls when fn failed, want its error after 1", err, calls)
+	}
+	return nil
+}
+
+func checkVersions(ctx context.Context, store Store) error {
+	created, err := store.People.Create(ctx, person("Ivan", "Petrov", 30))
+	if err != nil {
+		return err
+	}
+
+	change := created
+	change.Age = 31
+	change.Patronymic = ptr("Sergeevich")
+	updated, err := store.People.Update(ctx, created.ID, change, created.Version)
+	if err != nil {
+		return err
+	}
+	switch {
+	case updated.Version != created.Version+1:
+		return fmt.Errorf("got version %d after an update, want %d", updated.Version, created.Version+1)
+	case updated.Age != 31 || updated.Patronymic == nil || *updated.Patronymic != "Sergeevich":
+		return fmt.Errorf("got %+v, want the update applied", updated)
+	case !updated.CreatedAt.Equal(created.CreatedAt):
+		return fmt.Errorf("got created_at %v after an update, want %v", updated.CreatedAt, created.CreatedAt)
+	}
+
+	if _, err := store.People.Update(ctx, created.ID, change, created.Version); !errors.Is(err, domain.ErrVersionMismatch) {
+		return fmt.Errorf("Update with a stale version: got %v, want %v", err, domain.ErrVersionMismatch)
+	}
+	if err := store.People.Delete(ctx, created.ID, created.Version); !errors.Is(err, domain.ErrVersionMismatch) {
+		return fmt.Errorf("Delete with a stale version: got %v, want %v", err, domain.ErrVersionMismatch)
+	}
+
+	// Zero skips the check.
+	change.Patronymic = nil
+	updated, err = store.People.Update(ctx, created.ID, change, 0)
+	if err != nil {
+		return err
+	}
+	if updated.Version != created.Version+2 || updated.Patronymic != nil {
+		return fmt.Errorf("got %+v, want version %d and no patronymic", updated, created.Version+2)
+	}
+
+	if err := store.People.Delete(ctx, created.ID, updated.Version); err != nil {
+		return err
+	}
+	if _, err := store.People.Update(ctx, created.ID, change, 0); !errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("Update of a deleted person: got %v, want %v", err, domain.ErrNotFound)
+	}
+	return nil
+}
+
+func checkSoftDelete(ctx context.Context, store Store) error {
+	ids, err := createAll(ctx, store,
+		person("Ivan", "Petrov", 30),
+		person("Anna", "Petrova", 25),
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := store.People.Delete(ctx, ids[0], 0); err != nil {
+		return err
+	}
+	if err := store.People.Delete(ctx, ids[0], 0); !errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("second Delete: got %v, want %v", err, domain.ErrNotFound)
+	}
+	if _, err := store.People.GetByID(ctx, ids[0]); !errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("GetByID of a deleted person: got %v, want %v", err, domain.ErrNotFound)
+	}
+	if err := store.People.Restore(ctx, ids[1]); !errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("Restore of a live person: got %v, want %v", err, domain.ErrNotFound)
+	}
+
+	modes := []struct {
+		deleted string
+		want    []int
+	}{
+		{"", ids[1:]},
+		{domain.DeletedExclude, ids[1:]},
+		{domain.DeletedOnly, ids[:1]},
+		{domain.DeletedInclude, ids},
+	}
+	for _, m := range modes {
+		people, err := store.People.GetAll(ctx, domain.PersonFilter{Deleted: m.deleted}, 1, 10)
+		if err != nil {
+			return fmt.Errorf("deleted %q: %w", m.deleted, err)
+		}
+		if err := sameIDs(people, m.want); err != nil {
+			return fmt.Errorf("deleted %q: %w", m.deleted, err)
+		}
+	}
+
+	deleted, err := store.People.GetAll(ctx, domain.PersonFilter{Deleted: domain.DeletedOnly}, 1, 10)
+	if err != nil {
+		return err
+	}
+	if deleted[0].DeletedAt == nil || deleted[0].Version != 2 {
+		return fmt.Errorf("got deleted_at %v and version %d, want them set by Delete", deleted[0].DeletedAt, deleted[0].Version)
+	}
+
+	if err := store.People.Restore(ctx, ids[0]); err != nil {
+		return err
+	}
+	restored, err := store.People.GetByID(ctx, ids[0])
+	if err != nil {
+		return err
+	}
+	if restored.DeletedAt != nil || restored.Version != 3 {
+		return fmt.Errorf("got deleted_at %v and version %d after Restore, want none and 3", restored.DeletedAt, restored.Version)
+	}
+	return nil
+}
+
+func checkDuplicateCandidates(ctx context.Context, store Store) error {
+	ids, err := createAll(ctx, store,
+		person("Ivan", "Petrov", 30),
+		person("igor", "pavlov", 40),
+		person("Ivan", "Sidorov", 30),
+		person("Иван", "Петров", 30),
+		person("Ilya", "Popov", 20),
+	)
+	if err != nil {
+		return err
+	}
+	if err := store.People.Delete(ctx, ids[4], 0); err != nil {
+		return err
+	}
+
+	people, err := store.People.FindDuplicateCandidates(ctx, "ivan", "PETROV", 0, 10)
+	if err != nil {
+		return err
+	}
+	if err := sameIDs(people, ids[:2]); err != nil {
+		return err
+	}
+
+	// Candidates are paged through by ID.
+	people, err = store.People.FindDuplicateCandidates(ctx, "ivan", "PETROV", 0, 1)
+	if err != nil {
+		return err
+	}
+	if err := sameIDs(people, ids[:1]); err != nil {
+		return fmt.Errorf("first page: %w", err)
+	}
+	people, err = store.People.FindDuplicateCandidates(ctx, "ivan", "PETROV", ids[0], 1)
+	if err != nil {
+		return err
+	}
+	if err := sameIDs(people, ids[1:2]); err != nil {
+		return fmt.Errorf("second page: %w", err)
+	}
+
+	people, err = store.People.FindDuplicateCandidates(ctx, "иван", "петров", 0, 10)
+	if err != nil {
+		return err
+	}
+	return sameIDs(people, ids[3:4])
+}
+
+func checkMerge(ctx context.Context, store Store) error {
+	ids, err := createAll(ctx, store,
+		person("Ivan", "Petrov", 30),
+		person("Ivan", "Petrov", 0),
+		person("Ivan", "Petrov", 31),
+	)
+	if err != nil {
+		return err
+	}
+	target, err := store.People.GetByID(ctx, ids[0])
+	if err != nil {
+		return err
+	}
+
+	merged := target
+	merged.Patronymic = ptr("Sergeevich")
+	if err := store.People.Merge(ctx, ids[0], ids[1], merged, target.Version+1, 0); !errors.Is(err, domain.ErrVersionMismatch) {
+		return fmt.Errorf("Merge into a stale target: got %v, want %v", err, domain.ErrVersionMismatch)
+	}
+	if err := store.People.Merge(ctx, ids[0], ids[1], merged, 0, target.Version+1); !errors.Is(err, domain.ErrVersionMismatch) {
+		return fmt.Errorf("Merge from a stale source: got %v, want %v", err, domain.ErrVersionMismatch)
+	}
+	if err := store.People.Merge(ctx, ids[0], ids[1], merged, target.Version, 1); err != nil {
+		return err
+	}
+
+	if _, err := store.People.GetByID(ctx, ids[1]); !errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("GetByID of the merged person: got %v, want %v", err, domain.ErrNotFound)
+	}
+	people, err := store.People.GetAll(ctx, domain.PersonFilter{Deleted: domain.DeletedInclude}, 1, 10)
+	if err != nil {
+		return err
+	}
+	if err := sameIDs(people, []int{ids[0], ids[2]}); err != nil {
+		return fmt.Errorf("merged person is still listed: %w", err)
+	}
+
+	result, err := store.People.GetByID(ctx, ids[0])
+	if err != nil {
+		return err
+	}
+	if result.Patronymic == nil || *result.Patronymic != "Sergeevich" || result.Version != target.Version+1 {
+		return fmt.Errorf("got %+v, want the merged fields and version %d", result, target.Version+1)
+	}
+
+	if err := expectMergeTarget(ctx, store, ids[1], ids[0]); err != nil {
+		return err
+	}
+
+	// Merging the target again carries the earlier merge along.
+	if err := store.People.Merge(ctx, ids[2], ids[0], result, 0, 0); err != nil {
+		return err
+	}
+	if err := expectMergeTarget(ctx, store, ids[0], ids[2]); err != nil {
+		return err
+	}
+	return expectMergeTarget(ctx, store, ids[1], ids[2])
+}
+
+func checkPurge(ctx context.Context, store Store) error {
+	ids, err := createAll(ctx, store,
+		person("Ivan", "Petrov", 30),
+		person("Anna", "Petrova", 25),
+		person("Oleg", "Ivanov", 35),
+		person("Ivan", "Petrov", 0),
+	)
+	if err != nil {
+		return err
+	}
+	target, err := store.People.GetByID(ctx, ids[0])
+	if err != nil {
+		return err
+	}
+	if err := store.People.Merge(ctx, ids[0], ids[3], target, 0, 0); err != nil {
+		return err
+	}
+
+	for _, id := range ids[:2] {
+		if err := store.People.Delete(ctx, id, 0); err != nil {
+			return err
+		}
+	}
+
+	deleted, err := store.People.GetAll(ctx, domain.PersonFilter{Deleted: domain.DeletedOnly}, 1, 10)
+	if err != nil {
+		return err
+	}
+	purged, err := store.People.Purge(ctx, *deleted[0].DeletedAt)
+	if err != nil {
+		return err
+	}
+	if len(purged) != 0 {
+		return fmt.Errorf("purged %d people deleted at the cutoff, want 0", len(purged))
+	}
+
+	purged, err = store.People.Purge(ctx, deleted[1].DeletedAt.Add(time.Second))
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(purged, func(a, b domain.Person) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	if err := sameIDs(purged, ids[:2]); err != nil {
+		return fmt.Errorf("Purge: %w", err)
+	}
+	if purged[0].DeletedAt == nil {
+		return fmt.Errorf("Purge returned person %d without deleted_at", purged[0].ID)
+	}
+
+	people, err := store.People.GetAll(ctx, domain.PersonFilter{Deleted: domain.DeletedInclude}, 1, 10)
+	if err != nil {
+		return err
+	}
+	if err := sameIDs(people, ids[2:3]); err != nil {
+		return err
+	}
+	if err := store.People.Restore(ctx, ids[0]); !errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("Restore of a purged person: got %v, want %v", err, domain.ErrNotFound)
+	}
+	// Merges into a purged person go with it.
+	if _, err := store.People.GetMergeTarget(ctx, ids[3]); !errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("GetMergeTarget into a purged person: got %v, want %v", err, domain.ErrNotFound)
+	}
+	return nil
+}
+
+func checkTransactions(ctx context.Context, store Store) error {
+	failed := errors.New("failed")
+	committed := false
+
+	err := store.Transactor.WithinTx(ctx, func(ctx context.Context) error {
+		created, err := store.People.Create(ctx, person("Ivan", "Petrov", 30))
+		if err != nil {
+			return err
+		}
+		if _, err := store.People.GetByID(ctx, created.ID); err != nil {
+			return fmt.Errorf("person created in the transaction is not visible in it: %w", err)
+		}
+		store.Transactor.AfterCommit(ctx, func() { committed = true })
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		return fmt.Errorf("got %v from a failed transaction, want its error", err)
+	}
+	if committed {
+		return fmt.Errorf("AfterCommit ran for a rolled back transaction")
+	}
+	people, err := store.People.GetAll(ctx, domain.PersonFilter{Deleted: domain.DeletedInclude}, 1, 10)
+	if err != nil {
+		return err
+	}
+	if len(people) != 0 {
+		return fmt.Errorf("got %d people after a rollback, want 0", len(people))
+	}
+
+	var created domain.Person
+	err = store.Transactor.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		created, err = store.People.Create(ctx, person("Anna", "Petrova", 25))
+		if err != nil {
+			return err
+		}
+		store.Transactor.AfterCommit(ctx, func() { committed = true })
+		return store.People.Delete(ctx, created.ID, created.Version)
+	})
+	if err != nil {
+		return err
+	}
+	if !committed {
+		return fmt.Errorf("AfterCommit did not run for a committed transaction")
+	}
+	people, err = store.People.GetAll(ctx, domain.PersonFilter{Deleted: domain.DeletedOnly}, 1, 10)
+	if err != nil {
+		return err
+	}
+	return sameIDs(people, []int{created.ID})
+}
+
+func person(name, surname string, age int) domain.Person {
+	return domain.Person{
+		Name:        name,
+		Surname:     surname,
+		Age:         age,
+		Gender:      "male",
+		Nationality: "RU",
+	}
+}
+
+// createAll creates people one after another, a little apart so that their
+// timestamps differ, and returns their IDs.
+func createAll(ctx context.Context, store Store, people ...domain.Person) ([]int, error) {
+	ids := make([]int, 0, len(people))
+	for _, p := range people {
+		created, err := store.People.Create(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, created.ID)
+		time.Sleep(2 * time.Millisecond)
+	}
+	return ids, nil
+}
+
+func idsCreatedAfter(people []domain.Person, after time.Time) []int {
+	var ids []int
+	for _, p := range people {
+		if p.CreatedAt.After(after) {
+			ids = append(ids, p.ID)
+		}
+	}
+	return ids
+}
+
+func expectMergeTarget(ctx context.Context, store Store, sourceID, want int) error {
+	got, err := store.People.GetMergeTarget(ctx, sourceID)
+	if err != nil {
+		return fmt.Errorf("GetMergeTarget(%d): %w", sourceID, err)
+	}
+	if got != want {
+		return fmt.Errorf("GetMergeTarget(%d): got %d, want %d", sourceID, got, want)
+	}
+	return nil
+}
+
+func samePerson(got, want domain.Person) error {
+	same := got.ID == want.ID &&
+		got.Name == want.Name &&
+		got.Surname == want.Surname &&
+		equalPtr(got.Patronymic, want.Patronymic) &&
+		got.Age == want.Age &&
+		got.Gender == want.Gender &&
+		got.Nationality == want.Nationality &&
+		got.Version == want.Version &&
+		got.CreatedAt.Equal(want.CreatedAt) &&
+		got.UpdatedAt.Equal(want.UpdatedAt) &&
+		(got.DeletedAt == nil) == (want.DeletedAt == nil)
+	if !same {
+		return fmt.Errorf("got %+v, want %+v", got, want)
+	}
+	return nil
+}
+
+func sameIDs(people []domain.Person, want []int) error {
+	got := make([]string, len(people))
+	for i, p := range people {
+		got[i] = fmt.Sprint(p.ID)
+	}
+	wanted := make([]string, len(want))
+	for i, id := range want {
+		wanted[i] = fmt.Sprint(id)
+	}
+	if strings.Join(got, ",") != strings.Join(wanted, ",") {
+		return fmt.Errorf("got people [%s], want [%s]", strings.Join(got, ","), strings.Join(wanted, ","))
+	}
+	return nil
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
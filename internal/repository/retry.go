@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"github.com/lib/pq"
+	"io"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+)
+
+const (
+	// retryAttempts is how many times a transaction, or a read outside of
+	// one, is tried when it fails on a transient error.
+	retryAttempts = 3
+	// retryBackoff is the delay before the first retry. It doubles with
+	// every retry and is jittered so that conflicting transactions do not
+	// collide again.
+	retryBackoff = 50 * time.Millisecond
+)
+
+// conflict reports whether err is a serialization failure or a deadlock.
+// Postgres rolled the transaction back, so running it again is safe.
+func conflict(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == "40001" || pqErr.Code == "40P01"
+}
+
+// connectionLost reports whether err means the connection broke or the
+// server went away. A statement sent before that may or may not have been
+// applied.
+func connectionLost(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "57P01", "57P02", "57P03":
+			// admin_shutdown, crash_shutdown, cannot_connect_now
+			return true
+		}
+		return pqErr.Code.Class() == "08"
+	}
+	return false
+}
+
+// retry runs fn until it succeeds, fails on an error retryable does not
+// accept, runs out of attempts or ctx is done.
+func retry(ctx context.Context, retryable func(err error) bool, fn func() error) error {
+	delay := retryBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= retryAttempts || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+
+		timer := time.NewTimer(delay/2 + time.Duration(rand.Int63n(int64(delay))))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
@@ -0,0 +1,389 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/jmoiron/sqlx"
+	"modernc.org/sqlite"
+	"strconv"
+	"time"
+)
+
+const sqliteDriver = "sqlite"
+
+// sqliteBusyTimeout is how long a write waits for another one to finish
+// before failing. SQLite takes one writer at a time.
+const sqliteBusyTimeout = 5 * time.Second
+
+// sqliteSchema holds people and person_merges as the Postgres migrations
+// leave them. Timestamps are written by the repository, in UTC and in a
+// format that sorts as text, rather than by CURRENT_TIMESTAMP and triggers.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS people (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    surname TEXT NOT NULL,
+    patronymic TEXT,
+    age INTEGER NOT NULL,
+    gender TEXT NOT NULL,
+    nationality TEXT NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_people_deleted_at ON people (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_people_created_at ON people (created_at);
+CREATE INDEX IF NOT EXISTS idx_people_updated_at ON people (updated_at, id);
+
+CREATE TABLE IF NOT EXISTS person_merges (
+    source_id INTEGER PRIMARY KEY,
+    target_id INTEGER NOT NULL REFERENCES people (id) ON DELETE CASCADE,
+    snapshot TEXT NOT NULL,
+    merged_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_person_merges_target_id ON person_merges (target_id);
+`
+
+func init() {
+	// SQLite lower-cases ASCII only, which would miss duplicates among
+	// Cyrillic names.
+	sqlite.MustRegisterDeterministicScalarFunction("initial", 1, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		s, _ := args[0].(string)
+		return initial(s), nil
+	})
+}
+
+// NewSQLiteDB opens the SQLite database at path, creating it and its schema
+// if needed. Write transactions lock the database when they begin, so that
+// they wait for each other instead of failing to upgrade a read lock. The
+// path ":memory:" opens a database that lives as long as the pool.
+func NewSQLiteDB(path string) (*sqlx.DB, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(%d)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)&_txlock=immediate&_time_format=sqlite",
+		path, sqliteBusyTimeout.Milliseconds())
+	db, err := sqlx.Open(sqliteDriver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	if path == ":memory:" {
+		// Every connection would get a database of its own.
+		db.SetMaxOpenConns(1)
+		db.SetConnMaxLifetime(0)
+		db.SetConnMaxIdleTime(0)
+	}
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create sqlite schema: %w", err)
+	}
+
+	return db, nil
+}
+
+type sqlitePersonRepository struct {
+	db     *sqlx.DB
+	logger logging.Logger
+}
+
+// NewSQLitePersonRepository stores people in a database opened by
+// NewSQLiteDB. Transactions on it come from NewTransactor.
+func NewSQLitePersonRepository(db *sqlx.DB, logger logging.Logger) PersonRepository {
+	return &sqlitePersonRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *sqlitePersonRepository) log(ctx context.Context) logging.Logger {
+	return logging.FromContext(ctx, r.logger)
+}
+
+func (r *sqlitePersonRepository) Create(ctx context.Context, person domain.Person) (domain.Person, error) {
+	query := `INSERT INTO people (name, surname, patronymic, age, gender, nationality, created_at, updated_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $7) RETURNING ` + personColumns
+
+	var created domain.Person
+	err := sqlx.GetContext(ctx, conn(ctx, r.db), &created, query,
+		person.Name,
+		person.Surname,
+		person.Patronymic,
+		person.Age,
+		person.Gender,
+		person.Nationality,
+		time.Now().UTC(),
+	)
+
+	if err != nil {
+		r.log(ctx).Error("Failed to create person: %v", err)
+		return domain.Person{}, err
+	}
+
+	return created, nil
+}
+
+func (r *sqlitePersonRepository) GetAll(ctx context.Context, filter domain.PersonFilter, page, limit int) ([]domain.Person, error) {
+	where, args := filterClause(filter)
+	argPos := len(args) + 1
+
+	query := `SELECT ` + personColumns + ` FROM people WHERE ` + where + orderClause(filter) +
+		` LIMIT $` + strconv.Itoa(argPos) + ` OFFSET $` + strconv.Itoa(argPos+1)
+	args = append(utcArgs(args), limit, (page-1)*limit)
+
+	var people []domain.Person
+	err := sqlx.SelectContext(ctx, conn(ctx, r.db), &people, query, args...)
+	if err != nil {
+		r.log(ctx).Error("Failed to get all people: %v", err)
+		return nil, err
+	}
+
+	return people, nil
+}
+
+func (r *sqlitePersonRepository) Export(ctx context.Context, filter domain.PersonFilter, fn func(person domain.Person) error) error {
+	where, args := filterClause(filter)
+	query := `SELECT ` + personColumns + ` FROM people WHERE ` + where + orderClause(filter)
+
+	rows, err := conn(ctx, r.db).QueryxContext(ctx, query, utcArgs(args)...)
+	if err != nil {
+		r.log(ctx).Error("Failed to export people: %v", err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var person domain.Person
+		if err := rows.StructScan(&person); err != nil {
+			return err
+		}
+		if err := fn(person); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// utcArgs converts the times among args to UTC, since timestamps are
+// compared as text.
+func utcArgs(args []interface{}) []interface{} {
+	for i, arg := range args {
+		if t, ok := arg.(time.Time); ok {
+			args[i] = t.UTC()
+		}
+	}
+	return args
+}
+
+func (r *sqlitePersonRepository) GetByID(ctx context.Context, id int) (domain.Person, error) {
+	query := `SELECT ` + personColumns + ` FROM people WHERE id = $1 AND deleted_at IS NULL`
+
+	var person domain.Person
+	err := sqlx.GetContext(ctx, conn(ctx, r.db), &person, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Person{}, domain.ErrNotFound
+	}
+	if err != nil {
+		r.log(ctx).Error("Failed to get person by ID %d: %v", id, err)
+		return domain.Person{}, err
+	}
+
+	return person, nil
+}
+
+func (r *sqlitePersonRepository) Update(ctx context.Context, id int, person domain.Person, expectedVersion int) (domain.Person, error) {
+	query := `UPDATE people SET name = $1, surname = $2, patronymic = $3, age = $4, gender = $5, nationality = $6,
+	          version = version + 1, updated_at = $9
+	          WHERE id = $7 AND deleted_at IS NULL AND ($8 = 0 OR version = $8) RETURNING ` + personColumns
+
+	var updated domain.Person
+	err := sqlx.GetContext(ctx, conn(ctx, r.db), &updated, query,
+		person.Name,
+		person.Surname,
+		person.Patronymic,
+		person.Age,
+		person.Gender,
+		person.Nationality,
+		id,
+		expectedVersion,
+		time.Now().UTC(),
+	)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Person{}, r.versionConflict(ctx, id)
+	}
+	if err != nil {
+		r.log(ctx).Error("Failed to update person with ID %d: %v", id, err)
+		return domain.Person{}, err
+	}
+
+	return updated, nil
+}
+
+func (r *sqlitePersonRepository) Delete(ctx context.Context, id int, expectedVersion int) error {
+	query := `UPDATE people SET deleted_at = $3, updated_at = $3, version = version + 1
+	          WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR version = $2)`
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, id, expectedVersion, time.Now().UTC())
+	if err != nil {
+		r.log(ctx).Error("Failed to delete person with ID %d: %v", id, err)
+		return err
+	}
+
+	err = checkAffected(res)
+	if errors.Is(err, domain.ErrNotFound) {
+		return r.versionConflict(ctx, id)
+	}
+	return err
+}
+
+// versionConflict tells apart a missing person from a stale version after a
+// conditional write matched no rows.
+func (r *sqlitePersonRepository) versionConflict(ctx context.Context, id int) error {
+	var exists bool
+	err := sqlx.GetContext(ctx, conn(ctx, r.db), &exists,
+		`SELECT EXISTS (SELECT 1 FROM people WHERE id = $1 AND deleted_at IS NULL)`, id)
+	if err != nil {
+		r.log(ctx).Error("Failed to check person with ID %d: %v", id, err)
+		return err
+	}
+	if exists {
+		return domain.ErrVersionMismatch
+	}
+	return domain.ErrNotFound
+}
+
+func (r *sqlitePersonRepository) FindDuplicateCandidates(ctx context.Context, name, surname string, afterID, limit int) ([]domain.Person, error) {
+	query := `SELECT ` + personColumns + ` FROM people
+	          WHERE deleted_at IS NULL AND initial(name) = initial($1) AND initial(surname) = initial($2)
+	          AND id > $3 ORDER BY id LIMIT $4`
+
+	var people []domain.Person
+	err := sqlx.SelectContext(ctx, conn(ctx, r.db), &people, query, name, surname, afterID, limit)
+	if err != nil {
+		r.log(ctx).Error("Failed to find duplicate candidates for %s %s: %v", name, surname, err)
+		return nil, err
+	}
+
+	return people, nil
+}
+
+func (r *sqlitePersonRepository) Merge(ctx context.Context, targetID, sourceID int, merged domain.Person, targetVersion, sourceVersion int) error {
+	return withinTx(ctx, r.db, func(ctx context.Context) error {
+		tx := conn(ctx, r.db)
+		now := time.Now().UTC()
+
+		// The transaction locked the database when it began, so the source
+		// cannot change before it is deleted.
+		var source domain.Person
+		err := sqlx.GetContext(ctx, tx, &source,
+			`SELECT `+personColumns+` FROM people WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR version = $2)`,
+			sourceID, sourceVersion)
+		if errors.Is(err, sql.ErrNoRows) {
+			return r.versionConflict(ctx, sourceID)
+		}
+		if err != nil {
+			r.log(ctx).Error("Failed to get person with ID %d: %v", sourceID, err)
+			return err
+		}
+
+		snapshot, err := json.Marshal(source)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx,
+			`UPDATE people SET name = $1, surname = $2, patronymic = $3, age = $4, gender = $5, nationality = $6,
+			 version = version + 1, updated_at = $8 WHERE id = $7 AND deleted_at IS NULL AND ($9 = 0 OR version = $9)`,
+			merged.Name,
+			merged.Surname,
+			merged.Patronymic,
+			merged.Age,
+			merged.Gender,
+			merged.Nationality,
+			targetID,
+			now,
+			targetVersion,
+		)
+		if err != nil {
+			r.log(ctx).Error("Failed to update merge target %d: %v", targetID, err)
+			return err
+		}
+		if err := checkAffected(res); errors.Is(err, domain.ErrNotFound) {
+			return r.versionConflict(ctx, targetID)
+		} else if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO person_merges (source_id, target_id, snapshot, merged_at) VALUES ($1, $2, $3, $4)`,
+			sourceID, targetID, string(snapshot), now,
+		); err != nil {
+			r.log(ctx).Error("Failed to record merge of %d into %d: %v", sourceID, targetID, err)
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE person_merges SET target_id = $1 WHERE target_id = $2`,
+			targetID, sourceID,
+		); err != nil {
+			r.log(ctx).Error("Failed to redirect merges of %d to %d: %v", sourceID, targetID, err)
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM people WHERE id = $1`, sourceID); err != nil {
+			r.log(ctx).Error("Failed to delete merged person %d: %v", sourceID, err)
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (r *sqlitePersonRepository) GetMergeTarget(ctx context.Context, id int) (int, error) {
+	query := `SELECT target_id FROM person_merges WHERE source_id = $1`
+
+	var targetID int
+	err := sqlx.GetContext(ctx, conn(ctx, r.db), &targetID, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, domain.ErrNotFound
+	}
+	if err != nil {
+		r.log(ctx).Error("Failed to get merge target for ID %d: %v", id, err)
+		return 0, err
+	}
+
+	return targetID, nil
+}
+
+func (r *sqlitePersonRepository) Restore(ctx context.Context, id int) error {
+	query := `UPDATE people SET deleted_at = NULL, updated_at = $2, version = version + 1 WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, id, time.Now().UTC())
+	if err != nil {
+		r.log(ctx).Error("Failed to restore person with ID %d: %v", id, err)
+		return err
+	}
+
+	return checkAffected(res)
+}
+
+func (r *sqlitePersonRepository) Purge(ctx context.Context, deletedBefore time.Time) ([]domain.Person, error) {
+	query := `DELETE FROM people WHERE deleted_at IS NOT NULL AND deleted_at < $1 RETURNING ` + personColumns
+
+	var people []domain.Person
+	err := sqlx.SelectContext(ctx, conn(ctx, r.db), &people, query, deletedBefore.UTC())
+	if err != nil {
+		r.log(ctx).Error("Failed to purge deleted people: %v", err)
+		return nil, err
+	}
+
+	return people, nil
+}
@@ -0,0 +1,29 @@
+package repository_test
+
+import (
+	"context"
+	"github.com/RakhimovAns/Person-Service/internal/repository"
+	"github.com/RakhimovAns/Person-Service/internal/repository/repotest"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"testing"
+)
+
+func TestSQLitePersonRepository(t *testing.T) {
+	logger := logging.New("error", "text")
+
+	err := repotest.TestPersonRepository(context.Background(), func() (repotest.Store, error) {
+		db, err := repository.NewSQLiteDB(":memory:")
+		if err != nil {
+			return repotest.Store{}, err
+		}
+		t.Cleanup(func() { db.Close() })
+
+		return repotest.Store{
+			People:     repository.NewSQLitePersonRepository(db, logger),
+			Transactor: repository.NewTransactor(db),
+		}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
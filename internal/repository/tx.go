@@ -0,0 +1,294 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/RakhimovAns/Person-Service/pkg/requestctx"
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+)
+
+var tracer = otel.Tracer("github.com/RakhimovAns/Person-Service/internal/repository")
+
+type Transactor interface {
+	// WithinTx runs fn in a database transaction. Repository calls made with
+	// the context passed to fn join the transaction; nested calls reuse it.
+	// When the transaction fails on a serialization failure, a deadlock or a
+	// lost connection before commit, it is rolled back and fn runs again, so
+	// fn must have no effects outside the transaction other than through
+	// AfterCommit: HTTP calls, publishing to sinks and enrichment belong
+	// before or after it.
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+	// AfterCommit runs fn once the transaction bound to ctx commits, or
+	// right away when there is none. It is not run if the transaction rolls
+	// back.
+	AfterCommit(ctx context.Context, fn func())
+}
+
+// txKey binds a transaction on db to a context, so that a context can carry
+// transactions on several databases.
+type txKey struct {
+	db *sqlx.DB
+}
+
+// txState is the transaction bound to a context and the callbacks waiting
+// for it to commit.
+type txState struct {
+	tx          *sqlx.Tx
+	afterCommit []func()
+}
+
+type transactor struct {
+	db *sqlx.DB
+}
+
+func NewTransactor(db *sqlx.DB) Transactor {
+	return &transactor{db: db}
+}
+
+func (t *transactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return withinTx(ctx, t.db, fn)
+}
+
+func (t *transactor) AfterCommit(ctx context.Context, fn func()) {
+	if state, ok := ctx.Value(txKey{db: t.db}).(*txState); ok {
+		state.afterCommit = append(state.afterCommit, fn)
+		return
+	}
+	fn()
+}
+
+type chainedTransactor struct {
+	outer  Transactor
+	inner  Transactor
+	logger logging.Logger
+}
+
+// NewChainedTransactor runs every transaction on two databases that cannot
+// share one, with the transaction on inner nested in the one on outer. When
+// fn fails, both roll back; inner commits first, so a failure to commit
+// outer after that leaves the two apart, is logged as an error and matches
+// ErrCommitUnknown. Such a transaction is not retried, which would apply fn
+// to inner twice.
+// AfterCommit waits for outer.
+func NewChainedTransactor(outer, inner Transactor, logger logging.Logger) Transactor {
+	return &chainedTransactor{
+		outer:  outer,
+		inner:  inner,
+		logger: logger,
+	}
+}
+
+// errInnerCommitted ends the retries of an outer transaction whose inner
+// transaction has committed already.
+var errInnerCommitted = errors.New("outer transaction failed to commit after the inner transaction committed")
+
+func (t *chainedTransactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	innerCommitted := false
+	err := t.outer.WithinTx(ctx, func(ctx context.Context) error {
+		if innerCommitted {
+			return errInnerCommitted
+		}
+		if err := t.inner.WithinTx(ctx, fn); err != nil {
+			return err
+		}
+		innerCommitted = true
+		return nil
+	})
+	if err != nil && innerCommitted {
+		logging.FromContext(ctx, t.logger).Error("Inner transaction committed but the outer one failed, the databases are out of sync: %v", err)
+		return &commitError{err: err}
+	}
+	return err
+}
+
+func (t *chainedTransactor) AfterCommit(ctx context.Context, fn func()) {
+	t.outer.AfterCommit(ctx, fn)
+}
+
+func withinTx(ctx context.Context, db *sqlx.DB, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{db: db}).(*txState); ok {
+		return fn(ctx)
+	}
+
+	return retry(ctx, retryableTx, func() error {
+		return runTx(ctx, db, fn)
+	})
+}
+
+// ErrCommitUnknown matches the errors of transactions that may have been
+// applied, in full or in part, although WithinTx failed.
+var ErrCommitUnknown = errors.New("transaction outcome unknown")
+
+// commitError is a lost connection during commit. Whether the transaction
+// was applied is unknown, so it is not retried.
+type commitError struct {
+	err error
+}
+
+func (e *commitError) Error() string        { return e.err.Error() }
+func (e *commitError) Unwrap() error        { return e.err }
+func (e *commitError) Is(target error) bool { return target == ErrCommitUnknown }
+
+func retryableTx(err error) bool {
+	var commitErr *commitError
+	return conflict(err) || (connectionLost(err) && !errors.As(err, &commitErr))
+}
+
+func runTx(ctx context.Context, db *sqlx.DB, fn func(ctx context.Context) error) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	state := &txState{tx: tx}
+	if err := fn(context.WithValue(ctx, txKey{db: db}, state)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		if connectionLost(err) {
+			return &commitError{err: err}
+		}
+		return err
+	}
+
+	for _, callback := range state.afterCommit {
+		callback()
+	}
+	return nil
+}
+
+// conn returns the transaction on db bound to ctx, or db when there is none.
+// Every query gets a client span. When ctx carries a request ID, queries are
+// also tagged with it in a trailing comment so that slow query logs and
+// pg_stat_activity can be traced to a request. Outside of a transaction,
+// SELECT queries are retried when the connection is lost.
+func conn(ctx context.Context, db *sqlx.DB) sqlx.ExtContext {
+	var ext sqlx.ExtContext = db
+	retryReads := true
+	if state, ok := ctx.Value(txKey{db: db}).(*txState); ok {
+		ext = state.tx
+		retryReads = false
+	}
+
+	var comment string
+	if requestID := requestctx.RequestID(ctx); requestctx.ValidRequestID(requestID) {
+		comment = " /*request_id='" + requestID + "'*/"
+	}
+	return &tracedConn{
+		ExtContext: ext,
+		system:     dbSystem(db),
+		comment:    comment,
+		retryReads: retryReads,
+	}
+}
+
+// dbSystem identifies the database behind db in spans.
+func dbSystem(db *sqlx.DB) attribute.KeyValue {
+	if db.DriverName() == sqliteDriver {
+		return semconv.DBSystemNameSqlite
+	}
+	return semconv.DBSystemNamePostgreSQL
+}
+
+// tracedConn starts a span for every query and appends comment to it.
+// Request IDs are validated before they get here, so they cannot end the
+// comment early. Spans of row queries end once the query has been sent, not
+// when the rows have been read.
+type tracedConn struct {
+	sqlx.ExtContext
+	system     attribute.KeyValue
+	comment    string
+	retryReads bool
+}
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := c.read(ctx, query, func() error {
+		ctx, span := startQuerySpan(ctx, c.system, query)
+		var err error
+		rows, err = c.ExtContext.QueryContext(ctx, query+c.comment, args...)
+		endQuerySpan(span, err)
+		return err
+	})
+	return rows, err
+}
+
+func (c *tracedConn) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	var rows *sqlx.Rows
+	err := c.read(ctx, query, func() error {
+		ctx, span := startQuerySpan(ctx, c.system, query)
+		var err error
+		rows, err = c.ExtContext.QueryxContext(ctx, query+c.comment, args...)
+		endQuerySpan(span, err)
+		return err
+	})
+	return rows, err
+}
+
+func (c *tracedConn) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
+	var row *sqlx.Row
+	_ = c.read(ctx, query, func() error {
+		ctx, span := startQuerySpan(ctx, c.system, query)
+		row = c.ExtContext.QueryRowxContext(ctx, query+c.comment, args...)
+		endQuerySpan(span, row.Err())
+		return row.Err()
+	})
+	return row
+}
+
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startQuerySpan(ctx, c.system, query)
+	result, err := c.ExtContext.ExecContext(ctx, query+c.comment, args...)
+	endQuerySpan(span, err)
+	return result, err
+}
+
+// read runs query through fn, retrying it when it is a SELECT outside of a
+// transaction and the connection was lost. Anything else could have changed
+// data before failing.
+func (c *tracedConn) read(ctx context.Context, query string, fn func() error) error {
+	if !c.retryReads || queryOperation(query) != "SELECT" {
+		return fn()
+	}
+	return retry(ctx, connectionLost, fn)
+}
+
+// startQuerySpan starts a span named after the SQL operation of query. The
+// query text carries placeholders only, never argument values.
+func startQuerySpan(ctx context.Context, system attribute.KeyValue, query string) (context.Context, trace.Span) {
+	operation := queryOperation(query)
+	return tracer.Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			system,
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(strings.TrimSpace(query)),
+		),
+	)
+}
+
+func endQuerySpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// queryOperation returns the first keyword of query.
+func queryOperation(query string) string {
+	if fields := strings.Fields(query); len(fields) > 0 {
+		return strings.ToUpper(fields[0])
+	}
+	return "QUERY"
+}
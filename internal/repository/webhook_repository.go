@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"time"
+)
+
+const deliveryColumns = `id, subscription_id, event_id, event_type, status, attempts, response_status, error,
+	created_at, next_attempt_at, delivered_at`
+
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, subscription domain.WebhookSubscription) (domain.WebhookSubscription, error)
+	// GetSubscriptions and GetSubscription leave the secret empty.
+	GetSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error)
+	GetSubscription(ctx context.Context, id int64) (domain.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id int64) error
+	// Enqueue schedules a delivery of the event to every subscription whose
+	// filter matches it. An event is enqueued at most once per subscription.
+	Enqueue(ctx context.Context, eventID int64, eventType string, payload []byte) (int64, error)
+	GetDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]domain.WebhookDelivery, error)
+	// Claim leases up to limit pending deliveries that are due, by pushing
+	// their next attempt lease into the future, so that they can be sent
+	// outside of a transaction without other workers taking them.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]domain.WebhookDispatch, error)
+	MarkDelivered(ctx context.Context, id int64, responseStatus int) error
+	// MarkFailed records a failed attempt. The delivery is retried after
+	// retryIn, or given up on when retryIn is zero.
+	MarkFailed(ctx context.Context, id int64, responseStatus *int, message string, retryIn time.Duration) error
+	// Replay schedules a delivery to be sent again from scratch.
+	Replay(ctx context.Context, subscriptionID, deliveryID int64) (domain.WebhookDelivery, error)
+}
+
+type webhookRepository struct {
+	db     *sqlx.DB
+	logger logging.Logger
+}
+
+func NewWebhookRepository(db *sqlx.DB, logger logging.Logger) WebhookRepository {
+	return &webhookRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *webhookRepository) log(ctx context.Context) logging.Logger {
+	return logging.FromContext(ctx, r.logger)
+}
+
+type subscriptionRow struct {
+	ID        int64          `db:"id"`
+	URL       string         `db:"url"`
+	Events    pq.StringArray `db:"events"`
+	Secret    string         `db:"secret"`
+	CreatedAt time.Time      `db:"created_at"`
+}
+
+func (row subscriptionRow) subscription() domain.WebhookSubscription {
+	return domain.WebhookSubscription{
+		ID:        row.ID,
+		URL:       row.URL,
+		Events:    []string(row.Events),
+		Secret:    row.Secret,
+		CreatedAt: row.CreatedAt,
+	}
+}
+
+type dispatchRow struct {
+	domain.WebhookDelivery
+	URL     string `db:"url"`
+	Secret  string `db:"secret"`
+	Payload []byte `db:"payload"`
+}
+
+func (r *webhookRepository) CreateSubscription(ctx context.Context, subscription domain.WebhookSubscription) (domain.WebhookSubscription, error) {
+	query := `INSERT INTO webhook_subscriptions (url, events, secret) VALUES ($1, $2, $3)
+	          RETURNING id, url, events, secret, created_at`
+
+	var row subscriptionRow
+	err := sqlx.GetContext(ctx, conn(ctx, r.db), &row, query,
+		subscription.URL,
+		pq.Array(subscription.Events),
+		subscription.Secret,
+	)
+	if err != nil {
+		r.log(ctx).Error("Failed to create webhook subscription: %v", err)
+		return domain.WebhookSubscription{}, err
+	}
+
+	return row.subscription(), nil
+}
+
+func (r *webhookRepository) GetSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	query := `SELECT id, url, events, created_at FROM webhook_subscriptions ORDER BY id`
+
+	var rows []subscriptionRow
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &rows, query); err != nil {
+		r.log(ctx).Error("Failed to get webhook subscriptions: %v", err)
+		return nil, err
+	}
+
+	subscriptions := make([]domain.WebhookSubscription, 0, len(rows))
+	for _, row := range rows {
+		subscriptions = append(subscriptions, row.subscription())
+	}
+
+	return subscriptions, nil
+}
+
+func (r *webhookRepository) GetSubscription(ctx context.Context, id int64) (domain.WebhookSubscription, error) {
+	query := `SELECT id, url, events, created_at FROM webhook_subscriptions WHERE id = $1`
+
+	var row subscriptionRow
+	err := sqlx.GetContext(ctx, conn(ctx, r.db), &row, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.WebhookSubscription{}, domain.ErrWebhookNotFound
+	}
+	if err != nil {
+		r.log(ctx).Error("Failed to get webhook subscription %d: %v", id, err)
+		return domain.WebhookSubscription{}, err
+	}
+
+	return row.subscription(), nil
+}
+
+func (r *webhookRepository) DeleteSubscription(ctx context.Context, id int64) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		r.log(ctx).Error("Failed to delete webhook subscription %d: %v", id, err)
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (r *webhookRepository) Enqueue(ctx context.Context, eventID int64, eventType string, payload []byte) (int64, error) {
+	query := `INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+	          SELECT id, $1::BIGINT, $2::VARCHAR, $3::JSONB FROM webhook_subscriptions
+	          WHERE cardinality(events) = 0 OR $2 = ANY(events)
+	          ON CONFLICT (subscription_id, event_id) DO NOTHING`
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, eventID, eventType, payload)
+	if err != nil {
+		r.log(ctx).Error("Failed to enqueue webhook deliveries of event %d: %v", eventID, err)
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (r *webhookRepository) GetDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]domain.WebhookDelivery, error) {
+	if _, err := r.GetSubscription(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries
+	          WHERE subscription_id = $1 ORDER BY id DESC LIMIT $2`
+
+	var deliveries []domain.WebhookDelivery
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &deliveries, query, subscriptionID, limit); err != nil {
+		r.log(ctx).Error("Failed to get deliveries of webhook %d: %v", subscriptionID, err)
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (r *webhookRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]domain.WebhookDispatch, error) {
+	query := `WITH claimed AS (
+	              UPDATE webhook_deliveries SET next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $2)
+	              WHERE id IN (
+	                  SELECT id FROM webhook_deliveries
+	                  WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+	                  ORDER BY next_attempt_at, id LIMIT $1 FOR UPDATE SKIP LOCKED
+	              ) RETURNING *
+	          )
+	          SELECT d.id, d.subscription_id, d.event_id, d.event_type, d.status, d.attempts, d.response_status,
+	                 d.error, d.created_at, d.next_attempt_at, d.delivered_at, s.url, s.secret, d.payload
+	          FROM claimed d JOIN webhook_subscriptions s ON s.id = d.subscription_id
+	          ORDER BY d.id`
+
+	var rows []dispatchRow
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &rows, query, limit, lease.Seconds()); err != nil {
+		r.log(ctx).Error("Failed to claim due webhook deliveries: %v", err)
+		return nil, err
+	}
+
+	dispatches := make([]domain.WebhookDispatch, 0, len(rows))
+	for _, row := range rows {
+		dispatches = append(dispatches, domain.WebhookDispatch{
+			Delivery: row.WebhookDelivery,
+			URL:      row.URL,
+			Secret:   row.Secret,
+			Payload:  row.Payload,
+		})
+	}
+
+	return dispatches, nil
+}
+
+func (r *webhookRepository) MarkDelivered(ctx context.Context, id int64, responseStatus int) error {
+	query := `UPDATE webhook_deliveries SET status = 'succeeded', attempts = attempts + 1, response_status = $2,
+	                 error = NULL, next_attempt_at = NULL, delivered_at = CURRENT_TIMESTAMP
+	          WHERE id = $1`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, id, responseStatus); err != nil {
+		r.log(ctx).Error("Failed to mark webhook delivery %d as delivered: %v", id, err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *webhookRepository) MarkFailed(ctx context.Context, id int64, responseStatus *int, message string, retryIn time.Duration) error {
+	query := `UPDATE webhook_deliveries SET attempts = attempts + 1, response_status = $2, error = $3,
+	                 status = CASE WHEN $4::FLOAT8 > 0 THEN 'pending' ELSE 'failed' END,
+	                 next_attempt_at = CASE WHEN $4 > 0 THEN CURRENT_TIMESTAMP + make_interval(secs => $4) END
+	          WHERE id = $1`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, id, responseStatus, message, retryIn.Seconds()); err != nil {
+		r.log(ctx).Error("Failed to record failure of webhook delivery %d: %v", id, err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *webhookRepository) Replay(ctx context.Context, subscriptionID, deliveryID int64) (domain.WebhookDelivery, error) {
+	query := `UPDATE webhook_deliveries SET status = 'pending', attempts = 0, response_status = NULL, error = NULL,
+	                 next_attempt_at = CURRENT_TIMESTAMP, delivered_at = NULL
+	          WHERE id = $1 AND subscription_id = $2 RETURNING ` + deliveryColumns
+
+	var delivery domain.WebhookDelivery
+	err := sqlx.GetContext(ctx, conn(ctx, r.db), &delivery, query, deliveryID, subscriptionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.WebhookDelivery{}, domain.ErrDeliveryNotFound
+	}
+	if err != nil {
+		r.log(ctx).Error("Failed to replay webhook delivery %d: %v", deliveryID, err)
+		return domain.WebhookDelivery{}, err
+	}
+
+	return delivery, nil
+}
@@ -0,0 +1,100 @@
+package rpc
+
+import (
+	personv1 "github.com/RakhimovAns/Person-Service/api/person/v1"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"time"
+)
+
+func toPerson(person domain.Person) *personv1.Person {
+	pb := &personv1.Person{
+		Id:          int64(person.ID),
+		Name:        person.Name,
+		Surname:     person.Surname,
+		Patronymic:  person.Patronymic,
+		Age:         int32(person.Age),
+		Gender:      person.Gender,
+		Nationality: person.Nationality,
+		Version:     int64(person.Version),
+		CreatedAt:   timestamppb.New(person.CreatedAt),
+		UpdatedAt:   timestamppb.New(person.UpdatedAt),
+	}
+	if person.DeletedAt != nil {
+		pb.DeletedAt = timestamppb.New(*person.DeletedAt)
+	}
+	return pb
+}
+
+func toEvent(event domain.Event) *personv1.PersonEvent {
+	pb := &personv1.PersonEvent{
+		Id:        event.ID,
+		Type:      event.Type,
+		PersonId:  int64(event.PersonID),
+		Actor:     event.Actor,
+		RequestId: event.RequestID,
+		Source:    event.Source,
+		CreatedAt: timestamppb.New(event.CreatedAt),
+	}
+	if event.Person != nil {
+		pb.Person = toPerson(*event.Person)
+	}
+	if len(event.Changes) > 0 {
+		pb.Changes = make(map[string]*personv1.FieldChange, len(event.Changes))
+		for field, change := range event.Changes {
+			pb.Changes[field] = &personv1.FieldChange{
+				Old: toValue(change.Old),
+				New: toValue(change.New),
+			}
+		}
+	}
+	return pb
+}
+
+// toValue converts a JSON-decoded value. Values that do not fit are sent as
+// null rather than failing the whole event.
+func toValue(v interface{}) *structpb.Value {
+	value, err := structpb.NewValue(v)
+	if err != nil {
+		return structpb.NewNullValue()
+	}
+	return value
+}
+
+func fromFilter(pb *personv1.PersonFilter) domain.PersonFilter {
+	filter := domain.PersonFilter{Deleted: domain.DeletedExclude}
+	if pb == nil {
+		return filter
+	}
+
+	filter.Name = pb.Name
+	filter.Surname = pb.Surname
+	filter.Patronymic = pb.Patronymic
+	filter.Gender = pb.Gender
+	filter.Nationality = pb.Nationality
+
+	if pb.Age != nil {
+		age := int(*pb.Age)
+		filter.Age = &age
+	}
+	if pb.CreatedAfter != nil {
+		filter.CreatedAfter = timePointer(pb.CreatedAfter.AsTime())
+	}
+	if pb.UpdatedSince != nil {
+		filter.UpdatedSince = timePointer(pb.UpdatedSince.AsTime())
+	}
+
+	switch pb.Deleted {
+	case personv1.DeletedFilter_DELETED_FILTER_ONLY:
+		filter.Deleted = domain.DeletedOnly
+	case personv1.DeletedFilter_DELETED_FILTER_INCLUDE:
+		filter.Deleted = domain.DeletedInclude
+	}
+
+	return filter
+}
+
+func timePointer(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,32 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// statusError maps service errors to the gRPC codes matching the REST status
+// codes for the same errors.
+func statusError(err error, message string) error {
+	var moved *domain.MovedError
+	var duplicate *domain.DuplicateError
+
+	switch {
+	case errors.As(err, &moved):
+		return status.Errorf(codes.NotFound, "Person was merged into %d", moved.ID)
+	case errors.Is(err, domain.ErrNotFound):
+		return status.Error(codes.NotFound, "Person not found")
+	case errors.As(err, &duplicate):
+		return status.Error(codes.AlreadyExists, fmt.Sprintf("Person already exists as %d (similarity %.2f)",
+			duplicate.Existing.ID, duplicate.Similarity))
+	case errors.Is(err, domain.ErrVersionMismatch):
+		return status.Error(codes.FailedPrecondition, "Person has been modified")
+	case errors.Is(err, domain.ErrInvalidMerge):
+		return status.Error(codes.InvalidArgument, "Cannot merge a person into itself")
+	default:
+		return status.Error(codes.Internal, message)
+	}
+}
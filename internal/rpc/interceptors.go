@@ -0,0 +1,71 @@
+package rpc
+
+import (
+	"context"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/RakhimovAns/Person-Service/pkg/requestctx"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	actorKey     = "x-actor"
+	requestIDKey = "x-request-id"
+)
+
+// requestContext stores the caller identity and request ID from the incoming
+// metadata, as the REST middleware does with the matching headers, so the
+// actor is recorded as unverified here too. It
+// returns the request ID, generated when the caller did not send a usable
+// one, to be echoed in the response header.
+func requestContext(ctx context.Context, logger logging.Logger) (context.Context, string) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	requestID := firstValue(md, requestIDKey)
+	if !requestctx.ValidRequestID(requestID) {
+		requestID = requestctx.NewRequestID()
+	}
+
+	ctx = requestctx.WithClaimedActor(ctx, firstValue(md, actorKey))
+	ctx = requestctx.WithRequestID(ctx, requestID)
+	ctx = requestctx.WithSource(ctx, requestctx.SourceAPI)
+	log := logger.With("request_id", requestID)
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		log = log.With("trace_id", spanContext.TraceID().String())
+	}
+	ctx = logging.NewContext(ctx, log)
+	return ctx, requestID
+}
+
+func unaryRequestContext(logger logging.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, requestID := requestContext(ctx, logger)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, requestID))
+		return handler(ctx, req)
+	}
+}
+
+func streamRequestContext(logger logging.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, requestID := requestContext(stream.Context(), logger)
+		_ = stream.SetHeader(metadata.Pairs(requestIDKey, requestID))
+		return handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func firstValue(md metadata.MD, key string) string {
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
@@ -0,0 +1,177 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	personv1 "github.com/RakhimovAns/Person-Service/api/person/v1"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/internal/service"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const defaultPageSize = 10
+
+type PersonServer struct {
+	personv1.UnimplementedPersonServiceServer
+	service service.PersonService
+	logger  logging.Logger
+}
+
+func NewPersonServer(service service.PersonService, logger logging.Logger) *PersonServer {
+	return &PersonServer{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (s *PersonServer) CreatePerson(ctx context.Context, req *personv1.CreatePersonRequest) (*personv1.Person, error) {
+	if req.Name == "" || req.Surname == "" {
+		return nil, status.Error(codes.InvalidArgument, "Name and surname are required")
+	}
+
+	person, err := s.service.Create(ctx, domain.PersonInput{
+		Name:       req.Name,
+		Surname:    req.Surname,
+		Patronymic: req.Patronymic,
+	})
+	var duplicate *domain.DuplicateError
+	if errors.As(err, &duplicate) {
+		s.log(ctx).Info("Duplicate of person %d detected (similarity %.2f)", duplicate.Existing.ID, duplicate.Similarity)
+		if duplicate.Policy == domain.DuplicatePolicyExisting {
+			return toPerson(person), nil
+		}
+		return nil, statusError(err, "")
+	}
+	if err != nil {
+		s.log(ctx).Error("Failed to create person: %v", err)
+		return nil, status.Error(codes.Internal, "Failed to create person")
+	}
+
+	return toPerson(person), nil
+}
+
+func (s *PersonServer) GetPerson(ctx context.Context, req *personv1.GetPersonRequest) (*personv1.Person, error) {
+	person, err := s.service.GetByID(ctx, int(req.Id))
+	if err != nil {
+		s.log(ctx).Error("Failed to get person by ID %d: %v", req.Id, err)
+		return nil, statusError(err, "Failed to get person")
+	}
+
+	return toPerson(person), nil
+}
+
+func (s *PersonServer) ListPeople(ctx context.Context, req *personv1.ListPeopleRequest) (*personv1.ListPeopleResponse, error) {
+	page := int(req.Page)
+	if page < 1 {
+		page = 1
+	}
+
+	limit := int(req.PageSize)
+	if limit < 1 {
+		limit = defaultPageSize
+	}
+
+	people, err := s.service.GetAll(ctx, fromFilter(req.Filter), page, limit)
+	if err != nil {
+		s.log(ctx).Error("Failed to get people: %v", err)
+		return nil, status.Error(codes.Internal, "Failed to get people")
+	}
+
+	resp := &personv1.ListPeopleResponse{People: make([]*personv1.Person, 0, len(people))}
+	for _, person := range people {
+		resp.People = append(resp.People, toPerson(person))
+	}
+	if len(people) == limit {
+		resp.NextPage = int32(page + 1)
+	}
+
+	return resp, nil
+}
+
+func (s *PersonServer) UpdatePerson(ctx context.Context, req *personv1.UpdatePersonRequest) (*personv1.Person, error) {
+	if req.Name == "" || req.Surname == "" {
+		return nil, status.Error(codes.InvalidArgument, "Name and surname are required")
+	}
+
+	person, err := s.service.Update(ctx, int(req.Id), domain.PersonInput{
+		Name:       req.Name,
+		Surname:    req.Surname,
+		Patronymic: req.Patronymic,
+	}, versions(req.ExpectedVersion))
+	if err != nil {
+		s.log(ctx).Error("Failed to update person with ID %d: %v", req.Id, err)
+		return nil, statusError(err, "Failed to update person")
+	}
+
+	return toPerson(person), nil
+}
+
+func (s *PersonServer) DeletePerson(ctx context.Context, req *personv1.DeletePersonRequest) (*emptypb.Empty, error) {
+	if err := s.service.Delete(ctx, int(req.Id), versions(req.ExpectedVersion)); err != nil {
+		s.log(ctx).Error("Failed to delete person with ID %d: %v", req.Id, err)
+		return nil, statusError(err, "Failed to delete person")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// WatchPeople ends with UNAVAILABLE when the watcher falls too far behind or
+// the server shuts down; clients reconnect with the ID of the last event they received.
+func (s *PersonServer) WatchPeople(req *personv1.WatchPeopleRequest, stream personv1.PersonService_WatchPeopleServer) error {
+	if req.LastEventId < 0 {
+		return status.Error(codes.InvalidArgument, "Invalid last_event_id")
+	}
+
+	types, err := eventTypes(req.Types)
+	if err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	for event := range s.service.Watch(ctx, fromFilter(req.Filter), req.LastEventId) {
+		if len(types) > 0 && !types[event.Type] {
+			continue
+		}
+		if err := stream.Send(toEvent(event)); err != nil {
+			return err
+		}
+	}
+
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	return status.Error(codes.Unavailable, "Watch ended, resume from the last event received")
+}
+
+func (s *PersonServer) log(ctx context.Context) logging.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
+
+// versions turns an expected_version field into the versions a write is
+// conditional on; zero means unconditional.
+func versions(expected int64) []int {
+	if expected == 0 {
+		return nil
+	}
+	return []int{int(expected)}
+}
+
+func eventTypes(values []string) (map[string]bool, error) {
+	known := make(map[string]bool, len(domain.EventTypes))
+	for _, eventType := range domain.EventTypes {
+		known[eventType] = true
+	}
+
+	types := make(map[string]bool, len(values))
+	for _, eventType := range values {
+		if !known[eventType] {
+			return nil, status.Errorf(codes.InvalidArgument, "Unknown event type %q", eventType)
+		}
+		types[eventType] = true
+	}
+
+	return types, nil
+}
@@ -0,0 +1,74 @@
+package rpc
+
+import (
+	"context"
+	personv1 "github.com/RakhimovAns/Person-Service/api/person/v1"
+	"github.com/RakhimovAns/Person-Service/internal/config"
+	"github.com/RakhimovAns/Person-Service/internal/service"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"net"
+)
+
+// Server serves the gRPC API on its own port, next to the REST server.
+type Server struct {
+	cfg    config.GRPCConfig
+	server *grpc.Server
+	logger logging.Logger
+}
+
+func NewServer(cfg config.GRPCConfig, service service.PersonService, logger logging.Logger) *Server {
+	server := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(unaryRequestContext(logger)),
+		grpc.ChainStreamInterceptor(streamRequestContext(logger)),
+	)
+
+	personv1.RegisterPersonServiceServer(server, NewPersonServer(service, logger))
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(personv1.PersonService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	if cfg.Reflection {
+		reflection.Register(server)
+	}
+
+	return &Server{
+		cfg:    cfg,
+		server: server,
+		logger: logger,
+	}
+}
+
+func (s *Server) Run() error {
+	listener, err := net.Listen("tcp", ":"+s.cfg.Port)
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("gRPC server listening on %s", listener.Addr())
+	return s.server.Serve(listener)
+}
+
+// Shutdown stops accepting connections and waits for the calls in flight
+// until ctx is done, after which they are cancelled.
+func (s *Server) Shutdown(ctx context.Context) {
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.server.Stop()
+		<-stopped
+	}
+}
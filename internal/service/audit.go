@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/pkg/metrics"
+	"github.com/RakhimovAns/Person-Service/pkg/requestctx"
+	"reflect"
+)
+
+// audit records a change in the audit log and writes the matching domain
+// event to the outbox. It must run in the transaction making the change.
+func (s *personService) audit(ctx context.Context, action string, personID int, before, after *domain.Person) error {
+	beforeData, beforeFields, err := snapshot(before)
+	if err != nil {
+		return err
+	}
+
+	afterData, afterFields, err := snapshot(after)
+	if err != nil {
+		return err
+	}
+
+	changes := diff(beforeFields, afterFields)
+
+	err = s.auditRepo.Create(ctx, domain.AuditEntry{
+		PersonID:  personID,
+		Action:    action,
+		Actor:     requestctx.Actor(ctx),
+		RequestID: requestctx.RequestID(ctx),
+		Source:    requestctx.Source(ctx),
+		OldData:   beforeData,
+		NewData:   afterData,
+		Diff:      changes,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.transactor.AfterCommit(ctx, func() {
+		metrics.PersonChanged(action)
+		if action == domain.AuditActionCreate && after != nil {
+			metrics.PersonCreated(after.Nationality)
+		}
+	})
+
+	person := after
+	if person == nil {
+		person = before
+	}
+
+	return s.outboxRepo.Create(ctx, domain.Event{
+		Type:      eventType(action, after),
+		PersonID:  personID,
+		Actor:     requestctx.Actor(ctx),
+		RequestID: requestctx.RequestID(ctx),
+		Source:    requestctx.Source(ctx),
+		Person:    person,
+		Changes:   changes,
+	})
+}
+
+// eventType maps an audit action to the event published for it. Merging
+// updates the target and deletes the source; a restore reads as an update.
+func eventType(action string, after *domain.Person) string {
+	switch {
+	case action == domain.AuditActionCreate:
+		return domain.EventPersonCreated
+	case action == domain.AuditActionEnrich:
+		return domain.EventPersonEnriched
+	case after == nil:
+		return domain.EventPersonDeleted
+	default:
+		return domain.EventPersonUpdated
+	}
+}
+
+func snapshot(person *domain.Person) (json.RawMessage, map[string]interface{}, error) {
+	if person == nil {
+		return nil, nil, nil
+	}
+
+	data, err := json.Marshal(person)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, nil, err
+	}
+
+	return data, fields, nil
+}
+
+// diff returns the fields whose values differ between two snapshots. A field
+// missing on one side is reported with a nil value there.
+func diff(before, after map[string]interface{}) map[string]domain.FieldChange {
+	changes := map[string]domain.FieldChange{}
+
+	for field, oldValue := range before {
+		newValue := after[field]
+		if !reflect.DeepEqual(oldValue, newValue) {
+			changes[field] = domain.FieldChange{Old: oldValue, New: newValue}
+		}
+	}
+
+	for field, newValue := range after {
+		if _, ok := before[field]; !ok {
+			changes[field] = domain.FieldChange{Old: nil, New: newValue}
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/internal/repository"
+	"github.com/RakhimovAns/Person-Service/pkg/metrics"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var errBatchAborted = errors.New("batch aborted")
+
+// CreateBatch enriches the inputs concurrently and stores them. In atomic
+// mode a single failure rolls back the whole batch; in partial mode the
+// items are stored in chunks and only the failing items are reported. Items
+// whose transaction may have committed although it failed are reported as
+// unknown rather than stored again.
+func (s *personService) CreateBatch(ctx context.Context, inputs []domain.PersonInput, mode string) (domain.BatchResult, error) {
+	if mode == "" {
+		mode = domain.BatchModePartial
+	}
+	if mode != domain.BatchModeAtomic && mode != domain.BatchModePartial {
+		return domain.BatchResult{}, fmt.Errorf("%w: unknown mode %q", domain.ErrInvalidBatch, mode)
+	}
+	if len(inputs) == 0 {
+		return domain.BatchResult{}, fmt.Errorf("%w: no items", domain.ErrInvalidBatch)
+	}
+	if s.batch.MaxItems > 0 && len(inputs) > s.batch.MaxItems {
+		return domain.BatchResult{}, fmt.Errorf("%w: at most %d items allowed", domain.ErrInvalidBatch, s.batch.MaxItems)
+	}
+
+	results := make([]domain.BatchItemResult, len(inputs))
+	s.dedupeBatch(ctx, inputs, results)
+	people := s.prepareBatch(ctx, inputs, results)
+
+	if mode == domain.BatchModeAtomic {
+		s.storeAtomic(ctx, people, results)
+	} else {
+		s.storePartial(ctx, people, results)
+	}
+
+	result := domain.BatchResult{Mode: mode, Items: results}
+	for _, item := range results {
+		switch item.Status {
+		case domain.BatchStatusCreated:
+			result.Created++
+		case domain.BatchStatusFailed:
+			result.Failed++
+		}
+	}
+
+	return result, nil
+}
+
+// dedupeBatch applies the duplicate policy to items that duplicate an
+// earlier item of the same batch, which the stored people cannot show. With
+// the existing policy the later item is skipped, as nothing is stored yet to
+// return in its place.
+func (s *personService) dedupeBatch(ctx context.Context, inputs []domain.PersonInput, results []domain.BatchItemResult) {
+	for i, input := range inputs {
+		for j, earlier := range inputs[:i] {
+			if results[j].Status != "" {
+				continue
+			}
+
+			score := similarity(input, domain.Person{
+				Name:       earlier.Name,
+				Surname:    earlier.Surname,
+				Patronymic: earlier.Patronymic,
+			})
+			if score < s.duplicates.Threshold {
+				continue
+			}
+
+			err := fmt.Errorf("duplicate of item %d (similarity %.2f)", j, score)
+			switch s.duplicates.Policy {
+			case domain.DuplicatePolicyReject:
+				results[i] = failed(err)
+			case domain.DuplicatePolicyExisting:
+				results[i] = domain.BatchItemResult{Status: domain.BatchStatusSkipped, Error: err.Error()}
+			default:
+				s.log(ctx).Warn("Creating possible duplicate of batch item %d (similarity %.2f)", j, score)
+				continue
+			}
+			results[i].Index = i
+			metrics.DuplicateDetected(s.duplicates.Policy)
+			break
+		}
+	}
+}
+
+// prepareBatch validates, de-duplicates and enriches every input not already
+// settled by dedupeBatch, with at most batch.Concurrency enrichments in
+// flight. Items that are ready to be stored are returned by index; the
+// others have their result filled in.
+func (s *personService) prepareBatch(ctx context.Context, inputs []domain.PersonInput, results []domain.BatchItemResult) map[int]domain.Person {
+	concurrency := s.batch.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		sem    = make(chan struct{}, concurrency)
+		people = make(map[int]domain.Person, len(inputs))
+	)
+
+	for i, input := range inputs {
+		if results[i].Status != "" {
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, input domain.PersonInput) {
+			defer wg.Done()
+
+			var (
+				person domain.Person
+				result domain.BatchItemResult
+			)
+			select {
+			case sem <- struct{}{}:
+				person, result = s.prepareItem(ctx, input)
+				<-sem
+			case <-ctx.Done():
+				result = failed(ctx.Err())
+			}
+			result.Index = i
+
+			mu.Lock()
+			defer mu.Unlock()
+			results[i] = result
+			if result.Status == "" {
+				people[i] = person
+			}
+		}(i, input)
+	}
+
+	wg.Wait()
+	return people
+}
+
+func (s *personService) prepareItem(ctx context.Context, input domain.PersonInput) (domain.Person, domain.BatchItemResult) {
+	if strings.TrimSpace(input.Name) == "" || strings.TrimSpace(input.Surname) == "" {
+		return domain.Person{}, failed(errors.New("name and surname are required"))
+	}
+
+	duplicate, err := s.findDuplicate(ctx, input)
+	if err != nil {
+		return domain.Person{}, failed(err)
+	}
+	if duplicate != nil {
+		switch duplicate.Policy {
+		case domain.DuplicatePolicyReject:
+			return domain.Person{}, failed(duplicate)
+		case domain.DuplicatePolicyExisting:
+			existing := duplicate.Existing
+			return domain.Person{}, domain.BatchItemResult{Status: domain.BatchStatusExisting, Person: &existing}
+		default:
+			s.log(ctx).Warn("Creating possible duplicate of person %d (similarity %.2f)", duplicate.Existing.ID, duplicate.Similarity)
+		}
+	}
+
+	person := domain.Person{
+		Name:       input.Name,
+		Surname:    input.Surname,
+		Patronymic: input.Patronymic,
+	}
+	if err := s.enrich(ctx, &person); err != nil {
+		return domain.Person{}, failed(err)
+	}
+
+	return person, domain.BatchItemResult{}
+}
+
+func (s *personService) storeAtomic(ctx context.Context, people map[int]domain.Person, results []domain.BatchItemResult) {
+	for i := range results {
+		if results[i].Status == domain.BatchStatusFailed {
+			skipPending(people, results, "batch aborted: item %d failed", i)
+			return
+		}
+	}
+
+	indexes := sortedIndexes(people)
+
+	// The transaction may run more than once, so what it stored is only
+	// turned into results once it is done.
+	var (
+		created  map[int]domain.Person
+		failedAt int
+		failure  error
+	)
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		created = make(map[int]domain.Person, len(indexes))
+		failedAt, failure = -1, nil
+		for _, i := range indexes {
+			person, err := s.insert(ctx, people[i])
+			if err != nil {
+				failedAt, failure = i, err
+				return errBatchAborted
+			}
+			created[i] = person
+		}
+		return nil
+	})
+	if errors.Is(err, repository.ErrCommitUnknown) {
+		s.log(ctx).Error("Failed to commit batch, its outcome is unknown: %v", err)
+		for _, i := range indexes {
+			results[i] = unknown(i, err)
+		}
+		return
+	}
+	if err != nil {
+		s.log(ctx).Error("Failed to store batch: %v", err)
+		for _, i := range indexes {
+			results[i] = domain.BatchItemResult{Index: i, Status: domain.BatchStatusSkipped, Error: "batch rolled back"}
+		}
+		if failedAt >= 0 {
+			results[failedAt] = failed(failure)
+			results[failedAt].Index = failedAt
+		}
+		return
+	}
+
+	for i, person := range created {
+		results[i] = domain.BatchItemResult{Index: i, Status: domain.BatchStatusCreated, Person: &person}
+	}
+}
+
+func (s *personService) storePartial(ctx context.Context, people map[int]domain.Person, results []domain.BatchItemResult) {
+	indexes := sortedIndexes(people)
+
+	chunkSize := s.batch.ChunkSize
+	if chunkSize < 1 {
+		chunkSize = len(indexes)
+	}
+
+	for start := 0; start < len(indexes); start += chunkSize {
+		chunk := indexes[start:min(start+chunkSize, len(indexes))]
+
+		var created map[int]domain.Person
+		err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+			created = make(map[int]domain.Person, len(chunk))
+			for _, i := range chunk {
+				person, err := s.insert(ctx, people[i])
+				if err != nil {
+					return err
+				}
+				created[i] = person
+			}
+			return nil
+		})
+
+		switch {
+		case errors.Is(err, repository.ErrCommitUnknown):
+			// The chunk may have been stored, so retrying its items could
+			// store them twice.
+			s.log(ctx).Error("Failed to commit batch chunk, its outcome is unknown: %v", err)
+			for _, i := range chunk {
+				results[i] = unknown(i, err)
+			}
+		case err != nil:
+			// A failed statement aborts the chunk transaction, so retry its
+			// items one by one to isolate the bad ones.
+			s.log(ctx).Warn("Failed to store batch chunk, retrying items individually: %v", err)
+			for _, i := range chunk {
+				person, err := s.insert(ctx, people[i])
+				if errors.Is(err, repository.ErrCommitUnknown) {
+					results[i] = unknown(i, err)
+					continue
+				}
+				if err != nil {
+					results[i] = failed(err)
+					results[i].Index = i
+					continue
+				}
+				results[i] = domain.BatchItemResult{Index: i, Status: domain.BatchStatusCreated, Person: &person}
+			}
+		default:
+			for i, person := range created {
+				results[i] = domain.BatchItemResult{Index: i, Status: domain.BatchStatusCreated, Person: &person}
+			}
+		}
+	}
+}
+
+func (s *personService) insert(ctx context.Context, person domain.Person) (domain.Person, error) {
+	var created domain.Person
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		created, err = s.repo.Create(ctx, person)
+		if err != nil {
+			return err
+		}
+
+		return s.audit(ctx, domain.AuditActionCreate, created.ID, nil, &created)
+	})
+
+	return created, err
+}
+
+func skipPending(people map[int]domain.Person, results []domain.BatchItemResult, format string, args ...interface{}) {
+	for i := range people {
+		results[i] = domain.BatchItemResult{Index: i, Status: domain.BatchStatusSkipped, Error: fmt.Sprintf(format, args...)}
+	}
+}
+
+func sortedIndexes(people map[int]domain.Person) []int {
+	indexes := make([]int, 0, len(people))
+	for i := range people {
+		indexes = append(indexes, i)
+	}
+	slices.Sort(indexes)
+	return indexes
+}
+
+func failed(err error) domain.BatchItemResult {
+	return domain.BatchItemResult{Status: domain.BatchStatusFailed, Error: err.Error()}
+}
+
+// unknown is the result of item i when the transaction storing it may or may
+// not have committed.
+func unknown(i int, err error) domain.BatchItemResult {
+	return domain.BatchItemResult{Index: i, Status: domain.BatchStatusUnknown, Error: err.Error()}
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/internal/repository"
+	"slices"
+	"testing"
+)
+
+var batchInputs = []domain.PersonInput{
+	{Name: "Ivan", Surname: "Petrov"},
+	{Name: "Anna", Surname: ""},
+	{Name: "Oleg", Surname: "Broken"},
+	{Name: "Maria", Surname: "Sidorova"},
+	{Name: "Pavel", Surname: "Orlov"},
+}
+
+func statuses(result domain.BatchResult) []string {
+	var statuses []string
+	for i, item := range result.Items {
+		if item.Index != i {
+			statuses = append(statuses, "wrong index")
+			continue
+		}
+		statuses = append(statuses, item.Status)
+	}
+	return statuses
+}
+
+// checkStored fails unless every created item of result is stored as
+// returned and nothing else is stored.
+func checkStored(t *testing.T, s testService, result domain.BatchResult) {
+	t.Helper()
+	ctx := context.Background()
+
+	var created []int
+	for _, item := range result.Items {
+		if item.Status != domain.BatchStatusCreated {
+			continue
+		}
+		stored, err := s.repo.GetByID(ctx, item.Person.ID)
+		if err != nil || stored.Surname != item.Person.Surname {
+			t.Errorf("item %d: got %+v, %v stored; want %+v", item.Index, stored, err, *item.Person)
+		}
+		created = append(created, item.Person.ID)
+	}
+
+	people, err := s.repo.GetAll(ctx, domain.PersonFilter{}, 1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored []int
+	for _, person := range people {
+		stored = append(stored, person.ID)
+	}
+	slices.Sort(created)
+	slices.Sort(stored)
+	if !slices.Equal(created, stored) {
+		t.Errorf("stored people %v, want the created ones %v", stored, created)
+	}
+}
+
+func TestCreateBatchPartial(t *testing.T) {
+	s := newTestService(nil)
+	s.people.failSurname = "Broken"
+
+	result, err := s.CreateBatch(context.Background(), batchInputs, domain.BatchModePartial)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		domain.BatchStatusCreated,
+		domain.BatchStatusFailed,
+		domain.BatchStatusFailed,
+		domain.BatchStatusCreated,
+		domain.BatchStatusCreated,
+	}
+	if got := statuses(result); !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if result.Created != 3 || result.Failed != 2 {
+		t.Errorf("got %d created and %d failed, want 3 and 2", result.Created, result.Failed)
+	}
+	checkStored(t, s, result)
+}
+
+func TestCreateBatchAtomic(t *testing.T) {
+	t.Run("invalid item", func(t *testing.T) {
+		s := newTestService(nil)
+
+		result, err := s.CreateBatch(context.Background(), batchInputs, domain.BatchModeAtomic)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []string{
+			domain.BatchStatusSkipped,
+			domain.BatchStatusFailed,
+			domain.BatchStatusSkipped,
+			domain.BatchStatusSkipped,
+			domain.BatchStatusSkipped,
+		}
+		if got := statuses(result); !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if calls := s.people.createCalls(); calls != 0 {
+			t.Errorf("got %d creates, want none", calls)
+		}
+	})
+
+	t.Run("failed insert", func(t *testing.T) {
+		s := newTestService(nil)
+		s.people.failSurname = "Broken"
+		inputs := slices.Delete(slices.Clone(batchInputs), 1, 2)
+
+		result, err := s.CreateBatch(context.Background(), inputs, domain.BatchModeAtomic)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []string{
+			domain.BatchStatusSkipped,
+			domain.BatchStatusFailed,
+			domain.BatchStatusSkipped,
+			domain.BatchStatusSkipped,
+		}
+		if got := statuses(result); !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		checkStored(t, s, result)
+	})
+
+	t.Run("retried transaction", func(t *testing.T) {
+		var transactor *flakyTransactor
+		s := newTestService(func(db *repository.MemoryDB) repository.Transactor {
+			transactor = &flakyTransactor{Transactor: repository.NewMemoryTransactor(db), conflict: true}
+			return transactor
+		})
+		inputs := slices.Delete(slices.Clone(batchInputs), 1, 3)
+
+		result, err := s.CreateBatch(context.Background(), inputs, domain.BatchModeAtomic)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []string{domain.BatchStatusCreated, domain.BatchStatusCreated, domain.BatchStatusCreated}
+		if got := statuses(result); !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if transactor.conflict {
+			t.Error("the transaction was not retried")
+		}
+		checkStored(t, s, result)
+	})
+}
+
+func TestCreateBatchLostCommit(t *testing.T) {
+	inputs := slices.Delete(slices.Clone(batchInputs), 1, 3)
+	unknown := []string{domain.BatchStatusUnknown, domain.BatchStatusUnknown, domain.BatchStatusUnknown}
+
+	for _, mode := range []string{domain.BatchModeAtomic, domain.BatchModePartial} {
+		t.Run(mode, func(t *testing.T) {
+			s := newTestService(func(db *repository.MemoryDB) repository.Transactor {
+				return &flakyTransactor{Transactor: repository.NewMemoryTransactor(db), lost: true}
+			})
+
+			result, err := s.CreateBatch(context.Background(), inputs, mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := statuses(result); !slices.Equal(got, unknown) {
+				t.Errorf("got %v, want %v", got, unknown)
+			}
+			if result.Created != 0 || result.Failed != 0 {
+				t.Errorf("got %d created and %d failed, want none", result.Created, result.Failed)
+			}
+			// The items may have been stored, so they must not be stored
+			// again one by one.
+			if calls := s.people.createCalls(); calls != len(inputs) {
+				t.Errorf("got %d creates, want %d", calls, len(inputs))
+			}
+		})
+	}
+}
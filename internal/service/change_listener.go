@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"github.com/RakhimovAns/Person-Service/internal/config"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/internal/repository"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"time"
+)
+
+// ChangeListener propagates person events across instances. Every instance
+// is notified of the events committed by any of them, loads them from the
+// outbox and hands them to its handlers, such as the SSE broker or local
+// caches that need invalidating.
+type ChangeListener struct {
+	listener  *repository.EventListener
+	repo      repository.OutboxRepository
+	cfg       config.ListenConfig
+	batchSize int
+	handlers  []func(event domain.Event)
+	logger    logging.Logger
+
+	// since is the creation time, by the database clock, of the last event
+	// received. Backfills start a window before it, as transactions can
+	// commit well after their events were created.
+	since time.Time
+}
+
+func NewChangeListener(
+	listener *repository.EventListener,
+	repo repository.OutboxRepository,
+	cfg config.ListenConfig,
+	batchSize int,
+	logger logging.Logger,
+) *ChangeListener {
+	return &ChangeListener{
+		listener:  listener,
+		repo:      repo,
+		cfg:       cfg,
+		batchSize: batchSize,
+		logger:    logger,
+	}
+}
+
+// OnChange registers fn to be called with every event. It must be called
+// before Run.
+func (l *ChangeListener) OnChange(fn func(event domain.Event)) {
+	l.handlers = append(l.handlers, fn)
+}
+
+// Run listens until ctx is cancelled, catching up on missed events at start
+// and after every reconnect.
+func (l *ChangeListener) Run(ctx context.Context) {
+	l.backfill(ctx)
+
+	err := l.listener.Listen(ctx, func(eventID int64) {
+		if eventID == 0 {
+			l.backfill(ctx)
+			return
+		}
+
+		event, err := l.repo.GetByID(ctx, eventID)
+		if err != nil {
+			l.logger.Error("Failed to load event %d: %v", eventID, err)
+			return
+		}
+		l.dispatch(event)
+	})
+	if err != nil {
+		l.logger.Error("Event listener stopped: %v", err)
+	}
+}
+
+// backfill dispatches the events that may have been missed. Handlers must
+// tolerate events they have already seen.
+func (l *ChangeListener) backfill(ctx context.Context) {
+	if l.since.IsZero() {
+		events, err := l.repo.Recent(ctx, l.batchSize)
+		if err != nil {
+			l.logger.Error("Failed to load recent events: %v", err)
+			return
+		}
+		for _, event := range events {
+			l.dispatch(event)
+		}
+		return
+	}
+
+	from := l.since.Add(-l.cfg.BackfillWindow)
+	afterID := int64(0)
+	dispatched := 0
+	for {
+		events, err := l.repo.Since(ctx, from, afterID, l.batchSize)
+		if err != nil {
+			l.logger.Error("Failed to backfill events: %v", err)
+			return
+		}
+
+		for _, event := range events {
+			l.dispatch(event)
+			afterID = event.ID
+		}
+		dispatched += len(events)
+
+		if len(events) < l.batchSize {
+			break
+		}
+	}
+
+	l.logger.Info("Backfilled %d events since %s", dispatched, from.Format(time.RFC3339))
+}
+
+func (l *ChangeListener) dispatch(event domain.Event) {
+	if event.CreatedAt.After(l.since) {
+		l.since = event.CreatedAt
+	}
+	for _, handler := range l.handlers {
+		handler(event)
+	}
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"strings"
+	"unicode"
+)
+
+// duplicateCandidatesPage is how many duplicate candidates are loaded and
+// scored at a time.
+const duplicateCandidatesPage = 500
+
+// normalizeName lowercases a name part, folds "ё" into "е", drops punctuation
+// and collapses whitespace so that "  Иванов-Петров " and "иванов петров" compare equal.
+func normalizeName(value string) string {
+	value = strings.ToLower(value)
+	value = strings.ReplaceAll(value, "ё", "е")
+
+	var b strings.Builder
+	for _, r := range value {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		case unicode.IsSpace(r) || r == '-':
+			b.WriteRune(' ')
+		}
+	}
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func fullName(name, surname string, patronymic *string) string {
+	parts := []string{normalizeName(surname), normalizeName(name)}
+	if patronymic != nil && normalizeName(*patronymic) != "" {
+		parts = append(parts, normalizeName(*patronymic))
+	}
+	return strings.Join(parts, " ")
+}
+
+// similarity compares two people by their normalised full names and returns
+// a score between 0 and 1. A missing patronymic on either side is ignored.
+func similarity(input domain.PersonInput, person domain.Person) float64 {
+	var inputPatronymic, personPatronymic *string
+	if input.Patronymic != nil && person.Patronymic != nil {
+		inputPatronymic, personPatronymic = input.Patronymic, person.Patronymic
+	}
+
+	a := []rune(fullName(input.Name, input.Surname, inputPatronymic))
+	b := []rune(fullName(person.Name, person.Surname, personPatronymic))
+
+	maxLen := len(a)
+	if len(b) > maxLen {
+		maxLen = len(b)
+	}
+	if maxLen == 0 {
+		return 1
+	}
+
+	return 1 - float64(levenshtein(a, b))/float64(maxLen)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
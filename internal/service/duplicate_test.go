@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"math"
+	"testing"
+)
+
+func TestSimilarity(t *testing.T) {
+	patronymic := func(value string) *string { return &value }
+
+	tests := []struct {
+		name   string
+		input  domain.PersonInput
+		person domain.Person
+		want   float64
+	}{
+		{
+			name:   "same",
+			input:  domain.PersonInput{Name: "Ivan", Surname: "Petrov", Patronymic: patronymic("Sergeevich")},
+			person: domain.Person{Name: "Ivan", Surname: "Petrov", Patronymic: patronymic("Sergeevich")},
+			want:   1,
+		},
+		{
+			name:   "case, punctuation and spacing",
+			input:  domain.PersonInput{Name: "  иван ", Surname: "Иванов-Петров"},
+			person: domain.Person{Name: "Иван", Surname: "иванов  петров"},
+			want:   1,
+		},
+		{
+			name:   "ё folded into е",
+			input:  domain.PersonInput{Name: "Пётр", Surname: "Семёнов"},
+			person: domain.Person{Name: "Петр", Surname: "Семенов"},
+			want:   1,
+		},
+		{
+			name:   "one patronymic missing",
+			input:  domain.PersonInput{Name: "Ivan", Surname: "Petrov"},
+			person: domain.Person{Name: "Ivan", Surname: "Petrov", Patronymic: patronymic("Sergeevich")},
+			want:   1,
+		},
+		{
+			name:   "different patronymics",
+			input:  domain.PersonInput{Name: "Ivan", Surname: "Petrov", Patronymic: patronymic("Olegovich")},
+			person: domain.Person{Name: "Ivan", Surname: "Petrov", Patronymic: patronymic("Sergeevich")},
+			want:   1 - 5.0/22,
+		},
+		{
+			name:   "one typo",
+			input:  domain.PersonInput{Name: "Ivan", Surname: "Petrof"},
+			person: domain.Person{Name: "Ivan", Surname: "Petrov"},
+			want:   1 - 1.0/11,
+		},
+		{
+			name:   "empty",
+			input:  domain.PersonInput{},
+			person: domain.Person{},
+			want:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		if got := similarity(tt.input, tt.person); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFindDuplicate(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(nil)
+	for _, person := range []domain.Person{
+		{Name: "Ivan", Surname: "Petrova"},
+		{Name: "Ivan", Surname: "Petrov"},
+		{Name: "Igor", Surname: "Popov"},
+	} {
+		if _, err := s.repo.Create(ctx, person); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, policy := range []string{domain.DuplicatePolicyWarn, domain.DuplicatePolicyReject, domain.DuplicatePolicyExisting} {
+		s.duplicates.Policy = policy
+
+		duplicate, err := s.findDuplicate(ctx, domain.PersonInput{Name: "ivan", Surname: "PETROV"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if duplicate == nil || duplicate.Existing.Surname != "Petrov" || duplicate.Similarity != 1 || duplicate.Policy != policy {
+			t.Errorf("%s: got %+v, want the exact match under that policy", policy, duplicate)
+		}
+		if !errors.Is(duplicate, domain.ErrDuplicate) {
+			t.Errorf("%s: %v does not match %v", policy, duplicate, domain.ErrDuplicate)
+		}
+	}
+
+	duplicate, err := s.findDuplicate(ctx, domain.PersonInput{Name: "Ivan", Surname: "Popov"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if duplicate != nil {
+		t.Errorf("got %+v below the threshold, want none", duplicate)
+	}
+}
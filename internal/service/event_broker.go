@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/pkg/sink"
+	"sync"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before it
+// is dropped. Dropped subscribers can resume from the last event they saw.
+const subscriberBuffer = 64
+
+// EventBroker fans person events out to in-process subscribers and keeps the
+// most recent ones in a bounded log so that subscribers can resume after a
+// reconnect. It is fed by the change listener, or by the outbox relay as a
+// sink when listening is disabled.
+type EventBroker struct {
+	mu          sync.Mutex
+	log         []domain.Event
+	next        int
+	full        bool
+	logged      map[int64]struct{}
+	subscribers map[chan domain.Event]struct{}
+	closed      bool
+}
+
+func NewEventBroker(capacity int) *EventBroker {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &EventBroker{
+		log:         make([]domain.Event, capacity),
+		logged:      make(map[int64]struct{}, capacity),
+		subscribers: make(map[chan domain.Event]struct{}),
+	}
+}
+
+func (b *EventBroker) Publish(ctx context.Context, msg sink.Message) error {
+	var event domain.Event
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		return err
+	}
+
+	b.Broadcast(event)
+	return nil
+}
+
+// Broadcast appends event to the log and hands it to every subscriber.
+// Events still in the log are ignored, since the relay delivers at least
+// once. IDs are not necessarily increasing: transactions commit in a
+// different order than they allocate event IDs.
+func (b *EventBroker) Broadcast(event domain.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.logged[event.ID]; ok {
+		return
+	}
+	if b.full {
+		delete(b.logged, b.log[b.next].ID)
+	}
+	b.logged[event.ID] = struct{}{}
+	b.log[b.next] = event
+	b.next = (b.next + 1) % len(b.log)
+	if b.next == 0 {
+		b.full = true
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe returns the logged events that followed the event lastID and a
+// channel of the events that follow. The channel is closed when the
+// subscriber falls too far behind, Unsubscribe is called or the broker is
+// closed.
+func (b *EventBroker) Subscribe(lastID int64) ([]domain.Event, chan domain.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var backlog []domain.Event
+	if lastID > 0 {
+		backlog = b.since(lastID)
+	}
+
+	ch := make(chan domain.Event, subscriberBuffer)
+	if b.closed {
+		close(ch)
+		return backlog, ch
+	}
+	b.subscribers[ch] = struct{}{}
+	return backlog, ch
+}
+
+func (b *EventBroker) Unsubscribe(ch chan domain.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// Close ends every subscription, and those made later, so that streams
+// finish and their clients can resume elsewhere when the server shuts down.
+func (b *EventBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for ch := range b.subscribers {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// since returns the logged events broadcast after the event lastID. When
+// that event has left the log, it falls back to the events with higher IDs.
+func (b *EventBroker) since(lastID int64) []domain.Event {
+	start, count := 0, b.next
+	if b.full {
+		start, count = b.next, len(b.log)
+	}
+
+	_, logged := b.logged[lastID]
+
+	var events []domain.Event
+	found := false
+	for i := 0; i < count; i++ {
+		event := b.log[(start+i)%len(b.log)]
+		switch {
+		case logged && found:
+			events = append(events, event)
+		case logged:
+			found = event.ID == lastID
+		case event.ID > lastID:
+			events = append(events, event)
+		}
+	}
+	return events
+}
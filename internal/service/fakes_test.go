@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/RakhimovAns/Person-Service/internal/config"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/internal/repository"
+	"github.com/RakhimovAns/Person-Service/pkg/client"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"sync"
+)
+
+// testService is a person service on an in-memory database, with every
+// provider answering with fixed values.
+type testService struct {
+	*personService
+	people *failingPeople
+}
+
+func newTestService(transactor func(db *repository.MemoryDB) repository.Transactor) testService {
+	db := repository.NewMemoryDB()
+	people := &failingPeople{PersonRepository: repository.NewMemoryPersonRepository(db)}
+	if transactor == nil {
+		transactor = repository.NewMemoryTransactor
+	}
+
+	var enricher fakeEnricher
+	s := NewPersonService(
+		people,
+		fakeAudit{},
+		fakeOutbox{},
+		transactor(db),
+		nil,
+		enricher,
+		enricher,
+		enricher,
+		config.DuplicateConfig{Policy: domain.DuplicatePolicyWarn, Threshold: 0.9},
+		config.BatchConfig{MaxItems: 100, Concurrency: 2, ChunkSize: 2},
+		logging.New("fatal", "text"),
+	).(*personService)
+
+	return testService{personService: s, people: people}
+}
+
+// failingPeople fails to create people with the surname failSurname and
+// counts the attempts.
+type failingPeople struct {
+	repository.PersonRepository
+	failSurname string
+
+	mu      sync.Mutex
+	creates int
+}
+
+func (r *failingPeople) Create(ctx context.Context, person domain.Person) (domain.Person, error) {
+	r.mu.Lock()
+	r.creates++
+	r.mu.Unlock()
+
+	if person.Surname == r.failSurname {
+		return domain.Person{}, fmt.Errorf("cannot store %s", person.Surname)
+	}
+	return r.PersonRepository.Create(ctx, person)
+}
+
+func (r *failingPeople) createCalls() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.creates
+}
+
+type fakeAudit struct {
+	repository.AuditRepository
+}
+
+func (fakeAudit) Create(context.Context, domain.AuditEntry) error { return nil }
+
+type fakeOutbox struct {
+	repository.OutboxRepository
+}
+
+func (fakeOutbox) Create(context.Context, domain.Event) error { return nil }
+
+type fakeEnricher struct{}
+
+func (fakeEnricher) GetAge(context.Context, string) (int, error)            { return 30, nil }
+func (fakeEnricher) GetGender(context.Context, string) (string, error)      { return "male", nil }
+func (fakeEnricher) GetNationality(context.Context, string) (string, error) { return "RU", nil }
+func (fakeEnricher) Status() client.ProviderStatus                          { return client.ProviderStatus{} }
+func (fakeEnricher) Ping(context.Context) error                             { return nil }
+
+// errConflict stands for a serialization failure on commit.
+var errConflict = errors.New("could not serialize access")
+
+// flakyTransactor wraps a transactor whose outermost transactions, when
+// they succeed, either fail to commit once and run again, as on a conflict,
+// or commit and report that the commit was lost. It is used by one goroutine
+// at a time.
+type flakyTransactor struct {
+	repository.Transactor
+	conflict bool
+	lost     bool
+
+	depth int
+}
+
+func (t *flakyTransactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	t.depth++
+	defer func() { t.depth-- }()
+	if t.depth > 1 {
+		return t.Transactor.WithinTx(ctx, fn)
+	}
+
+	if t.conflict {
+		t.conflict = false
+		err := t.Transactor.WithinTx(ctx, func(ctx context.Context) error {
+			if err := fn(ctx); err != nil {
+				return err
+			}
+			return errConflict
+		})
+		if !errors.Is(err, errConflict) {
+			return err
+		}
+	}
+
+	if err := t.Transactor.WithinTx(ctx, fn); err != nil {
+		return err
+	}
+	if t.lost {
+		return fmt.Errorf("%w: connection reset", repository.ErrCommitUnknown)
+	}
+	return nil
+}
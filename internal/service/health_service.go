@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"github.com/RakhimovAns/Person-Service/internal/config"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/internal/repository"
+	"github.com/RakhimovAns/Person-Service/pkg/client"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"sync"
+	"time"
+)
+
+type HealthService interface {
+	// Ready checks the database, the migrations and the enrichment providers
+	// within the configured timeout. The database and the migrations must be
+	// up for the service to be ready; degraded providers only make it not
+	// ready when the configuration says so.
+	Ready(ctx context.Context) domain.Readiness
+}
+
+type healthService struct {
+	repo      repository.HealthRepository
+	migrator  *repository.Migrator
+	providers []client.Provider
+	cfg       config.HealthConfig
+	logger    logging.Logger
+}
+
+func NewHealthService(repo repository.HealthRepository, migrator *repository.Migrator, providers []client.Provider, cfg config.HealthConfig, logger logging.Logger) HealthService {
+	return &healthService{
+		repo:      repo,
+		migrator:  migrator,
+		providers: providers,
+		cfg:       cfg,
+		logger:    logger,
+	}
+}
+
+func (s *healthService) Ready(ctx context.Context) domain.Readiness {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	readiness := domain.Readiness{
+		Providers: make([]domain.ProviderCheck, len(s.providers)),
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2 + len(s.providers))
+	go func() {
+		defer wg.Done()
+		readiness.Database = s.checkDatabase(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		readiness.Migrations = s.checkMigrations(ctx)
+	}()
+	for i, provider := range s.providers {
+		go func(i int, provider client.Provider) {
+			defer wg.Done()
+			readiness.Providers[i] = checkProvider(ctx, provider)
+		}(i, provider)
+	}
+	wg.Wait()
+
+	readiness.Status = domain.ReadinessReady
+	for _, provider := range readiness.Providers {
+		if provider.Status != domain.HealthStatusUp {
+			readiness.Status = domain.ReadinessDegraded
+			if s.cfg.FailOnDegradedProvider {
+				readiness.Status = domain.ReadinessNotReady
+			}
+		}
+	}
+	if readiness.Database.Status != domain.HealthStatusUp || readiness.Migrations.Status != domain.HealthStatusUp {
+		readiness.Status = domain.ReadinessNotReady
+	}
+
+	if readiness.Status == domain.ReadinessNotReady {
+		logging.FromContext(ctx, s.logger).Warn("Service is not ready: database %s, migrations %s", readiness.Database.Status, readiness.Migrations.Status)
+	}
+	return readiness
+}
+
+func (s *healthService) checkDatabase(ctx context.Context) domain.DatabaseCheck {
+	start := time.Now()
+	err := s.repo.Ping(ctx)
+	check := domain.DatabaseCheck{
+		Status:    domain.HealthStatusUp,
+		LatencyMS: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		check.Status = domain.HealthStatusDown
+		check.Error = err.Error()
+	}
+	return check
+}
+
+// checkMigrations reports the migrations down while any migration is pending,
+// since the code expects the latest schema.
+func (s *healthService) checkMigrations(ctx context.Context) domain.MigrationsCheck {
+	statuses, err := s.migrator.Status(ctx)
+	if err != nil {
+		return domain.MigrationsCheck{
+			Status: domain.HealthStatusDown,
+			Error:  err.Error(),
+		}
+	}
+
+	check := domain.MigrationsCheck{Status: domain.HealthStatusUp}
+	for _, status := range statuses {
+		if status.Applied {
+			check.Applied++
+		} else {
+			check.Pending++
+		}
+	}
+	if check.Pending > 0 {
+		check.Status = domain.HealthStatusDown
+	}
+	return check
+}
+
+// checkProvider reports a provider degraded when it cannot be reached, its
+// circuit breaker is not closed or its quota is used up.
+func checkProvider(ctx context.Context, provider client.Provider) domain.ProviderCheck {
+	status := provider.Status()
+	check := domain.ProviderCheck{
+		Name:           status.Name,
+		Status:         domain.HealthStatusUp,
+		Reachable:      true,
+		CircuitBreaker: status.CircuitBreaker,
+		LastError:      status.LastError,
+		LastErrorAt:    status.LastErrorAt,
+	}
+	if status.Quota != nil {
+		check.Quota = &domain.ProviderQuota{
+			Limit:     status.Quota.Limit,
+			Remaining: status.Quota.Remaining,
+			ResetAt:   status.Quota.ResetAt,
+		}
+	}
+
+	if err := provider.Ping(ctx); err != nil {
+		check.Reachable = false
+		check.Error = err.Error()
+	}
+
+	quotaExhausted := check.Quota != nil && check.Quota.Remaining <= 0 && time.Now().Before(check.Quota.ResetAt)
+	if !check.Reachable || check.CircuitBreaker != client.BreakerClosed || quotaExhausted {
+		check.Status = domain.HealthStatusDegraded
+	}
+	return check
+}
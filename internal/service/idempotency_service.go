@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/internal/repository"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"time"
+)
+
+type IdempotencyService interface {
+	// Begin reserves key for a request with the given hash. It returns the
+	// stored record when the request has already completed. Otherwise the
+	// caller should process the request and then Complete or Release the key
+	// with the token returned, which fail to apply once the reservation has
+	// been taken over by another request.
+	Begin(ctx context.Context, key, requestHash string) (record *domain.IdempotencyRecord, token string, err error)
+	Complete(ctx context.Context, key, token string, statusCode int, headers map[string]string, body []byte) error
+	Release(ctx context.Context, key, token string) error
+}
+
+type idempotencyService struct {
+	repo        repository.IdempotencyRepository
+	ttl         time.Duration
+	lockTimeout time.Duration
+	logger      logging.Logger
+}
+
+func NewIdempotencyService(repo repository.IdempotencyRepository, ttl, lockTimeout time.Duration, logger logging.Logger) IdempotencyService {
+	return &idempotencyService{
+		repo:        repo,
+		ttl:         ttl,
+		lockTimeout: lockTimeout,
+		logger:      logger,
+	}
+}
+
+func (s *idempotencyService) Begin(ctx context.Context, key, requestHash string) (*domain.IdempotencyRecord, string, error) {
+	token := randomToken()
+	record, reserved, err := s.repo.Reserve(ctx, key, requestHash, token, s.ttl, s.lockTimeout)
+	if err != nil {
+		return nil, "", err
+	}
+	if reserved {
+		return nil, token, nil
+	}
+
+	if record.RequestHash != requestHash {
+		return nil, "", domain.ErrIdempotencyMismatch
+	}
+	if record.StatusCode == nil {
+		return nil, "", domain.ErrIdempotencyInProgress
+	}
+
+	s.logger.Debug("Replaying response for idempotency key %s", key)
+	return &record, "", nil
+}
+
+func (s *idempotencyService) Complete(ctx context.Context, key, token string, statusCode int, headers map[string]string, body []byte) error {
+	return s.repo.Complete(ctx, key, token, statusCode, headers, body)
+}
+
+func (s *idempotencyService) Release(ctx context.Context, key, token string) error {
+	return s.repo.Release(ctx, key, token)
+}
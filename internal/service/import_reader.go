@@ -0,0 +1,147 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"io"
+	"strings"
+)
+
+type importRecord struct {
+	Line  int
+	Raw   string
+	Input domain.PersonInput
+	Err   error
+}
+
+// readImport calls fn for every record of the payload in order. Records
+// that cannot be parsed are passed with Err set instead of being skipped,
+// so that record positions stay stable across runs of the same job.
+func readImport(format string, payload []byte, fn func(record importRecord) error) error {
+	switch format {
+	case domain.ImportFormatCSV:
+		return readCSV(payload, fn)
+	case domain.ImportFormatJSONL:
+		return readJSONL(payload, fn)
+	default:
+		return fmt.Errorf("%w: unsupported format %q", domain.ErrInvalidImport, format)
+	}
+}
+
+func readCSV(payload []byte, fn func(record importRecord) error) error {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(payload, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	columns := map[string]int{"name": 0, "surname": 1, "patronymic": 2}
+	first := true
+
+	for {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			if err := fn(importRecord{Line: parseErr.StartLine, Err: err}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		if first {
+			first = false
+			if header, ok := csvHeader(fields); ok {
+				columns = header
+				continue
+			}
+		}
+
+		record := importRecord{Line: line, Raw: encodeCSV(fields)}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(fields) {
+				return strings.TrimSpace(fields[i])
+			}
+			return ""
+		}
+
+		record.Input = domain.PersonInput{Name: field("name"), Surname: field("surname")}
+		if patronymic := field("patronymic"); patronymic != "" {
+			record.Input.Patronymic = &patronymic
+		}
+		record.Err = validateImportInput(record.Input)
+
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+}
+
+// csvHeader recognises a header row naming at least the name and surname
+// columns and returns the position of each known column.
+func csvHeader(fields []string) (map[string]int, bool) {
+	columns := map[string]int{}
+	for i, field := range fields {
+		switch name := strings.ToLower(strings.TrimSpace(field)); name {
+		case "name", "surname", "patronymic":
+			columns[name] = i
+		}
+	}
+
+	_, hasName := columns["name"]
+	_, hasSurname := columns["surname"]
+	return columns, hasName && hasSurname
+}
+
+func encodeCSV(fields []string) string {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	_ = w.Write(fields)
+	w.Flush()
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func readJSONL(payload []byte, fn func(record importRecord) error) error {
+	scanner := bufio.NewScanner(bytes.NewReader(payload))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := strings.TrimSpace(scanner.Text())
+		if raw == "" {
+			continue
+		}
+
+		record := importRecord{Line: line, Raw: raw}
+		if err := json.Unmarshal([]byte(raw), &record.Input); err != nil {
+			record.Err = fmt.Errorf("invalid JSON: %w", err)
+		} else {
+			record.Err = validateImportInput(record.Input)
+		}
+
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+func validateImportInput(input domain.PersonInput) error {
+	if strings.TrimSpace(input.Name) == "" || strings.TrimSpace(input.Surname) == "" {
+		return errors.New("name and surname are required")
+	}
+	return nil
+}
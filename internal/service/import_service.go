@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/internal/repository"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/RakhimovAns/Person-Service/pkg/requestctx"
+)
+
+type ImportService interface {
+	Create(ctx context.Context, format, filename string, payload []byte) (domain.ImportJob, error)
+	GetByID(ctx context.Context, id int64) (domain.ImportJob, error)
+	Errors(ctx context.Context, id int64) ([]domain.ImportError, error)
+}
+
+type importService struct {
+	repo   repository.ImportRepository
+	logger logging.Logger
+}
+
+func NewImportService(repo repository.ImportRepository, logger logging.Logger) ImportService {
+	return &importService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+func (s *importService) Create(ctx context.Context, format, filename string, payload []byte) (domain.ImportJob, error) {
+	total := 0
+	err := readImport(format, payload, func(importRecord) error {
+		total++
+		return nil
+	})
+	if err != nil {
+		return domain.ImportJob{}, fmt.Errorf("%w: %v", domain.ErrInvalidImport, err)
+	}
+	if total == 0 {
+		return domain.ImportJob{}, fmt.Errorf("%w: no records", domain.ErrInvalidImport)
+	}
+
+	job, err := s.repo.Create(ctx, domain.ImportJob{
+		Format:   format,
+		Filename: filename,
+		Actor:    requestctx.Actor(ctx),
+		Total:    total,
+	}, payload)
+	if err != nil {
+		return domain.ImportJob{}, err
+	}
+
+	s.logger.Info("Created import job %d with %d records", job.ID, job.Total)
+	return job, nil
+}
+
+func (s *importService) GetByID(ctx context.Context, id int64) (domain.ImportJob, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *importService) Errors(ctx context.Context, id int64) ([]domain.ImportError, error) {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetErrors(ctx, id)
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/RakhimovAns/Person-Service/internal/config"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/internal/repository"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/RakhimovAns/Person-Service/pkg/requestctx"
+	"time"
+)
+
+// errImportPaused marks errors that stop a job for now without saying
+// anything about its payload.
+var errImportPaused = errors.New("import job paused")
+
+// ImportWorker processes import jobs in the background. Records are enriched
+// first; progress is then stored together with the created person in a short
+// transaction, so a job that was interrupted resumes where it stopped once
+// its lease expires. The lease is renewed while the job runs, and every
+// write checks that it is still held. Only records that are invalid or
+// rejected as duplicates are recorded as failures; any other error, such as
+// an enrichment provider being down, pauses the job until its lease expires
+// and it resumes from the same record.
+type ImportWorker struct {
+	repo          repository.ImportRepository
+	personService PersonService
+	transactor    repository.Transactor
+	cfg           config.ImportConfig
+	logger        logging.Logger
+}
+
+func NewImportWorker(
+	repo repository.ImportRepository,
+	personService PersonService,
+	transactor repository.Transactor,
+	cfg config.ImportConfig,
+	logger logging.Logger,
+) *ImportWorker {
+	return &ImportWorker{
+		repo:          repo,
+		personService: personService,
+		transactor:    transactor,
+		cfg:           cfg,
+		logger:        logger,
+	}
+}
+
+func (w *ImportWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for w.processNext(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// processNext claims and processes one job. It reports whether a job was
+// found so that queued jobs are drained without waiting for the next tick.
+func (w *ImportWorker) processNext(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	owner := randomToken()
+	job, err := w.repo.Claim(ctx, owner, w.cfg.Lease)
+	if errors.Is(err, domain.ErrImportNotFound) {
+		return false
+	}
+	if err != nil {
+		w.logger.Error("Failed to claim import job: %v", err)
+		return false
+	}
+
+	w.logger.Info("Processing import job %d (%d/%d records done)", job.ID, job.Processed, job.Total)
+
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	renewing := make(chan struct{})
+	go func() {
+		defer close(renewing)
+		w.renewLease(jobCtx, cancel, job.ID, owner)
+	}()
+	err = w.process(jobCtx, job, owner)
+	if cause := context.Cause(jobCtx); errors.Is(cause, domain.ErrImportLeaseLost) {
+		err = cause
+	}
+	cancel(nil)
+	<-renewing
+
+	if errors.Is(err, domain.ErrImportLeaseLost) {
+		w.logger.Warn("Import job %d was claimed by another worker, leaving it", job.ID)
+		return true
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			w.logger.Info("Import job %d interrupted, it will be resumed", job.ID)
+			return false
+		}
+		if errors.Is(err, errImportPaused) {
+			w.logger.Warn("Import job %d paused, it will be resumed once its lease expires: %v", job.ID, err)
+			return true
+		}
+
+		w.logger.Error("Import job %d failed: %v", job.ID, err)
+		message := err.Error()
+		if err := w.repo.Finish(ctx, job.ID, owner, domain.ImportStatusFailed, &message); err != nil {
+			w.logger.Error("Failed to mark import job %d as failed: %v", job.ID, err)
+		}
+		return true
+	}
+
+	if err := w.repo.Finish(ctx, job.ID, owner, domain.ImportStatusCompleted, nil); err != nil {
+		w.logger.Error("Failed to mark import job %d as completed: %v", job.ID, err)
+		return true
+	}
+
+	w.logger.Info("Import job %d completed", job.ID)
+	return true
+}
+
+// renewLease extends the lease on a job every third of the lease until ctx
+// is done, so that slow records do not let it expire. It cancels ctx with
+// domain.ErrImportLeaseLost when another worker has claimed the job.
+func (w *ImportWorker) renewLease(ctx context.Context, cancel context.CancelCauseFunc, id int64, owner string) {
+	interval := w.cfg.Lease / 3
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := w.repo.RenewLease(ctx, id, owner, w.cfg.Lease)
+		if errors.Is(err, domain.ErrImportLeaseLost) {
+			cancel(err)
+			return
+		}
+		if err != nil && ctx.Err() == nil {
+			w.logger.Warn("Failed to renew lease of import job %d: %v", id, err)
+		}
+	}
+}
+
+func (w *ImportWorker) process(ctx context.Context, job domain.ImportJob, owner string) error {
+	payload, err := w.repo.GetPayload(ctx, job.ID)
+	if err != nil {
+		return pause(err)
+	}
+
+	ctx = requestctx.WithActor(ctx, job.Actor)
+	ctx = requestctx.WithSource(ctx, requestctx.SourceImport)
+	ctx = requestctx.WithRequestID(ctx, fmt.Sprintf("import-%d", job.ID))
+	ctx = logging.NewContext(ctx, w.logger.With("request_id", requestctx.RequestID(ctx)))
+
+	position := 0
+	return readImport(job.Format, payload, func(record importRecord) error {
+		position++
+		if position <= job.Processed {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if record.Err == nil {
+			person, err := w.personService.PrepareCreate(ctx, record.Input)
+			if err == nil {
+				err = w.store(ctx, job.ID, owner, person)
+			}
+
+			var duplicate *domain.DuplicateError
+			switch {
+			case err == nil:
+				return nil
+			case errors.Is(err, domain.ErrImportLeaseLost) || ctx.Err() != nil:
+				return err
+			case !errors.As(err, &duplicate):
+				return pause(fmt.Errorf("line %d: %w", record.Line, err))
+			case duplicate.Policy == domain.DuplicatePolicyExisting:
+				return pause(w.repo.RecordSkipped(ctx, job.ID, owner, w.cfg.Lease))
+			}
+			record.Err = err
+		}
+
+		return pause(w.repo.RecordFailure(ctx, job.ID, owner, w.cfg.Lease, domain.ImportError{
+			Line:  record.Line,
+			Raw:   record.Raw,
+			Error: record.Err.Error(),
+		}))
+	})
+}
+
+// store saves a person prepared outside of any transaction together with the
+// job progress. The progress is written first, so the lease is checked and
+// the job row locked before the person is stored.
+func (w *ImportWorker) store(ctx context.Context, jobID int64, owner string, person domain.Person) error {
+	return w.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := w.repo.RecordSuccess(ctx, jobID, owner, w.cfg.Lease); err != nil {
+			return err
+		}
+
+		_, err := w.personService.CreatePrepared(ctx, person)
+		return err
+	})
+}
+
+// pause wraps err, if any, in errImportPaused.
+func pause(err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", errImportPaused, err)
+}
+
+// randomToken returns a random ID telling a claim, such as the lease on a
+// job or the reservation of an idempotency key, apart from any other.
+func randomToken() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
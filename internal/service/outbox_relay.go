@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/RakhimovAns/Person-Service/internal/config"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/internal/repository"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/RakhimovAns/Person-Service/pkg/sink"
+	"time"
+)
+
+// OutboxRelay publishes events from the outbox to a sink. Delivery is at
+// least once: events are leased, published outside of any transaction and
+// marked published only after the sink accepted them, and a failed event is
+// retried with exponential backoff.
+type OutboxRelay struct {
+	repo   repository.OutboxRepository
+	sink   sink.Sink
+	cfg    config.OutboxConfig
+	logger logging.Logger
+}
+
+func NewOutboxRelay(
+	repo repository.OutboxRepository,
+	sink sink.Sink,
+	cfg config.OutboxConfig,
+	logger logging.Logger,
+) *OutboxRelay {
+	return &OutboxRelay{
+		repo:   repo,
+		sink:   sink,
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for r.relay(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// relay publishes one batch of due events in order. It stops at the first
+// failure so that later events of the batch are not published ahead of it,
+// and reports whether a full batch was published.
+func (r *OutboxRelay) relay(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	events, err := r.repo.Claim(ctx, r.cfg.BatchSize, r.cfg.Lease)
+	if err != nil {
+		r.logger.Error("Failed to claim outbox events: %v", err)
+		return false
+	}
+
+	for i, event := range events {
+		if err := r.publish(ctx, event); err != nil {
+			retryIn := r.backoff(event.Attempts)
+			r.logger.Warn("Failed to publish event %d, retrying in %s: %v", event.ID, retryIn, err)
+			if err := r.repo.MarkFailed(ctx, event.ID, err.Error(), retryIn); err != nil {
+				r.logger.Error("Failed to record failure of event %d: %v", event.ID, err)
+			}
+			r.release(ctx, events[i+1:])
+			return false
+		}
+
+		if err := r.repo.MarkPublished(ctx, event.ID); err != nil {
+			r.logger.Error("Failed to mark event %d as published: %v", event.ID, err)
+			r.release(ctx, events[i+1:])
+			return false
+		}
+	}
+
+	return len(events) > 0 && len(events) == r.cfg.BatchSize
+}
+
+func (r *OutboxRelay) publish(ctx context.Context, event domain.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return r.sink.Publish(ctx, sink.Message{ID: event.ID, Type: event.Type, Data: data})
+}
+
+// release gives back the events of a batch that were claimed but not
+// published, so they need not wait for their lease to run out.
+func (r *OutboxRelay) release(ctx context.Context, events []domain.Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	ids := make([]int64, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+	if err := r.repo.Release(ctx, ids); err != nil {
+		r.logger.Error("Failed to release outbox events: %v", err)
+	}
+}
+
+func (r *OutboxRelay) backoff(attempts int) time.Duration {
+	delay := r.cfg.PollInterval
+	for i := 0; i < attempts && delay < r.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > r.cfg.MaxBackoff {
+		delay = r.cfg.MaxBackoff
+	}
+	return delay
+}
@@ -2,137 +2,412 @@ package service
 
 import (
 	"context"
+	"errors"
+	"github.com/RakhimovAns/Person-Service/internal/config"
 	"github.com/RakhimovAns/Person-Service/internal/domain"
 	"github.com/RakhimovAns/Person-Service/internal/repository"
 	"github.com/RakhimovAns/Person-Service/pkg/client"
 	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/RakhimovAns/Person-Service/pkg/metrics"
+	"slices"
+	"time"
 )
 
 type PersonService interface {
-	Create(person domain.PersonInput) (domain.Person, error)
-	GetAll(filter domain.PersonFilter, page, limit int) ([]domain.Person, error)
-	GetByID(id int) (domain.Person, error)
-	Update(id int, person domain.PersonInput) (domain.Person, error)
-	Delete(id int) error
+	Create(ctx context.Context, person domain.PersonInput) (domain.Person, error)
+	// PrepareCreate runs the duplicate check and enrichment of Create without
+	// storing anything, and CreatePrepared stores its result. Callers that
+	// store people in their own transaction use them to keep provider calls
+	// out of it.
+	PrepareCreate(ctx context.Context, person domain.PersonInput) (domain.Person, error)
+	CreatePrepared(ctx context.Context, person domain.Person) (domain.Person, error)
+	GetAll(ctx context.Context, filter domain.PersonFilter, page, limit int) ([]domain.Person, error)
+	Export(ctx context.Context, filter domain.PersonFilter, fn func(person domain.Person) error) error
+	GetByID(ctx context.Context, id int) (domain.Person, error)
+	// Update, Patch, Delete and Merge fail with domain.ErrVersionMismatch
+	// unless the person (the target, for Merge) is at one of the given
+	// versions; none skips the check.
+	Update(ctx context.Context, id int, person domain.PersonInput, versions []int) (domain.Person, error)
+	Patch(ctx context.Context, id int, patch domain.PersonPatch, versions []int) (domain.Person, error)
+	Delete(ctx context.Context, id int, versions []int) error
+	Merge(ctx context.Context, targetID, sourceID int, versions []int) (domain.Person, error)
+	Restore(ctx context.Context, id int) (domain.Person, error)
+	Enrich(ctx context.Context, id int) (domain.Person, error)
+	History(ctx context.Context, id int) ([]domain.AuditEntry, error)
+	// HistoryBatch returns the history of several people at once, keyed by
+	// person ID.
+	HistoryBatch(ctx context.Context, ids []int) (map[int][]domain.AuditEntry, error)
+	CreateBatch(ctx context.Context, inputs []domain.PersonInput, mode string) (domain.BatchResult, error)
+	// Watch streams events about people matching filter, starting after the
+	// event with ID lastEventID, or with new events when it is zero. The
+	// channel is closed when ctx is done or the watcher falls too far behind.
+	Watch(ctx context.Context, filter domain.PersonFilter, lastEventID int64) <-chan domain.Event
 }
 
 type personService struct {
 	repo              repository.PersonRepository
+	auditRepo         repository.AuditRepository
+	outboxRepo        repository.OutboxRepository
+	transactor        repository.Transactor
+	broker            *EventBroker
 	agifyClient       client.AgifyClient
 	genderizeClient   client.GenderizeClient
 	nationalizeClient client.NationalizeClient
+	duplicates        config.DuplicateConfig
+	batch             config.BatchConfig
 	logger            logging.Logger
 }
 
 func NewPersonService(
 	repo repository.PersonRepository,
+	auditRepo repository.AuditRepository,
+	outboxRepo repository.OutboxRepository,
+	transactor repository.Transactor,
+	broker *EventBroker,
 	agifyClient client.AgifyClient,
 	genderizeClient client.GenderizeClient,
 	nationalizeClient client.NationalizeClient,
+	duplicates config.DuplicateConfig,
+	batch config.BatchConfig,
 	logger logging.Logger,
 ) PersonService {
 	return &personService{
 		repo:              repo,
+		auditRepo:         auditRepo,
+		outboxRepo:        outboxRepo,
+		transactor:        transactor,
+		broker:            broker,
 		agifyClient:       agifyClient,
 		genderizeClient:   genderizeClient,
 		nationalizeClient: nationalizeClient,
+		duplicates:        duplicates,
+		batch:             batch,
 		logger:            logger,
 	}
 }
 
-func (s *personService) Create(input domain.PersonInput) (domain.Person, error) {
-	ctx := context.Background()
+func (s *personService) Create(ctx context.Context, input domain.PersonInput) (domain.Person, error) {
+	person, err := s.PrepareCreate(ctx, input)
+	if err != nil {
+		return person, err
+	}
+
+	return s.insert(ctx, person)
+}
 
-	age, err := s.agifyClient.GetAge(input.Name)
+func (s *personService) PrepareCreate(ctx context.Context, input domain.PersonInput) (domain.Person, error) {
+	duplicate, err := s.findDuplicate(ctx, input)
 	if err != nil {
-		s.logger.Error("Failed to get age: %v", err)
 		return domain.Person{}, err
 	}
+	if duplicate != nil {
+		switch duplicate.Policy {
+		case domain.DuplicatePolicyReject:
+			return domain.Person{}, duplicate
+		case domain.DuplicatePolicyExisting:
+			return duplicate.Existing, duplicate
+		default:
+			s.log(ctx).Warn("Creating possible duplicate of person %d (similarity %.2f)", duplicate.Existing.ID, duplicate.Similarity)
+		}
+	}
 
-	gender, err := s.genderizeClient.GetGender(input.Name)
-	if err != nil {
-		s.logger.Error("Failed to get gender: %v", err)
+	person := domain.Person{
+		Name:       input.Name,
+		Surname:    input.Surname,
+		Patronymic: input.Patronymic,
+	}
+
+	if err := s.enrich(ctx, &person); err != nil {
 		return domain.Person{}, err
 	}
 
-	nationality, err := s.nationalizeClient.GetNationality(input.Name)
+	return person, nil
+}
+
+func (s *personService) CreatePrepared(ctx context.Context, person domain.Person) (domain.Person, error) {
+	return s.insert(ctx, person)
+}
+
+func (s *personService) GetAll(ctx context.Context, filter domain.PersonFilter, page, limit int) ([]domain.Person, error) {
+	return s.repo.GetAll(ctx, filter, page, limit)
+}
+
+func (s *personService) Export(ctx context.Context, filter domain.PersonFilter, fn func(person domain.Person) error) error {
+	return s.repo.Export(ctx, filter, fn)
+}
+
+func (s *personService) GetByID(ctx context.Context, id int) (domain.Person, error) {
+	person, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, domain.ErrNotFound) {
+		targetID, mergeErr := s.repo.GetMergeTarget(ctx, id)
+		if mergeErr == nil {
+			return domain.Person{}, &domain.MovedError{ID: targetID}
+		}
+	}
+
+	return person, err
+}
+
+func (s *personService) Update(ctx context.Context, id int, input domain.PersonInput, versions []int) (domain.Person, error) {
+	old, err := s.getForWrite(ctx, id, versions)
 	if err != nil {
-		s.logger.Error("Failed to get nationality: %v", err)
 		return domain.Person{}, err
 	}
 
 	person := domain.Person{
-		Name:        input.Name,
-		Surname:     input.Surname,
-		Patronymic:  input.Patronymic,
-		Age:         age,
-		Gender:      gender,
-		Nationality: nationality,
+		ID:         id,
+		Name:       input.Name,
+		Surname:    input.Surname,
+		Patronymic: input.Patronymic,
 	}
 
-	id, err := s.repo.Create(ctx, person)
-	if err != nil {
+	if err := s.enrich(ctx, &person); err != nil {
 		return domain.Person{}, err
 	}
 
-	person.ID = id
-	return person, nil
+	return s.save(ctx, domain.AuditActionUpdate, old, person)
 }
 
-func (s *personService) GetAll(filter domain.PersonFilter, page, limit int) ([]domain.Person, error) {
-	ctx := context.Background()
-	return s.repo.GetAll(ctx, filter, page, limit)
+func (s *personService) Patch(ctx context.Context, id int, patch domain.PersonPatch, versions []int) (domain.Person, error) {
+	old, err := s.getForWrite(ctx, id, versions)
+	if err != nil {
+		return domain.Person{}, err
+	}
+
+	person := old
+	if patch.Surname != nil {
+		person.Surname = *patch.Surname
+	}
+	if patch.Patronymic.Set {
+		person.Patronymic = patch.Patronymic.Value
+	}
+	if patch.Name != nil && *patch.Name != old.Name {
+		person.Name = *patch.Name
+		if err := s.enrich(ctx, &person); err != nil {
+			return domain.Person{}, err
+		}
+	}
+
+	return s.save(ctx, domain.AuditActionUpdate, old, person)
 }
 
-func (s *personService) GetByID(id int) (domain.Person, error) {
-	ctx := context.Background()
-	return s.repo.GetByID(ctx, id)
+func (s *personService) Delete(ctx context.Context, id int, versions []int) error {
+	old, err := s.getForWrite(ctx, id, versions)
+	if err != nil {
+		return err
+	}
+
+	return s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.Delete(ctx, id, old.Version); err != nil {
+			return err
+		}
+
+		return s.audit(ctx, domain.AuditActionDelete, id, &old, nil)
+	})
 }
 
-func (s *personService) Update(id int, input domain.PersonInput) (domain.Person, error) {
-	ctx := context.Background()
+func (s *personService) Merge(ctx context.Context, targetID, sourceID int, versions []int) (domain.Person, error) {
+	if targetID == sourceID {
+		return domain.Person{}, domain.ErrInvalidMerge
+	}
 
-	_, err := s.repo.GetByID(ctx, id)
+	target, err := s.getForWrite(ctx, targetID, versions)
 	if err != nil {
 		return domain.Person{}, err
 	}
 
-	age, err := s.agifyClient.GetAge(input.Name)
+	source, err := s.repo.GetByID(ctx, sourceID)
 	if err != nil {
-		s.logger.Error("Failed to get age: %v", err)
 		return domain.Person{}, err
 	}
 
-	gender, err := s.genderizeClient.GetGender(input.Name)
+	merged := target
+	if merged.Patronymic == nil {
+		merged.Patronymic = source.Patronymic
+	}
+	if merged.Nationality == "" {
+		merged.Nationality = source.Nationality
+	}
+	if merged.Gender == "" {
+		merged.Gender = source.Gender
+	}
+	if merged.Age == 0 {
+		merged.Age = source.Age
+	}
+
+	var result domain.Person
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.Merge(ctx, targetID, sourceID, merged, target.Version, source.Version); err != nil {
+			return err
+		}
+
+		var err error
+		result, err = s.repo.GetByID(ctx, targetID)
+		if err != nil {
+			return err
+		}
+		if err := s.audit(ctx, domain.AuditActionMerge, targetID, &target, &result); err != nil {
+			return err
+		}
+
+		return s.audit(ctx, domain.AuditActionMerge, sourceID, &source, nil)
+	})
 	if err != nil {
-		s.logger.Error("Failed to get gender: %v", err)
 		return domain.Person{}, err
 	}
 
-	nationality, err := s.nationalizeClient.GetNationality(input.Name)
+	s.log(ctx).With("person_id", targetID).Info("Merged person %d into %d", sourceID, targetID)
+	return result, nil
+}
+
+func (s *personService) Restore(ctx context.Context, id int) (domain.Person, error) {
+	var person domain.Person
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.Restore(ctx, id); err != nil {
+			return err
+		}
+
+		var err error
+		person, err = s.repo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return s.audit(ctx, domain.AuditActionRestore, id, nil, &person)
+	})
 	if err != nil {
-		s.logger.Error("Failed to get nationality: %v", err)
 		return domain.Person{}, err
 	}
 
-	person := domain.Person{
-		ID:          id,
-		Name:        input.Name,
-		Surname:     input.Surname,
-		Patronymic:  input.Patronymic,
-		Age:         age,
-		Gender:      gender,
-		Nationality: nationality,
+	return person, nil
+}
+
+func (s *personService) Enrich(ctx context.Context, id int) (domain.Person, error) {
+	old, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return domain.Person{}, err
 	}
 
-	if err := s.repo.Update(ctx, id, person); err != nil {
+	person := old
+	if err := s.enrich(ctx, &person); err != nil {
 		return domain.Person{}, err
 	}
 
+	return s.save(ctx, domain.AuditActionEnrich, old, person)
+}
+
+func (s *personService) History(ctx context.Context, id int) ([]domain.AuditEntry, error) {
+	return s.auditRepo.GetByPersonID(ctx, id)
+}
+
+func (s *personService) HistoryBatch(ctx context.Context, ids []int) (map[int][]domain.AuditEntry, error) {
+	return s.auditRepo.GetByPersonIDs(ctx, ids)
+}
+
+func (s *personService) log(ctx context.Context) logging.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
+
+// getForWrite loads the person about to be changed and rejects the write
+// early when the caller holds a stale version.
+func (s *personService) getForWrite(ctx context.Context, id int, versions []int) (domain.Person, error) {
+	person, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return domain.Person{}, err
+	}
+	if len(versions) > 0 && !slices.Contains(versions, person.Version) {
+		return domain.Person{}, domain.ErrVersionMismatch
+	}
 	return person, nil
 }
 
-func (s *personService) Delete(id int) error {
-	ctx := context.Background()
-	return s.repo.Delete(ctx, id)
+// save writes person over old, guarded by the version old was read at, and
+// records the change in the audit log.
+func (s *personService) save(ctx context.Context, action string, old, person domain.Person) (domain.Person, error) {
+	var updated domain.Person
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		updated, err = s.repo.Update(ctx, old.ID, person, old.Version)
+		if err != nil {
+			return err
+		}
+
+		return s.audit(ctx, action, old.ID, &old, &updated)
+	})
+	if err != nil {
+		return domain.Person{}, err
+	}
+
+	return updated, nil
+}
+
+func (s *personService) enrich(ctx context.Context, person *domain.Person) error {
+	logger := s.log(ctx)
+	if person.ID != 0 {
+		logger = logger.With("person_id", person.ID)
+	}
+
+	start := time.Now()
+	age, err := s.agifyClient.GetAge(ctx, person.Name)
+	metrics.ObserveEnrichment("agify", start, err)
+	if err != nil {
+		logger.Error("Failed to get age: %v", err)
+		return err
+	}
+
+	start = time.Now()
+	gender, err := s.genderizeClient.GetGender(ctx, person.Name)
+	metrics.ObserveEnrichment("genderize", start, err)
+	if err != nil {
+		logger.Error("Failed to get gender: %v", err)
+		return err
+	}
+
+	start = time.Now()
+	nationality, err := s.nationalizeClient.GetNationality(ctx, person.Name)
+	metrics.ObserveEnrichment("nationalize", start, err)
+	if err != nil {
+		logger.Error("Failed to get nationality: %v", err)
+		return err
+	}
+
+	person.Age = age
+	person.Gender = gender
+	person.Nationality = nationality
+	return nil
+}
+
+func (s *personService) findDuplicate(ctx context.Context, input domain.PersonInput) (*domain.DuplicateError, error) {
+	var best *domain.DuplicateError
+	afterID := 0
+	for {
+		candidates, err := s.repo.FindDuplicateCandidates(ctx, input.Name, input.Surname, afterID, duplicateCandidatesPage)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, candidate := range candidates {
+			score := similarity(input, candidate)
+			if score < s.duplicates.Threshold {
+				continue
+			}
+			if best == nil || score > best.Similarity {
+				best = &domain.DuplicateError{
+					Existing:   candidate,
+					Similarity: score,
+					Policy:     s.duplicates.Policy,
+				}
+			}
+		}
+
+		if len(candidates) < duplicateCandidatesPage {
+			break
+		}
+		afterID = candidates[len(candidates)-1].ID
+	}
+
+	if best != nil {
+		metrics.DuplicateDetected(best.Policy)
+	}
+	return best, nil
 }
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/internal/repository"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/RakhimovAns/Person-Service/pkg/requestctx"
+	"time"
+)
+
+// purgeActor is who hard deletes are attributed to in the audit log.
+const purgeActor = "purge-worker"
+
+// PurgeWorker hard-deletes people that have been soft-deleted for longer
+// than the retention period, along with expired idempotency keys and old
+// published outbox events. Every person purged gets an audit entry with
+// their last snapshot.
+type PurgeWorker struct {
+	repo            repository.PersonRepository
+	auditRepo       repository.AuditRepository
+	idempotencyRepo repository.IdempotencyRepository
+	outboxRepo      repository.OutboxRepository
+	transactor      repository.Transactor
+	retention       time.Duration
+	outboxRetention time.Duration
+	interval        time.Duration
+	logger          logging.Logger
+}
+
+func NewPurgeWorker(
+	repo repository.PersonRepository,
+	auditRepo repository.AuditRepository,
+	idempotencyRepo repository.IdempotencyRepository,
+	outboxRepo repository.OutboxRepository,
+	transactor repository.Transactor,
+	retention, outboxRetention, interval time.Duration,
+	logger logging.Logger,
+) *PurgeWorker {
+	return &PurgeWorker{
+		repo:            repo,
+		auditRepo:       auditRepo,
+		idempotencyRepo: idempotencyRepo,
+		outboxRepo:      outboxRepo,
+		transactor:      transactor,
+		retention:       retention,
+		outboxRetention: outboxRetention,
+		interval:        interval,
+		logger:          logger,
+	}
+}
+
+// Run purges on every interval until ctx is cancelled. A zero interval
+// disables the worker; a zero retention keeps deleted people, or published
+// events, forever.
+func (w *PurgeWorker) Run(ctx context.Context) {
+	if w.interval <= 0 {
+		w.logger.Info("Purge worker disabled")
+		return
+	}
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *PurgeWorker) purge(ctx context.Context) {
+	if w.retention > 0 {
+		purged, err := w.purgePeople(ctx)
+		if err != nil {
+			w.logger.Error("Failed to purge deleted people: %v", err)
+		} else if purged > 0 {
+			w.logger.Info("Purged %d deleted people", purged)
+		}
+	}
+
+	expired, err := w.idempotencyRepo.DeleteExpired(ctx)
+	if err != nil {
+		w.logger.Error("Failed to purge expired idempotency keys: %v", err)
+	} else if expired > 0 {
+		w.logger.Info("Purged %d expired idempotency keys", expired)
+	}
+
+	if w.outboxRetention > 0 {
+		published, err := w.outboxRepo.DeletePublished(ctx, w.outboxRetention)
+		if err != nil {
+			w.logger.Error("Failed to purge published outbox events: %v", err)
+		} else if published > 0 {
+			w.logger.Info("Purged %d published outbox events", published)
+		}
+	}
+}
+
+// purgePeople hard-deletes people past retention and records each in the
+// audit log in the same transaction.
+func (w *PurgeWorker) purgePeople(ctx context.Context) (int, error) {
+	ctx = requestctx.WithActor(ctx, purgeActor)
+	ctx = requestctx.WithSource(ctx, requestctx.SourceWorker)
+
+	var purged []domain.Person
+	err := w.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		purged, err = w.repo.Purge(ctx, time.Now().UTC().Add(-w.retention))
+		if err != nil {
+			return err
+		}
+
+		for _, person := range purged {
+			data, fields, err := snapshot(&person)
+			if err != nil {
+				return err
+			}
+
+			err = w.auditRepo.Create(ctx, domain.AuditEntry{
+				PersonID: person.ID,
+				Action:   domain.AuditActionPurge,
+				Actor:    requestctx.Actor(ctx),
+				Source:   requestctx.Source(ctx),
+				OldData:  data,
+				Diff:     diff(fields, nil),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(purged), nil
+}
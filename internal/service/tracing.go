@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/RakhimovAns/Person-Service/internal/service")
+
+// tracedPersonService wraps a PersonService with a span per call. Provider
+// calls and queries made by the wrapped service become its children.
+type tracedPersonService struct {
+	next PersonService
+}
+
+func NewTracedPersonService(next PersonService) PersonService {
+	return &tracedPersonService{next: next}
+}
+
+func (s *tracedPersonService) Create(ctx context.Context, person domain.PersonInput) (domain.Person, error) {
+	ctx, span := tracer.Start(ctx, "PersonService.Create")
+	created, err := s.next.Create(ctx, person)
+	endSpan(span, err, attribute.Int("person.id", created.ID))
+	return created, err
+}
+
+func (s *tracedPersonService) PrepareCreate(ctx context.Context, person domain.PersonInput) (domain.Person, error) {
+	ctx, span := tracer.Start(ctx, "PersonService.PrepareCreate")
+	prepared, err := s.next.PrepareCreate(ctx, person)
+	endSpan(span, err)
+	return prepared, err
+}
+
+func (s *tracedPersonService) CreatePrepared(ctx context.Context, person domain.Person) (domain.Person, error) {
+	ctx, span := tracer.Start(ctx, "PersonService.CreatePrepared")
+	created, err := s.next.CreatePrepared(ctx, person)
+	endSpan(span, err, attribute.Int("person.id", created.ID))
+	return created, err
+}
+
+func (s *tracedPersonService) GetAll(ctx context.Context, filter domain.PersonFilter, page, limit int) ([]domain.Person, error) {
+	ctx, span := tracer.Start(ctx, "PersonService.GetAll", trace.WithAttributes(
+		attribute.Int("page", page),
+		attribute.Int("limit", limit),
+	))
+	people, err := s.next.GetAll(ctx, filter, page, limit)
+	endSpan(span, err, attribute.Int("result.count", len(people)))
+	return people, err
+}
+
+func (s *tracedPersonService) Export(ctx context.Context, filter domain.PersonFilter, fn func(person domain.Person) error) error {
+	ctx, span := tracer.Start(ctx, "PersonService.Export")
+	count := 0
+	err := s.next.Export(ctx, filter, func(person domain.Person) error {
+		count++
+		return fn(person)
+	})
+	endSpan(span, err, attribute.Int("result.count", count))
+	return err
+}
+
+func (s *tracedPersonService) GetByID(ctx context.Context, id int) (domain.Person, error) {
+	ctx, span := tracer.Start(ctx, "PersonService.GetByID", personAttribute(id))
+	person, err := s.next.GetByID(ctx, id)
+	endSpan(span, err)
+	return person, err
+}
+
+func (s *tracedPersonService) Update(ctx context.Context, id int, person domain.PersonInput, versions []int) (domain.Person, error) {
+	ctx, span := tracer.Start(ctx, "PersonService.Update", personAttribute(id))
+	updated, err := s.next.Update(ctx, id, person, versions)
+	endSpan(span, err)
+	return updated, err
+}
+
+func (s *tracedPersonService) Patch(ctx context.Context, id int, patch domain.PersonPatch, versions []int) (domain.Person, error) {
+	ctx, span := tracer.Start(ctx, "PersonService.Patch", personAttribute(id))
+	patched, err := s.next.Patch(ctx, id, patch, versions)
+	endSpan(span, err)
+	return patched, err
+}
+
+func (s *tracedPersonService) Delete(ctx context.Context, id int, versions []int) error {
+	ctx, span := tracer.Start(ctx, "PersonService.Delete", personAttribute(id))
+	err := s.next.Delete(ctx, id, versions)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedPersonService) Merge(ctx context.Context, targetID, sourceID int, versions []int) (domain.Person, error) {
+	ctx, span := tracer.Start(ctx, "PersonService.Merge", trace.WithAttributes(
+		attribute.Int("person.id", targetID),
+		attribute.Int("merge.source_id", sourceID),
+	))
+	person, err := s.next.Merge(ctx, targetID, sourceID, versions)
+	endSpan(span, err)
+	return person, err
+}
+
+func (s *tracedPersonService) Restore(ctx context.Context, id int) (domain.Person, error) {
+	ctx, span := tracer.Start(ctx, "PersonService.Restore", personAttribute(id))
+	person, err := s.next.Restore(ctx, id)
+	endSpan(span, err)
+	return person, err
+}
+
+func (s *tracedPersonService) Enrich(ctx context.Context, id int) (domain.Person, error) {
+	ctx, span := tracer.Start(ctx, "PersonService.Enrich", personAttribute(id))
+	person, err := s.next.Enrich(ctx, id)
+	endSpan(span, err)
+	return person, err
+}
+
+func (s *tracedPersonService) History(ctx context.Context, id int) ([]domain.AuditEntry, error) {
+	ctx, span := tracer.Start(ctx, "PersonService.History", personAttribute(id))
+	entries, err := s.next.History(ctx, id)
+	endSpan(span, err, attribute.Int("result.count", len(entries)))
+	return entries, err
+}
+
+func (s *tracedPersonService) HistoryBatch(ctx context.Context, ids []int) (map[int][]domain.AuditEntry, error) {
+	ctx, span := tracer.Start(ctx, "PersonService.HistoryBatch", trace.WithAttributes(
+		attribute.IntSlice("person.ids", ids),
+	))
+	entries, err := s.next.HistoryBatch(ctx, ids)
+	endSpan(span, err)
+	return entries, err
+}
+
+func (s *tracedPersonService) CreateBatch(ctx context.Context, inputs []domain.PersonInput, mode string) (domain.BatchResult, error) {
+	ctx, span := tracer.Start(ctx, "PersonService.CreateBatch", trace.WithAttributes(
+		attribute.Int("batch.size", len(inputs)),
+		attribute.String("batch.mode", mode),
+	))
+	result, err := s.next.CreateBatch(ctx, inputs, mode)
+	endSpan(span, err)
+	return result, err
+}
+
+// Watch is traced up to the point the stream is set up; the events that
+// follow can span hours and are not part of it.
+func (s *tracedPersonService) Watch(ctx context.Context, filter domain.PersonFilter, lastEventID int64) <-chan domain.Event {
+	_, span := tracer.Start(ctx, "PersonService.Watch", trace.WithAttributes(
+		attribute.Int64("watch.last_event_id", lastEventID),
+	))
+	defer span.End()
+	return s.next.Watch(ctx, filter, lastEventID)
+}
+
+func personAttribute(id int) trace.SpanStartOption {
+	return trace.WithAttributes(attribute.Int("person.id", id))
+}
+
+// endSpan records err on span, if any, along with attrs and ends it.
+func endSpan(span trace.Span, err error, attrs ...attribute.KeyValue) {
+	span.SetAttributes(attrs...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+)
+
+func (s *personService) Watch(ctx context.Context, filter domain.PersonFilter, lastEventID int64) <-chan domain.Event {
+	backlog, events := s.broker.Subscribe(lastEventID)
+	out := make(chan domain.Event)
+
+	go func() {
+		defer close(out)
+		defer s.broker.Unsubscribe(events)
+
+		send := func(event domain.Event) bool {
+			if event.Person == nil || !filter.Matches(*event.Person) {
+				return true
+			}
+			select {
+			case out <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for _, event := range backlog {
+			if !send(event) {
+				return
+			}
+		}
+
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok || !send(event) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/RakhimovAns/Person-Service/internal/config"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/internal/repository"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/RakhimovAns/Person-Service/pkg/sink"
+	"github.com/RakhimovAns/Person-Service/pkg/webhook"
+	"net"
+	"net/url"
+)
+
+// WebhookService manages webhook subscriptions. It is also the sink through
+// which the outbox relay hands events over for delivery to subscribers.
+type WebhookService interface {
+	sink.Sink
+	Create(ctx context.Context, input domain.WebhookInput) (domain.WebhookSubscription, error)
+	GetAll(ctx context.Context) ([]domain.WebhookSubscription, error)
+	GetByID(ctx context.Context, id int64) (domain.WebhookSubscription, error)
+	Delete(ctx context.Context, id int64) error
+	Deliveries(ctx context.Context, id int64, limit int) ([]domain.WebhookDelivery, error)
+	Replay(ctx context.Context, id, deliveryID int64) (domain.WebhookDelivery, error)
+}
+
+type webhookService struct {
+	repo    repository.WebhookRepository
+	secrets *webhook.SecretBox
+	cfg     config.WebhookConfig
+	logger  logging.Logger
+}
+
+func NewWebhookService(
+	repo repository.WebhookRepository,
+	secrets *webhook.SecretBox,
+	cfg config.WebhookConfig,
+	logger logging.Logger,
+) WebhookService {
+	return &webhookService{
+		repo:    repo,
+		secrets: secrets,
+		cfg:     cfg,
+		logger:  logger,
+	}
+}
+
+// Create registers a subscription. Unless private targets are allowed, URLs
+// whose host resolves to an address that is not public are rejected; the
+// worker checks again when it connects. The secret is stored encrypted and
+// only returned when it was generated here.
+func (s *webhookService) Create(ctx context.Context, input domain.WebhookInput) (domain.WebhookSubscription, error) {
+	target, err := url.Parse(input.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return domain.WebhookSubscription{}, fmt.Errorf("%w: url must be an absolute http(s) URL", domain.ErrInvalidWebhook)
+	}
+	if !s.cfg.AllowPrivateTargets {
+		if err := webhook.CheckHost(ctx, net.DefaultResolver, target.Hostname()); err != nil {
+			return domain.WebhookSubscription{}, fmt.Errorf("%w: url host: %v", domain.ErrInvalidWebhook, err)
+		}
+	}
+
+	events, err := eventFilter(input.Events)
+	if err != nil {
+		return domain.WebhookSubscription{}, err
+	}
+
+	if s.secrets == nil {
+		return domain.WebhookSubscription{}, domain.ErrWebhooksDisabled
+	}
+
+	secret := input.Secret
+	if secret == "" {
+		if secret, err = newSecret(); err != nil {
+			return domain.WebhookSubscription{}, err
+		}
+	}
+
+	sealed, err := s.secrets.Seal(secret)
+	if err != nil {
+		return domain.WebhookSubscription{}, err
+	}
+
+	subscription, err := s.repo.CreateSubscription(ctx, domain.WebhookSubscription{
+		URL:    input.URL,
+		Events: events,
+		Secret: sealed,
+	})
+	if err != nil {
+		return domain.WebhookSubscription{}, err
+	}
+
+	subscription.Secret = ""
+	if input.Secret == "" {
+		subscription.Secret = secret
+	}
+
+	s.logger.Info("Created webhook %d for %s", subscription.ID, subscription.URL)
+	return subscription, nil
+}
+
+func (s *webhookService) GetAll(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	return s.repo.GetSubscriptions(ctx)
+}
+
+func (s *webhookService) GetByID(ctx context.Context, id int64) (domain.WebhookSubscription, error) {
+	return s.repo.GetSubscription(ctx, id)
+}
+
+func (s *webhookService) Delete(ctx context.Context, id int64) error {
+	return s.repo.DeleteSubscription(ctx, id)
+}
+
+func (s *webhookService) Deliveries(ctx context.Context, id int64, limit int) ([]domain.WebhookDelivery, error) {
+	return s.repo.GetDeliveries(ctx, id, limit)
+}
+
+func (s *webhookService) Replay(ctx context.Context, id, deliveryID int64) (domain.WebhookDelivery, error) {
+	return s.repo.Replay(ctx, id, deliveryID)
+}
+
+// Publish schedules deliveries of an event to the matching subscriptions.
+// Called by the outbox relay outside of any transaction; an event published
+// twice is enqueued once per subscription.
+func (s *webhookService) Publish(ctx context.Context, msg sink.Message) error {
+	enqueued, err := s.repo.Enqueue(ctx, msg.ID, msg.Type, msg.Data)
+	if err != nil {
+		return err
+	}
+	if enqueued > 0 {
+		s.logger.Debug("Scheduled %d webhook deliveries of event %d", enqueued, msg.ID)
+	}
+	return nil
+}
+
+// eventFilter validates and de-duplicates the event types of a subscription.
+func eventFilter(events []string) ([]string, error) {
+	known := make(map[string]bool, len(domain.EventTypes))
+	for _, eventType := range domain.EventTypes {
+		known[eventType] = true
+	}
+
+	filter := make([]string, 0, len(events))
+	seen := make(map[string]bool, len(events))
+	for _, eventType := range events {
+		if !known[eventType] {
+			return nil, fmt.Errorf("%w: unknown event type %q", domain.ErrInvalidWebhook, eventType)
+		}
+		if !seen[eventType] {
+			seen[eventType] = true
+			filter = append(filter, eventType)
+		}
+	}
+
+	return filter, nil
+}
+
+func newSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
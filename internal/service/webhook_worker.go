@@ -0,0 +1,180 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/RakhimovAns/Person-Service/internal/config"
+	"github.com/RakhimovAns/Person-Service/internal/domain"
+	"github.com/RakhimovAns/Person-Service/internal/repository"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/RakhimovAns/Person-Service/pkg/webhook"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// WebhookWorker sends scheduled webhook deliveries. Each request is signed
+// with the subscription secret; failed deliveries are retried with
+// exponential backoff until the attempts run out. Unless private targets are
+// allowed, connections to addresses that are not public are refused.
+type WebhookWorker struct {
+	repo    repository.WebhookRepository
+	secrets *webhook.SecretBox
+	client  *http.Client
+	cfg     config.WebhookConfig
+	logger  logging.Logger
+}
+
+func NewWebhookWorker(
+	repo repository.WebhookRepository,
+	secrets *webhook.SecretBox,
+	cfg config.WebhookConfig,
+	logger logging.Logger,
+) *WebhookWorker {
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivateTargets {
+		dialer.Control = webhook.DialControl
+	}
+
+	// Deliveries bypass proxies so that the address check applies to the
+	// subscriber itself.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &WebhookWorker{
+		repo:    repo,
+		secrets: secrets,
+		client:  &http.Client{Timeout: cfg.Timeout, Transport: transport},
+		cfg:     cfg,
+		logger:  logger,
+	}
+}
+
+func (w *WebhookWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for w.deliverDue(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverDue sends one batch of due deliveries and reports whether the batch
+// was full, meaning more may be waiting. Deliveries are claimed with a lease
+// and sent outside of any transaction, so a slow subscriber holds no locks
+// and a retried transaction never sends twice.
+func (w *WebhookWorker) deliverDue(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	dispatches, err := w.repo.Claim(ctx, w.cfg.BatchSize, w.cfg.Lease)
+	if err != nil {
+		w.logger.Error("Failed to claim webhook deliveries: %v", err)
+		return false
+	}
+
+	for _, dispatch := range dispatches {
+		if ctx.Err() != nil {
+			// The rest are sent once their lease runs out.
+			return false
+		}
+		if err := w.deliver(ctx, dispatch); err != nil {
+			w.logger.Error("Failed to record webhook delivery %d: %v", dispatch.Delivery.ID, err)
+		}
+	}
+
+	return len(dispatches) > 0 && len(dispatches) == w.cfg.BatchSize
+}
+
+// deliver sends a delivery and records the outcome. Only a failure to record
+// it is returned.
+func (w *WebhookWorker) deliver(ctx context.Context, dispatch domain.WebhookDispatch) error {
+	delivery := dispatch.Delivery
+
+	status, err := w.send(ctx, dispatch)
+	if err == nil {
+		w.logger.Debug("Delivered event %d to webhook %d", delivery.EventID, delivery.SubscriptionID)
+		return w.repo.MarkDelivered(ctx, delivery.ID, status)
+	}
+	if ctx.Err() != nil {
+		// Shutting down is not the subscriber's fault; the lease runs out
+		// and the delivery is sent again.
+		return nil
+	}
+
+	var responseStatus *int
+	if status != 0 {
+		responseStatus = &status
+	}
+
+	attempts := delivery.Attempts + 1
+	if attempts >= w.cfg.MaxAttempts {
+		w.logger.Warn("Giving up on delivery %d to webhook %d after %d attempts: %v",
+			delivery.ID, delivery.SubscriptionID, attempts, err)
+		return w.repo.MarkFailed(ctx, delivery.ID, responseStatus, err.Error(), 0)
+	}
+
+	retryIn := w.backoff(delivery.Attempts)
+	w.logger.Warn("Failed to deliver %d to webhook %d, retrying in %s: %v",
+		delivery.ID, delivery.SubscriptionID, retryIn, err)
+	return w.repo.MarkFailed(ctx, delivery.ID, responseStatus, err.Error(), retryIn)
+}
+
+// send posts the signed payload and returns the response status, if any.
+func (w *WebhookWorker) send(ctx context.Context, dispatch domain.WebhookDispatch) (int, error) {
+	delivery := dispatch.Delivery
+
+	secret, err := w.secrets.Open(dispatch.Secret)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dispatch.URL, bytes.NewReader(dispatch.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(webhook.EventIDHeader, strconv.FormatInt(delivery.EventID, 10))
+	req.Header.Set(webhook.EventTypeHeader, delivery.EventType)
+	req.Header.Set(webhook.TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(webhook.SignatureHeader, webhook.Sign(secret, timestamp, dispatch.Payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+func (w *WebhookWorker) backoff(attempts int) time.Duration {
+	delay := w.cfg.MinBackoff
+	for i := 0; i < attempts && delay < w.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > w.cfg.MaxBackoff {
+		delay = w.cfg.MaxBackoff
+	}
+	return delay
+}
@@ -1,11 +1,12 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
 	"io"
-	"net/http"
+	"time"
 )
 
 type AgifyResponse struct {
@@ -15,43 +16,46 @@ type AgifyResponse struct {
 }
 
 type AgifyClient interface {
-	GetAge(name string) (int, error)
+	Provider
+	GetAge(ctx context.Context, name string) (int, error)
 }
 
 type agifyClient struct {
-	baseURL string
-	logger  logging.Logger
+	*provider
+	logger logging.Logger
 }
 
-func NewAgifyClient(baseURL string, logger logging.Logger) AgifyClient {
+func NewAgifyClient(baseURL string, threshold int, cooldown time.Duration, propagate bool, logger logging.Logger) AgifyClient {
 	return &agifyClient{
-		baseURL: baseURL,
-		logger:  logger,
+		provider: newProvider("agify", baseURL, threshold, cooldown, propagate),
+		logger:   logger.With("provider", "agify"),
 	}
 }
 
-func (c *agifyClient) GetAge(name string) (int, error) {
+func (c *agifyClient) GetAge(ctx context.Context, name string) (int, error) {
 	url := fmt.Sprintf("%s/?name=%s", c.baseURL, name)
 
-	resp, err := http.Get(url)
+	logger := requestLogger(ctx, c.logger)
+
+	resp, err := c.get(ctx, url)
 	if err != nil {
-		c.logger.Error("Failed to make request to Agify API: %v", err)
+		logger.Error("Failed to make request to Agify API: %v", err)
 		return 0, err
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		c.logger.Error("Failed to read Agify API response: %v", err)
+		logger.Error("Failed to read Agify API response: %v", err)
 		return 0, err
 	}
 
 	var agifyResponse AgifyResponse
 	if err := json.Unmarshal(body, &agifyResponse); err != nil {
-		c.logger.Error("Failed to unmarshal Agify API response: %v", err)
+		logger.Error("Failed to unmarshal Agify API response: %v", err)
 		return 0, err
 	}
 
-	c.logger.Debug("Received age %d for name %s", agifyResponse.Age, name)
+	logger.Debug("Received age %d for name %s", agifyResponse.Age, name)
 	return agifyResponse.Age, nil
 }
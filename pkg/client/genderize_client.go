@@ -1,11 +1,12 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
 	"io"
-	"net/http"
+	"time"
 )
 
 type GenderizeResponse struct {
@@ -16,43 +17,46 @@ type GenderizeResponse struct {
 }
 
 type GenderizeClient interface {
-	GetGender(name string) (string, error)
+	Provider
+	GetGender(ctx context.Context, name string) (string, error)
 }
 
 type genderizeClient struct {
-	baseURL string
-	logger  logging.Logger
+	*provider
+	logger logging.Logger
 }
 
-func NewGenderizeClient(baseURL string, logger logging.Logger) GenderizeClient {
+func NewGenderizeClient(baseURL string, threshold int, cooldown time.Duration, propagate bool, logger logging.Logger) GenderizeClient {
 	return &genderizeClient{
-		baseURL: baseURL,
-		logger:  logger,
+		provider: newProvider("genderize", baseURL, threshold, cooldown, propagate),
+		logger:   logger.With("provider", "genderize"),
 	}
 }
 
-func (c *genderizeClient) GetGender(name string) (string, error) {
+func (c *genderizeClient) GetGender(ctx context.Context, name string) (string, error) {
 	url := fmt.Sprintf("%s/?name=%s", c.baseURL, name)
 
-	resp, err := http.Get(url)
+	logger := requestLogger(ctx, c.logger)
+
+	resp, err := c.get(ctx, url)
 	if err != nil {
-		c.logger.Error("Failed to make request to Genderize API: %v", err)
+		logger.Error("Failed to make request to Genderize API: %v", err)
 		return "", err
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		c.logger.Error("Failed to read Genderize API response: %v", err)
+		logger.Error("Failed to read Genderize API response: %v", err)
 		return "", err
 	}
 
 	var genderizeResponse GenderizeResponse
 	if err := json.Unmarshal(body, &genderizeResponse); err != nil {
-		c.logger.Error("Failed to unmarshal Genderize API response: %v", err)
+		logger.Error("Failed to unmarshal Genderize API response: %v", err)
 		return "", err
 	}
 
-	c.logger.Debug("Received gender %s for name %s", genderizeResponse.Gender, name)
+	logger.Debug("Received gender %s for name %s", genderizeResponse.Gender, name)
 	return genderizeResponse.Gender, nil
 }
@@ -0,0 +1,19 @@
+package logging
+
+import "context"
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying logger, typically a child logger
+// with request-scoped fields.
+func NewContext(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, or fallback if there is none.
+func FromContext(ctx context.Context, fallback Logger) Logger {
+	if logger, ok := ctx.Value(contextKey{}).(Logger); ok {
+		return logger
+	}
+	return fallback
+}
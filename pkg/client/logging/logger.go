@@ -1,52 +1,201 @@
 package logging
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"runtime"
+	"strings"
+	"time"
 )
 
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// LevelFatal is logged by Fatal, above slog's levels.
+const LevelFatal = slog.Level(12)
+
 type Logger interface {
 	Debug(format string, args ...interface{})
 	Info(format string, args ...interface{})
 	Warn(format string, args ...interface{})
 	Error(format string, args ...interface{})
 	Fatal(format string, args ...interface{})
+	// With returns a child logger that adds the given key-value pairs to
+	// every line. Children share their parent's level.
+	With(args ...interface{}) Logger
+	Level() string
+	SetLevel(level string) error
 }
 
 type logger struct {
-	debugLog *log.Logger
-	infoLog  *log.Logger
-	warnLog  *log.Logger
-	errorLog *log.Logger
-	fatalLog *log.Logger
+	logger *slog.Logger
+	level  *slog.LevelVar
 }
 
-func New(level string) Logger {
+// New creates a logger writing in the given format, text or json, that drops
+// lines below level. Error and fatal lines go to stderr, the others to
+// stdout. Unknown levels fall back to info.
+func New(level, format string) Logger {
+	return newLogger(os.Stdout, os.Stderr, level, format)
+}
+
+func newLogger(out, errOut io.Writer, level, format string) *logger {
+	levelVar := new(slog.LevelVar)
+	if parsed, err := ParseLevel(level); err == nil {
+		levelVar.Set(parsed)
+	}
+
+	options := &slog.HandlerOptions{
+		AddSource:   true,
+		Level:       levelVar,
+		ReplaceAttr: replaceAttr,
+	}
+
+	newHandler := func(w io.Writer) slog.Handler {
+		if strings.EqualFold(format, FormatJSON) {
+			return slog.NewJSONHandler(w, options)
+		}
+		return slog.NewTextHandler(w, options)
+	}
+
 	return &logger{
-		debugLog: log.New(os.Stdout, "DEBUG: ", log.Ldate|log.Ltime|log.Lshortfile),
-		infoLog:  log.New(os.Stdout, "INFO: ", log.Ldate|log.Ltime|log.Lshortfile),
-		warnLog:  log.New(os.Stdout, "WARN: ", log.Ldate|log.Ltime|log.Lshortfile),
-		errorLog: log.New(os.Stderr, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile),
-		fatalLog: log.New(os.Stderr, "FATAL: ", log.Ldate|log.Ltime|log.Lshortfile),
+		logger: slog.New(splitHandler{out: newHandler(out), errOut: newHandler(errOut)}),
+		level:  levelVar,
+	}
+}
+
+// splitHandler hands error and fatal records to errOut and the others to
+// out. Both share the same options, and so the same level.
+type splitHandler struct {
+	out    slog.Handler
+	errOut slog.Handler
+}
+
+func (h splitHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.out.Enabled(ctx, level)
+}
+
+func (h splitHandler) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level >= slog.LevelError {
+		return h.errOut.Handle(ctx, record)
+	}
+	return h.out.Handle(ctx, record)
+}
+
+func (h splitHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return splitHandler{out: h.out.WithAttrs(attrs), errOut: h.errOut.WithAttrs(attrs)}
+}
+
+func (h splitHandler) WithGroup(name string) slog.Handler {
+	return splitHandler{out: h.out.WithGroup(name), errOut: h.errOut.WithGroup(name)}
+}
+
+// ParseLevel parses debug, info, warn, error or fatal, in any case.
+func ParseLevel(level string) (slog.Level, error) {
+	if strings.EqualFold(level, "fatal") {
+		return LevelFatal, nil
+	}
+
+	var parsed slog.Level
+	if err := parsed.UnmarshalText([]byte(level)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q", level)
 	}
+	return parsed, nil
 }
 
 func (l *logger) Debug(format string, args ...interface{}) {
-	l.debugLog.Printf(format, args...)
+	l.log(slog.LevelDebug, format, args...)
 }
 
 func (l *logger) Info(format string, args ...interface{}) {
-	l.infoLog.Printf(format, args...)
+	l.log(slog.LevelInfo, format, args...)
 }
 
 func (l *logger) Warn(format string, args ...interface{}) {
-	l.warnLog.Printf(format, args...)
+	l.log(slog.LevelWarn, format, args...)
 }
 
 func (l *logger) Error(format string, args ...interface{}) {
-	l.errorLog.Printf(format, args...)
+	l.log(slog.LevelError, format, args...)
 }
 
 func (l *logger) Fatal(format string, args ...interface{}) {
-	l.fatalLog.Fatalf(format, args...)
+	l.log(LevelFatal, format, args...)
+	os.Exit(1)
+}
+
+func (l *logger) With(args ...interface{}) Logger {
+	return &logger{
+		logger: l.logger.With(args...),
+		level:  l.level,
+	}
+}
+
+func (l *logger) Level() string {
+	return levelName(l.level.Level())
+}
+
+func (l *logger) SetLevel(level string) error {
+	parsed, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	l.level.Set(parsed)
+	return nil
+}
+
+// log formats the message only when the level is enabled, and reports the
+// caller of the Logger method as the source.
+func (l *logger) log(level slog.Level, format string, args ...interface{}) {
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:])
+	record := slog.NewRecord(time.Now(), level, fmt.Sprintf(format, args...), pcs[0])
+	_ = l.logger.Handler().Handle(ctx, record)
+}
+
+func replaceAttr(groups []string, attr slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return attr
+	}
+
+	switch attr.Key {
+	case slog.LevelKey:
+		if level, ok := attr.Value.Any().(slog.Level); ok {
+			attr.Value = slog.StringValue(strings.ToUpper(levelName(level)))
+		}
+	case slog.SourceKey:
+		if source, ok := attr.Value.Any().(*slog.Source); ok {
+			attr.Value = slog.StringValue(fmt.Sprintf("%s:%d", shortFile(source.File), source.Line))
+		}
+	}
+	return attr
+}
+
+func levelName(level slog.Level) string {
+	if level == LevelFatal {
+		return "fatal"
+	}
+	return strings.ToLower(level.String())
+}
+
+// shortFile trims the path to the package directory and file name.
+func shortFile(file string) string {
+	dir := strings.LastIndexByte(file, '/')
+	if dir < 0 {
+		return file
+	}
+	if parent := strings.LastIndexByte(file[:dir], '/'); parent >= 0 {
+		return file[parent+1:]
+	}
+	return file
 }
@@ -1,11 +1,12 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
 	"io"
-	"net/http"
+	"time"
 )
 
 type Country struct {
@@ -20,48 +21,51 @@ type NationalizeResponse struct {
 }
 
 type NationalizeClient interface {
-	GetNationality(name string) (string, error)
+	Provider
+	GetNationality(ctx context.Context, name string) (string, error)
 }
 
 type nationalizeClient struct {
-	baseURL string
-	logger  logging.Logger
+	*provider
+	logger logging.Logger
 }
 
-func NewNationalizeClient(baseURL string, logger logging.Logger) NationalizeClient {
+func NewNationalizeClient(baseURL string, threshold int, cooldown time.Duration, propagate bool, logger logging.Logger) NationalizeClient {
 	return &nationalizeClient{
-		baseURL: baseURL,
-		logger:  logger,
+		provider: newProvider("nationalize", baseURL, threshold, cooldown, propagate),
+		logger:   logger.With("provider", "nationalize"),
 	}
 }
 
-func (c *nationalizeClient) GetNationality(name string) (string, error) {
+func (c *nationalizeClient) GetNationality(ctx context.Context, name string) (string, error) {
 	url := fmt.Sprintf("%s/?name=%s", c.baseURL, name)
 
-	resp, err := http.Get(url)
+	logger := requestLogger(ctx, c.logger)
+
+	resp, err := c.get(ctx, url)
 	if err != nil {
-		c.logger.Error("Failed to make request to Nationalize API: %v", err)
+		logger.Error("Failed to make request to Nationalize API: %v", err)
 		return "", err
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		c.logger.Error("Failed to read Nationalize API response: %v", err)
+		logger.Error("Failed to read Nationalize API response: %v", err)
 		return "", err
 	}
 
 	var nationalizeResponse NationalizeResponse
 	if err := json.Unmarshal(body, &nationalizeResponse); err != nil {
-		c.logger.Error("Failed to unmarshal Nationalize API response: %v", err)
+		logger.Error("Failed to unmarshal Nationalize API response: %v", err)
 		return "", err
 	}
 
 	if len(nationalizeResponse.Country) == 0 {
-		c.logger.Debug("No country data for name %s", name)
+		logger.Debug("No country data for name %s", name)
 		return "", nil
 	}
 
-	c.logger.Debug("Received country %s for name %s", nationalizeResponse.Country[0].CountryID, name)
+	logger.Debug("Received country %s for name %s", nationalizeResponse.Country[0].CountryID, name)
 	return nationalizeResponse.Country[0].CountryID, nil
 }
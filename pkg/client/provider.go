@@ -0,0 +1,161 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Provider is implemented by every enrichment client so that its health can
+// be reported.
+type Provider interface {
+	// Status describes the provider as seen from recent calls.
+	Status() ProviderStatus
+	// Ping checks that the provider can be reached, without calling its API
+	// and so without using up quota.
+	Ping(ctx context.Context) error
+}
+
+type ProviderStatus struct {
+	Name           string     `json:"name"`
+	CircuitBreaker string     `json:"circuit_breaker"`
+	Quota          *Quota     `json:"quota,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	LastErrorAt    *time.Time `json:"last_error_at,omitempty"`
+}
+
+// Quota is the rate limit reported by the provider in its last response.
+type Quota struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	ResetAt   time.Time `json:"reset_at"`
+}
+
+// Rate limit headers sent by agify.io, genderize.io and nationalize.io.
+const (
+	rateLimitHeader     = "X-Rate-Limit-Limit"
+	rateRemainingHeader = "X-Rate-Limit-Remaining"
+	rateResetHeader     = "X-Rate-Limit-Reset"
+)
+
+// provider holds what the clients share: the circuit breaker and what was
+// learned about the provider from its responses.
+type provider struct {
+	name      string
+	baseURL   string
+	breaker   *breaker
+	propagate bool
+
+	mu          sync.Mutex
+	quota       *Quota
+	lastError   string
+	lastErrorAt *time.Time
+}
+
+func newProvider(name, baseURL string, threshold int, cooldown time.Duration, propagate bool) *provider {
+	return &provider{
+		name:      name,
+		baseURL:   baseURL,
+		breaker:   newBreaker(threshold, cooldown),
+		propagate: propagate,
+	}
+}
+
+// get calls the provider through its circuit breaker. Server errors and rate
+// limiting count as failures, as do transport errors.
+func (p *provider) get(ctx context.Context, url string) (*http.Response, error) {
+	if !p.breaker.allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	resp, err := get(ctx, url, p.propagate)
+	if err != nil {
+		p.breaker.record(false)
+		p.recordError(err.Error())
+		return nil, err
+	}
+
+	p.recordQuota(resp.Header)
+	if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+		p.breaker.record(false)
+		p.recordError(resp.Status)
+		return resp, nil
+	}
+
+	p.breaker.record(true)
+	return resp, nil
+}
+
+func (p *provider) recordError(message string) {
+	now := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastError = message
+	p.lastErrorAt = &now
+}
+
+func (p *provider) recordQuota(header http.Header) {
+	limit, err := strconv.Atoi(header.Get(rateLimitHeader))
+	if err != nil {
+		return
+	}
+	remaining, err := strconv.Atoi(header.Get(rateRemainingHeader))
+	if err != nil {
+		return
+	}
+	reset, _ := strconv.Atoi(header.Get(rateResetHeader))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.quota = &Quota{
+		Limit:     limit,
+		Remaining: remaining,
+		ResetAt:   time.Now().Add(time.Duration(reset) * time.Second).UTC(),
+	}
+}
+
+func (p *provider) Status() ProviderStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := ProviderStatus{
+		Name:           p.name,
+		CircuitBreaker: p.breaker.State(),
+		LastError:      p.lastError,
+		LastErrorAt:    p.lastErrorAt,
+	}
+	if p.quota != nil {
+		quota := *p.quota
+		status.Quota = &quota
+	}
+	return status
+}
+
+// Ping opens a TCP connection to the provider host.
+func (p *provider) Ping(ctx context.Context) error {
+	u, err := url.Parse(p.baseURL)
+	if err != nil {
+		return err
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+
+	var dialer net.Dialer
+	connection, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return fmt.Errorf("%s unreachable: %w", p.name, err)
+	}
+	return connection.Close()
+}
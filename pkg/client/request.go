@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"github.com/RakhimovAns/Person-Service/pkg/requestctx"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/propagation"
+	"net/http"
+)
+
+var (
+	// httpClient traces provider calls and propagates the trace context and
+	// baggage to them.
+	httpClient = newHTTPClient()
+	// unpropagatedHTTPClient traces provider calls without sending them the
+	// trace context or baggage, for providers opted out of propagation.
+	unpropagatedHTTPClient = newHTTPClient(otelhttp.WithPropagators(propagation.NewCompositeTextMapPropagator()))
+)
+
+// newHTTPClient creates a traced client whose spans are named after the
+// provider host.
+func newHTTPClient(opts ...otelhttp.Option) *http.Client {
+	opts = append(opts, otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return r.Method + " " + r.URL.Host
+	}))
+	return &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport, opts...)}
+}
+
+// get sends a GET request bound to ctx, forwarding the request ID so that
+// provider calls can be traced to the request that caused them. Unless
+// propagate is false, the trace context and baggage are forwarded too.
+func get(ctx context.Context, url string, propagate bool) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if requestID := requestctx.RequestID(ctx); requestID != "" {
+		req.Header.Set(requestctx.RequestIDHeader, requestID)
+	}
+
+	if !propagate {
+		return unpropagatedHTTPClient.Do(req)
+	}
+	return httpClient.Do(req)
+}
+
+// requestLogger tags logger with the request ID from ctx.
+func requestLogger(ctx context.Context, logger logging.Logger) logging.Logger {
+	if requestID := requestctx.RequestID(ctx); requestID != "" {
+		return logger.With("request_id", requestID)
+	}
+	return logger
+}
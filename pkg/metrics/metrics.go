@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"database/sql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const namespace = "person_service"
+
+// Registry holds every metric the service exposes.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	httpRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests served, by route and status.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time to serve HTTP requests, by route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	httpRequestsInFlight = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests being served, including open streams.",
+	})
+
+	enrichmentDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "enrichment_request_duration_seconds",
+		Help:      "Time taken by enrichment provider calls.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"provider"})
+
+	enrichmentErrors = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "enrichment_errors_total",
+		Help:      "Failed enrichment provider calls.",
+	}, []string{"provider"})
+
+	peopleCreated = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "people_created_total",
+		Help:      "People created, by predicted nationality.",
+	}, []string{"nationality"})
+
+	personChanges = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "person_changes_total",
+		Help:      "Committed changes to people, by audit action.",
+	}, []string{"action"})
+
+	duplicatesDetected = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "duplicates_detected_total",
+		Help:      "Possible duplicates detected on create, by duplicate policy.",
+	}, []string{"policy"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// RegisterDB exposes the connection pool statistics of db.
+func RegisterDB(db *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// ObserveHTTPRequest records a served request. Route is the route pattern,
+// not the path, to keep the number of series bounded.
+func ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	labels := []string{method, route, strconv.Itoa(status)}
+	httpRequests.WithLabelValues(labels...).Inc()
+	httpRequestDuration.WithLabelValues(labels...).Observe(duration.Seconds())
+}
+
+// TrackInFlight counts a request as in flight until the returned function
+// is called.
+func TrackInFlight() func() {
+	httpRequestsInFlight.Inc()
+	return httpRequestsInFlight.Dec
+}
+
+// ObserveEnrichment records a provider call that started at start.
+func ObserveEnrichment(provider string, start time.Time, err error) {
+	enrichmentDuration.WithLabelValues(provider).Observe(time.Since(start).Seconds())
+	if err != nil {
+		enrichmentErrors.WithLabelValues(provider).Inc()
+	}
+}
+
+// PersonChanged records a committed change to a person.
+func PersonChanged(action string) {
+	personChanges.WithLabelValues(action).Inc()
+}
+
+// PersonCreated records a committed creation by the predicted nationality.
+func PersonCreated(nationality string) {
+	if nationality == "" {
+		nationality = "unknown"
+	}
+	peopleCreated.WithLabelValues(nationality).Inc()
+}
+
+func DuplicateDetected(policy string) {
+	duplicatesDetected.WithLabelValues(policy).Inc()
+}
@@ -0,0 +1,95 @@
+package requestctx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+const (
+	SourceAPI    = "api"
+	SourceImport = "import"
+	SourceWorker = "worker"
+
+	anonymousActor = "anonymous"
+	// unverifiedPrefix marks actors named by the caller. Nothing
+	// authenticates them, so the audit log must not present them as proven.
+	unverifiedPrefix = "unverified:"
+
+	// RequestIDHeader carries the request ID on HTTP requests and responses,
+	// inbound and outbound.
+	RequestIDHeader = "X-Request-ID"
+
+	maxRequestIDLength = 128
+)
+
+type actorKey struct{}
+type requestIDKey struct{}
+type sourceKey struct{}
+
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// WithClaimedActor stores an actor the caller named without proof, such as
+// the X-Actor header, labelled as unverified.
+func WithClaimedActor(ctx context.Context, actor string) context.Context {
+	if actor == "" {
+		return ctx
+	}
+	return WithActor(ctx, unverifiedPrefix+actor)
+}
+
+// Actor returns the actor stored in ctx or "anonymous" if there is none.
+func Actor(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return anonymousActor
+}
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// NewRequestID returns a random 32-character hex request ID.
+func NewRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// ValidRequestID reports whether a caller-supplied request ID is safe to
+// propagate into logs, headers and SQL comments: up to 128 letters, digits
+// and '-', '_', '.' or ':'.
+func ValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range requestID {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func WithSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+// Source returns where the change originated from, defaulting to SourceAPI.
+func Source(ctx context.Context) string {
+	if source, ok := ctx.Value(sourceKey{}).(string); ok && source != "" {
+		return source
+	}
+	return SourceAPI
+}
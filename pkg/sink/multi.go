@@ -0,0 +1,59 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+)
+
+type multiSink struct {
+	sinks []Sink
+
+	mu sync.Mutex
+	// accepted holds, for the messages that some sinks failed to accept,
+	// which of the sinks did accept them.
+	accepted map[int64][]bool
+}
+
+// NewMulti publishes every message to each of sinks. A sink that fails does
+// not keep the message from the others, and when the message is retried
+// only the sinks that have not accepted it yet see it again. What was
+// accepted is only remembered by this process, so the sinks must still
+// tolerate seeing a message more than once.
+func NewMulti(sinks ...Sink) Sink {
+	return &multiSink{
+		sinks:    sinks,
+		accepted: map[int64][]bool{},
+	}
+}
+
+func (s *multiSink) Publish(ctx context.Context, msg Message) error {
+	s.mu.Lock()
+	accepted := slices.Clone(s.accepted[msg.ID])
+	s.mu.Unlock()
+	if accepted == nil {
+		accepted = make([]bool, len(s.sinks))
+	}
+
+	var errs []error
+	for i, sink := range s.sinks {
+		if accepted[i] {
+			continue
+		}
+		if err := sink.Publish(ctx, msg); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		accepted[i] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(errs) == 0 {
+		delete(s.accepted, msg.ID)
+		return nil
+	}
+	s.accepted[msg.ID] = accepted
+	return errors.Join(errs...)
+}
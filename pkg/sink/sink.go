@@ -0,0 +1,16 @@
+package sink
+
+import "context"
+
+// Message is a published event: its ID, its type and its JSON encoding.
+type Message struct {
+	ID   int64
+	Type string
+	Data []byte
+}
+
+// Sink delivers messages to an external system. Publish must return an
+// error unless the message was accepted, so that it is retried later.
+type Sink interface {
+	Publish(ctx context.Context, msg Message) error
+}
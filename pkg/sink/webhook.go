@@ -0,0 +1,53 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/RakhimovAns/Person-Service/pkg/client/logging"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type webhookSink struct {
+	url    string
+	client *http.Client
+	logger logging.Logger
+}
+
+// NewWebhookSink POSTs each message to url. Any response other than 2xx is
+// treated as a failed delivery.
+func NewWebhookSink(url string, timeout time.Duration, logger logging.Logger) Sink {
+	return &webhookSink{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+		logger: logger,
+	}
+}
+
+func (s *webhookSink) Publish(ctx context.Context, msg Message) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(msg.Data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", strconv.FormatInt(msg.ID, 10))
+	req.Header.Set("X-Event-Type", msg.Type)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		s.logger.Error("Failed to deliver event %d to webhook: %v", msg.ID, err)
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	s.logger.Debug("Delivered event %d to webhook", msg.ID)
+	return nil
+}
@@ -0,0 +1,30 @@
+package sink
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+type writerSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink writes each message as a line of JSON to w, such as stdout or
+// an append-only file.
+func NewWriterSink(w io.Writer) Sink {
+	return &writerSink{w: w}
+}
+
+func (s *writerSink) Publish(ctx context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	line := make([]byte, 0, len(msg.Data)+1)
+	line = append(line, msg.Data...)
+	line = append(line, '\n')
+
+	_, err := s.w.Write(line)
+	return err
+}
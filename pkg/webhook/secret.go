@@ -0,0 +1,88 @@
+package webhook
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// sealedPrefix marks secrets sealed by a SecretBox, telling them apart from
+// secrets stored in plaintext before they were encrypted.
+const sealedPrefix = "v1:"
+
+// ErrNoSecretKey is returned when a secret has to be sealed or opened
+// without a key.
+var ErrNoSecretKey = errors.New("no webhook secret key configured")
+
+// SecretBox encrypts subscription secrets at rest with AES-256-GCM. A nil
+// SecretBox has no key: it cannot seal secrets, and opens only those that
+// were stored in plaintext.
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox creates a SecretBox from a hex-encoded 32-byte key.
+func NewSecretBox(hexKey string) (*SecretBox, error) {
+	key, err := hex.DecodeString(hexKey)
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("webhook secret key must be 64 hex characters")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SecretBox{aead: aead}, nil
+}
+
+// Seal encrypts secret for storage.
+func (b *SecretBox) Seal(secret string) (string, error) {
+	if b == nil {
+		return "", ErrNoSecretKey
+	}
+
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := b.aead.Seal(nonce, nonce, []byte(secret), nil)
+	return sealedPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a stored secret. Secrets stored in plaintext are returned
+// as they are.
+func (b *SecretBox) Open(stored string) (string, error) {
+	encoded, ok := strings.CutPrefix(stored, sealedPrefix)
+	if !ok {
+		return stored, nil
+	}
+	if b == nil {
+		return "", ErrNoSecretKey
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("decode webhook secret: %w", err)
+	}
+	nonceSize := b.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", errors.New("decode webhook secret: too short")
+	}
+
+	secret, err := b.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt webhook secret: %w", err)
+	}
+	return string(secret), nil
+}